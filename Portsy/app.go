@@ -3,15 +3,18 @@ package main
 import (
 	"Portsy/backend"
 	ui "Portsy/backend/uiapi"
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -32,6 +35,11 @@ type RootStatsResult struct {
 
 var (
 	watchCancel context.CancelFunc // global cancel for the watcher
+	watchWG     sync.WaitGroup     // StartWatcherAll goroutines, for Shutdown
+
+	scanMu     sync.Mutex
+	scanCancel context.CancelFunc // cancel for the in-flight GUI scan (one at a time)
+	scanGen    uint64             // bumps per scan so a finished scan doesn't clear a newer one
 )
 
 func NewApp() *App { return &App{} }
@@ -43,6 +51,10 @@ func (a *App) Startup(ctx context.Context) {
 
 	// Load .env so GUI has the same env as CLI
 	_ = godotenv.Overload(".env", "../.env", "../../.env")
+	backend.SetCaseSensitivePathsFromEnv()
+	if err := backend.LoadKeychainEnv(); err != nil {
+		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("Keychain: %v", err))
+	}
 
 	// ---- locate CLI (as you had) ----
 	if p := os.Getenv("PORTSY_CLI"); p != "" {
@@ -81,25 +93,30 @@ func (a *App) Startup(ctx context.Context) {
 	}
 
 	// ---- init Firestore MetaStore for GUI calls (ListRemoteProjects etc.) ----
-	// Needs GCP_PROJECT_ID and GOOGLE_APPLICATION_CREDENTIALS
+	// Needs GCP_PROJECT_ID and GOOGLE_APPLICATION_CREDENTIALS, or
+	// FIRESTORE_EMULATOR_HOST instead of the credentials.
 	proj := os.Getenv("GCP_PROJECT_ID")
 	cred := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	emulator := os.Getenv(backend.EmulatorHostEnv)
 	if strings.HasPrefix(cred, ".") {
 		if abs, err := filepath.Abs(cred); err == nil {
 			cred = abs
 		}
 	}
-	if proj == "" || cred == "" {
+	if proj == "" || (cred == "" && emulator == "") {
 		runtime.EventsEmit(a.ctx, "log", "Firestore not configured (set GCP_PROJECT_ID and GOOGLE_APPLICATION_CREDENTIALS). ListRemoteProjects will be unavailable.")
 		return
 	}
-	if _, err := os.Stat(cred); err != nil {
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("GOOGLE_APPLICATION_CREDENTIALS not found at %q: %v", cred, err))
-		return
+	if emulator == "" {
+		if _, err := os.Stat(cred); err != nil {
+			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("GOOGLE_APPLICATION_CREDENTIALS not found at %q: %v", cred, err))
+			return
+		}
 	}
 	metaCfg := backend.MetaStoreConfig{
 		ProjectID:       proj,
 		CredentialsPath: cred,
+		EmulatorHost:    emulator,
 	}
 	m, err := backend.NewMetaStore(metaCfg)
 	if err != nil {
@@ -179,14 +196,88 @@ func (a *App) PickRoot() (string, error) {
 
 // ---- direct (non-CLI) convenience, optional ----
 
+// ScanProjects scans in-process. Starting a new scan (or calling CancelScan)
+// cancels the previous one so a huge root can't keep the disk busy after the
+// user has moved on.
 func (a *App) ScanProjects(rootPath string) ([]backend.AbletonProject, error) {
-	return backend.ScanProjects(rootPath)
+	ctx, done := a.beginScan()
+	defer done()
+
+	projs, err := backend.ScanProjectsCtx(ctx, rootPath)
+	if err != nil && ctx.Err() != nil {
+		a.emitScanCancelled(rootPath)
+	}
+	return projs, err
+}
+
+// CancelScan aborts the in-flight scan (if any).
+func (a *App) CancelScan() {
+	scanMu.Lock()
+	defer scanMu.Unlock()
+	if scanCancel != nil {
+		scanCancel()
+		scanCancel = nil
+	}
+}
+
+// beginScan cancels any previous scan and returns a fresh per-request context.
+func (a *App) beginScan() (context.Context, func()) {
+	parent := a.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	scanMu.Lock()
+	if scanCancel != nil {
+		scanCancel()
+	}
+	scanCancel = cancel
+	scanGen++
+	gen := scanGen
+	scanMu.Unlock()
+
+	return ctx, func() {
+		cancel()
+		scanMu.Lock()
+		if scanGen == gen {
+			scanCancel = nil
+		}
+		scanMu.Unlock()
+	}
+}
+
+func (a *App) emitScanCancelled(root string) {
+	runtime.EventsEmit(a.ctx, "scan:cancelled", map[string]any{
+		"root": root,
+		"at":   time.Now().Format(time.RFC3339),
+	})
+}
+
+// RootStatus is the dashboard query: per-project change counts, bytes to push
+// and ahead/behind vs remote, with one manifest build per project.
+func (a *App) RootStatus(root string) ([]backend.ProjectStatus, error) {
+	if strings.TrimSpace(root) == "" {
+		return nil, fmt.Errorf("no root selected")
+	}
+	var head backend.HeadLookup
+	if a.meta != nil {
+		head = a.meta.GetLatestState
+	}
+	return backend.RootStatus(a.ctx, root, head)
 }
 
 // ---- CLI passthroughs ----
 
 func (a *App) ScanJSON(root string) (string, error) {
-	return a.runCmd(a.ctx, "-mode=scan", "-root", root, "-json")
+	ctx, done := a.beginScan()
+	defer done()
+
+	out, err := a.runCmd(ctx, "-mode=scan", "-root", root, "-json")
+	if err != nil && ctx.Err() != nil {
+		a.emitScanCancelled(root)
+	}
+	return out, err
 }
 
 func (a *App) PendingJSON(root string) (string, error) {
@@ -213,6 +304,65 @@ func (a *App) DiffJSON(root string) (string, error) {
 	return t, nil
 }
 
+// SelfTest runs the CLI's connectivity checks (Firestore, R2 credentials and
+// a round-trip upload) and returns its log.
+func (a *App) SelfTest() (string, error) {
+	return a.runCmd(a.ctx, "-mode=check")
+}
+
+// CloneProject creates newName as a new remote project starting from src at
+// commit ("" = HEAD), reusing the source's blobs.
+func (a *App) CloneProject(src, commit, newName string) (string, error) {
+	args := []string{"-mode=clone", "-project", src, "-as", newName}
+	if commit != "" {
+		args = append(args, "-commit", commit)
+	}
+	return a.runCmd(a.ctx, args...)
+}
+
+// RemoteFiles returns the files of the project's commit ("" = HEAD) as JSON
+// (backend.FileEntry, with audio info where recorded).
+func (a *App) RemoteFiles(project, commit string) (string, error) {
+	args := []string{"-mode=files", "-project", project, "-json"}
+	if commit != "" {
+		args = append(args, "-commit", commit)
+	}
+	return a.runCmd(a.ctx, args...)
+}
+
+// PreviewURL returns a presigned URL that streams file from the project's
+// commit ("" = HEAD) inline, for an <audio> player in the remote browser.
+func (a *App) PreviewURL(project, commit, file string) (string, error) {
+	args := []string{"-mode=preview", "-project", project, "-file", file}
+	if commit != "" {
+		args = append(args, "-commit", commit)
+	}
+	out, err := a.runCmd(a.ctx, args...)
+	return strings.TrimSpace(out), err
+}
+
+// Peaks returns the stored waveform peaks of an audio file as JSON (see
+// backend.PeakData); only files pushed with -peaks have them.
+func (a *App) Peaks(project, commit, file string) (string, error) {
+	args := []string{"-mode=peaks", "-project", project, "-file", file}
+	if commit != "" {
+		args = append(args, "-commit", commit)
+	}
+	return a.runCmd(a.ctx, args...)
+}
+
+// StorageUsage returns the project's R2 usage as JSON (see
+// backend.StorageUsage) for the storage panel.
+func (a *App) StorageUsage(project string) (string, error) {
+	return a.runCmd(a.ctx, "-mode=usage", "-project", project, "-json")
+}
+
+// TotalStorageUsage returns the whole bucket's usage by project with a
+// monthly cost estimate, as JSON (see backend.BucketUsage).
+func (a *App) TotalStorageUsage() (string, error) {
+	return a.runCmd(a.ctx, "-mode=usage", "-json")
+}
+
 func (a *App) Push(root, project, msg string) (string, error) {
 	if msg == "" {
 		msg = "GUI push: " + time.Now().Format(time.RFC3339)
@@ -245,6 +395,110 @@ func (a *App) Rollback(project, dest, commit string) (string, error) {
 	return a.runCmd(a.ctx, args...)
 }
 
+// Export writes a project version to a standalone zip (HEAD if commit == "").
+func (a *App) Export(project, commit, destZip string) (string, error) {
+	args := []string{"-mode=export", "-project", project, "-dest", destZip}
+	if commit != "" {
+		args = append(args, "-commit", commit)
+	}
+	return a.runCmd(a.ctx, args...)
+}
+
+// ImportProject onboards a local project (hash + upload + first commit).
+// Each CLI progress line is forwarded as an "import:progress" event.
+func (a *App) ImportProject(root, project, msg string) error {
+	if msg == "" {
+		msg = "Initial import"
+	}
+	return a.runCmdStream(a.ctx, func(line []byte) {
+		var p backend.ImportProgress
+		if json.Unmarshal(line, &p) == nil && p.Phase != "" {
+			runtime.EventsEmit(a.ctx, "import:progress", p)
+		}
+	}, "-mode=import", "-root", root, "-project", project, "-msg", msg)
+}
+
+// PushAll pushes every project under root with unsynced changes.
+// Per-project progress is emitted as "pushall:progress" events.
+func (a *App) PushAll(root, msg string) ([]backend.PushAllResult, error) {
+	if msg == "" {
+		msg = "GUI push-all: " + time.Now().Format(time.RFC3339)
+	}
+	var results []backend.PushAllResult
+	err := a.runCmdStream(a.ctx, func(line []byte) {
+		var p backend.PushAllProgress
+		if json.Unmarshal(line, &p) == nil && p.Status != "" {
+			runtime.EventsEmit(a.ctx, "pushall:progress", p)
+			return
+		}
+		var summary struct {
+			Results []backend.PushAllResult `json:"results"`
+		}
+		if json.Unmarshal(line, &summary) == nil && summary.Results != nil {
+			results = summary.Results
+		}
+	}, "-mode=push-all", "-root", root, "-msg", msg, "-json")
+	return results, err
+}
+
+// PullAll updates every local project under root whose remote is ahead,
+// skipping projects with unpushed changes. Emits "pullall:progress" events.
+func (a *App) PullAll(root string) ([]backend.PullAllResult, error) {
+	var results []backend.PullAllResult
+	err := a.runCmdStream(a.ctx, func(line []byte) {
+		var p backend.PullAllProgress
+		if json.Unmarshal(line, &p) == nil && p.Status != "" {
+			runtime.EventsEmit(a.ctx, "pullall:progress", p)
+			return
+		}
+		var summary struct {
+			Results []backend.PullAllResult `json:"results"`
+		}
+		if json.Unmarshal(line, &summary) == nil && summary.Results != nil {
+			results = summary.Results
+		}
+	}, "-mode=pull-all", "-root", root, "-json")
+	return results, err
+}
+
+// runCmdStream runs the CLI and hands each stdout line to onLine as it
+// arrives (for progress events); stderr is attached to the error.
+func (a *App) runCmdStream(ctx context.Context, onLine func([]byte), args ...string) error {
+	if a.cliPath == "" {
+		return fmt.Errorf("portsy CLI not found (set PORTSY_CLI or place portsy.exe next to the app)")
+	}
+	runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("CLI: %s %v", a.cliPath, args))
+
+	cmd := exec.CommandContext(ctx, a.cliPath, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var errb bytes.Buffer
+	cmd.Stderr = &errb
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	sc := bufio.NewScanner(stdout)
+	for sc.Scan() {
+		onLine(sc.Bytes())
+	}
+	if err := cmd.Wait(); err != nil {
+		if errb.Len() > 0 {
+			return fmt.Errorf("%v\n%s", err, errb.String())
+		}
+		return err
+	}
+	return nil
+}
+
+// PreviewCollect lists the samples a project's .als references and which of
+// them a collect would copy, without touching disk. alsPath "" = main .als.
+func (a *App) PreviewCollect(projectPath, alsPath string) (*backend.CollectPreview, error) {
+	return backend.PreviewCollect(alsPath, projectPath)
+}
+
 // ---- watcher (in-process), emits UI events ----
 func (a *App) StartWatcherAll(root string, autopush bool) error {
 	a.currentRoot = root
@@ -258,13 +512,32 @@ func (a *App) StartWatcherAll(root string, autopush bool) error {
 	log.Printf("[StartWatcherAll] root=%s autopush=%v", root, autopush)
 	runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("[StartWatcherAll] root=%s autopush=%v", root, autopush))
 
+	watchWG.Add(1)
 	go func() {
+		defer watchWG.Done()
 		log.Printf("[StartWatcherAll] entering WatchAllProjects on %s", root)
 		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("[StartWatcherAll] entering WatchAllProjects on %s", root))
 
 		_ = backend.WatchAllProjects(ctx, root, 750*time.Millisecond, func(evt backend.SaveEvent) {
+			if err := backend.CheckALS(evt.ALSPath); errors.Is(err, backend.ErrCorruptALS) {
+				// Don't collect from or push a broken set; point at a good copy.
+				log.Printf("[watch] %s: %v; skipping push", evt.ProjectName, err)
+				runtime.EventsEmit(a.ctx, "als:corrupt", map[string]any{
+					"project":  evt.ProjectName,
+					"path":     evt.ALSPath,
+					"error":    err.Error(),
+					"lastGood": backend.LastGoodALS(evt.ProjectPath, filepath.Base(evt.ALSPath)),
+				})
+				return
+			}
+
 			// existing logs...
-			_, _ = backend.CollectNewSamples(ctx, evt.ProjectPath, evt.ALSPath)
+			if res, err := backend.CollectNewSamplesResult(ctx, evt.ProjectPath, evt.ALSPath); err == nil {
+				runtime.EventsEmit(a.ctx, "collect:result", map[string]any{
+					"project": evt.ProjectName,
+					"result":  res,
+				})
+			}
 
 			// --- NEW: build & emit a DiffSummary ---
 			js, err := a.GetDiffForProject(evt.ProjectName)
@@ -336,6 +609,37 @@ func (a *App) StopWatcherAll() {
 	}
 }
 
+// shutdownTimeout bounds how long Shutdown waits for in-flight watcher work.
+const shutdownTimeout = 30 * time.Second
+
+// Shutdown stops the watcher and any GUI scan, then waits (up to
+// shutdownTimeout) for the watcher goroutines to exit. WatchAllProjects only
+// returns once its save handlers are done, so an autopush CLI run, which
+// writes the project's cache when it finishes, completes instead of being
+// cut off when the window closes.
+// Cache writes that failed during the session are retried last.
+func (a *App) Shutdown() error {
+	a.StopWatcherAll()
+	scanMu.Lock()
+	if scanCancel != nil {
+		scanCancel()
+	}
+	scanMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		watchWG.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		err = fmt.Errorf("shutdown: watcher still busy after %s", shutdownTimeout)
+	}
+	return errors.Join(err, backend.FlushCacheWrites())
+}
+
 func (a *App) ListRemoteProjects() ([]backend.ProjectDoc, error) {
 	if a.meta == nil {
 		return nil, fmt.Errorf("firestore not configured in GUI (set GCP_PROJECT_ID and GOOGLE_APPLICATION_CREDENTIALS, or check Startup logs)")
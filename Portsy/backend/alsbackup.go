@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultALSBackupKeep is how many .als safety copies a project keeps when
+// PullOptions.ALSBackupKeep is 0.
+const DefaultALSBackupKeep = 20
+
+// alsBackupStamp prefixes each backup name, so names sort by age.
+const alsBackupStamp = "20060102-150405.000"
+
+// ALSBackup is a safety copy of a top-level .als, taken before a pull
+// overwrote or deleted it.
+type ALSBackup struct {
+	Name     string    `json:"name"`     // file name in .portsy/als-backups
+	Original string    `json:"original"` // the .als it was copied from
+	Created  time.Time `json:"created"`
+	Size     int64     `json:"size"`
+}
+
+func alsBackupDir(projectPath string) string {
+	return filepath.Join(projectPath, ".portsy", "als-backups")
+}
+
+// isTopLevelALS reports whether rel (slash-separated) is a set in the project
+// root, as opposed to Live's own Backup/ copies.
+func isTopLevelALS(rel string) bool {
+	return !strings.Contains(rel, "/") && strings.EqualFold(path.Ext(rel), ".als")
+}
+
+// backupALS copies the project's rel .als to .portsy/als-backups/<stamp>_<name>
+// and prunes the oldest copies beyond keep (0 = DefaultALSBackupKeep,
+// < 0 = backups off).
+func backupALS(projectPath, rel string, keep int) error {
+	if keep < 0 {
+		return nil
+	}
+	if keep == 0 {
+		keep = DefaultALSBackupKeep
+	}
+	name := time.Now().Format(alsBackupStamp) + "_" + rel
+	if err := copyFile(filepath.Join(projectPath, rel), filepath.Join(alsBackupDir(projectPath), name)); err != nil {
+		return fmt.Errorf("backup %s: %w", rel, err)
+	}
+	backups, err := ListALSBackups(projectPath)
+	if err != nil {
+		return nil // the copy is safe; pruning can wait
+	}
+	for _, b := range backups[min(keep, len(backups)):] {
+		_ = os.Remove(filepath.Join(alsBackupDir(projectPath), b.Name))
+	}
+	return nil
+}
+
+// ListALSBackups returns the project's .als safety copies, newest first.
+func ListALSBackups(projectPath string) ([]ALSBackup, error) {
+	ents, err := os.ReadDir(alsBackupDir(projectPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []ALSBackup
+	for _, e := range ents {
+		stamp, orig, ok := strings.Cut(e.Name(), "_")
+		if !ok || e.IsDir() {
+			continue
+		}
+		created, err := time.ParseInLocation(alsBackupStamp, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		b := ALSBackup{Name: e.Name(), Original: orig, Created: created}
+		if fi, err := e.Info(); err == nil {
+			b.Size = fi.Size()
+		}
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name > out[j].Name })
+	return out, nil
+}
+
+// RestoreALSBackup puts a backup back as its original .als. The .als being
+// replaced is itself backed up first, so a restore can be undone.
+func RestoreALSBackup(projectPath, name string) error {
+	stamp, orig, ok := strings.Cut(name, "_")
+	if _, err := time.Parse(alsBackupStamp, stamp); !ok || err != nil || !isTopLevelALS(orig) {
+		return fmt.Errorf("restore: %q is not an .als backup", name)
+	}
+	src := filepath.Join(alsBackupDir(projectPath), name)
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	dst := filepath.Join(projectPath, orig)
+	if _, err := os.Stat(dst); err == nil {
+		if err := backupALS(projectPath, orig, 0); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(dst, func(tf *os.File) error {
+		in, err := os.Open(longPath(src))
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = tf.ReadFrom(in)
+		return err
+	})
+}
+
+// LastGoodALS returns the newest copy of the project's rel .als that still
+// reads cleanly (see CheckALS), looking at Portsy's safety copies and Live's
+// own Backup/ folder; "" if there is none. For recovering from a corrupt set.
+func LastGoodALS(projectPath, rel string) string {
+	type candidate struct {
+		path string
+		mod  time.Time
+	}
+	var cands []candidate
+	add := func(dir string, match func(name string) bool) {
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range ents {
+			if e.IsDir() || !match(e.Name()) {
+				continue
+			}
+			if fi, err := e.Info(); err == nil {
+				cands = append(cands, candidate{filepath.Join(dir, e.Name()), fi.ModTime()})
+			}
+		}
+	}
+	add(alsBackupDir(projectPath), func(name string) bool {
+		_, orig, ok := strings.Cut(name, "_")
+		return ok && orig == rel
+	})
+	// Live names its copies "<set> [2024-01-31 101500].als".
+	stem := strings.TrimSuffix(rel, path.Ext(rel))
+	add(filepath.Join(projectPath, "Backup"), func(name string) bool {
+		return strings.HasPrefix(name, stem+" [") && strings.EqualFold(path.Ext(name), ".als")
+	})
+
+	sort.Slice(cands, func(i, j int) bool { return cands[i].mod.After(cands[j].mod) })
+	for _, c := range cands {
+		if CheckALS(c.path) == nil {
+			return c.path
+		}
+	}
+	return ""
+}
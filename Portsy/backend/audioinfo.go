@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"Portsy/backend/internal/audio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReadAudioInfo reads a WAV, AIFF or FLAC file's header. Other formats fail
+// with an error wrapping audio.ErrUnsupported.
+func ReadAudioInfo(localPath string) (*AudioInfo, error) {
+	in, err := audio.ReadInfo(longPath(localPath))
+	if err != nil {
+		return nil, err
+	}
+	return &AudioInfo{
+		Format:     in.Format,
+		Duration:   in.Duration,
+		SampleRate: in.SampleRate,
+		Channels:   in.Channels,
+		BitDepth:   in.BitDepth,
+	}, nil
+}
+
+// FormatAudioInfo renders info the way the file listing shows it, e.g.
+// "0:02, 44.1kHz, 24-bit, stereo".
+func FormatAudioInfo(a *AudioInfo) string {
+	if a == nil {
+		return ""
+	}
+	d := time.Duration(a.Duration * float64(time.Second)).Round(time.Second)
+	ch := fmt.Sprintf("%d ch", a.Channels)
+	switch a.Channels {
+	case 1:
+		ch = "mono"
+	case 2:
+		ch = "stereo"
+	}
+	return fmt.Sprintf("%d:%02d, %gkHz, %d-bit, %s", int(d.Minutes()), int(d.Seconds())%60, float64(a.SampleRate)/1000, a.BitDepth, ch)
+}
+
+// reuseRender points f at pf's blob when f is a re-render of pf with the same
+// audio (PushOptions.AudioFingerprint): only R2Key/ETag carry over, f keeps
+// its own Hash, Size and Modified. It reports whether it did.
+func reuseRender(f *FileEntry, pf FileEntry) bool {
+	if f.AudioHash == "" || f.AudioHash != pf.AudioHash || pf.R2Key == "" {
+		return false
+	}
+	f.R2Key, f.ETag = pf.R2Key, pf.ETag
+	return true
+}
+
+// sameAudio reports whether the file at localPath has fe's audio
+// fingerprint, i.e. it is the render a reused blob (see reuseRender) holds.
+func sameAudio(localPath string, fe FileEntry) bool {
+	if fe.AudioHash == "" {
+		return false
+	}
+	fp, err := audio.Fingerprint(longPath(localPath))
+	return err == nil && fp == fe.AudioHash
+}
+
+// sameAudioReader is sameAudio over a blob's bytes.
+func sameAudioReader(r io.Reader, fe FileEntry) bool {
+	if fe.AudioHash == "" {
+		return false
+	}
+	fp, err := audio.FingerprintReader(r)
+	return err == nil && fp == fe.AudioHash
+}
@@ -0,0 +1,37 @@
+package backend
+
+import "sync"
+
+// blobOnce runs the work for each blob key at most once per pull. Files with
+// identical content share a key, so without it a project full of duplicated
+// samples pays one download per path instead of one per unique hash. Callers
+// arriving while the first is in flight wait for it. (Push groups its upload
+// plan by key up front instead.)
+type blobOnce struct {
+	mu    sync.Mutex
+	calls map[string]*blobCall
+}
+
+type blobCall struct {
+	once sync.Once
+	val  string
+	err  error
+}
+
+// do returns fn's result for key, calling fn only for the first caller.
+// shared is true when the result came from an earlier caller.
+func (b *blobOnce) do(key string, fn func() (string, error)) (val string, shared bool, err error) {
+	b.mu.Lock()
+	if b.calls == nil {
+		b.calls = map[string]*blobCall{}
+	}
+	c, ok := b.calls[key]
+	if !ok {
+		c = &blobCall{}
+		b.calls[key] = c
+	}
+	b.mu.Unlock()
+
+	c.once.Do(func() { c.val, c.err = fn() })
+	return c.val, ok, c.err
+}
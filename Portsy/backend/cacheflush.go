@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// pendingCaches holds synced states whose cache.json write failed (a file
+// locked by antivirus or a sync client, a full disk). Left alone, the next
+// launch diffs against the old cache and shows already-synced files as
+// changed, so they're retried by FlushCacheWrites, e.g. on shutdown.
+var pendingCaches = struct {
+	sync.Mutex
+	m map[string]pendingCache // key: cleaned project path
+}{m: map[string]pendingCache{}}
+
+type pendingCache struct {
+	ps   ProjectState
+	algo string
+}
+
+// QueueCacheWrite records ps as the project's synced state, to be written
+// by the next FlushCacheWrites. A later queue or successful write for the
+// same project replaces it.
+func QueueCacheWrite(projectPath string, ps ProjectState, algo string) {
+	pendingCaches.Lock()
+	pendingCaches.m[filepath.Clean(projectPath)] = pendingCache{ps: ps, algo: algo}
+	pendingCaches.Unlock()
+}
+
+// forgetCacheWrite drops a queued write superseded by a successful one.
+func forgetCacheWrite(projectPath string) {
+	pendingCaches.Lock()
+	delete(pendingCaches.m, filepath.Clean(projectPath))
+	pendingCaches.Unlock()
+}
+
+// FlushCacheWrites writes every queued cache. Writes that fail again stay
+// queued; their errors are joined.
+func FlushCacheWrites() error {
+	pendingCaches.Lock()
+	todo := pendingCaches.m
+	pendingCaches.m = map[string]pendingCache{}
+	pendingCaches.Unlock()
+
+	var errs []error
+	for p, pc := range todo {
+		if err := WriteCacheFromState(p, pc.ps, pc.algo); err != nil {
+			errs = append(errs, fmt.Errorf("flush cache %s: %w", p, err))
+		}
+	}
+	return errors.Join(errs...)
+}
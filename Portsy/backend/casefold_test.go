@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"Portsy/backend/internal/core/scan"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestFile writes body to p, creating parent folders.
+func writeTestFile(t *testing.T, p, body string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestUnicodeFolderCacheStable: a project with non-ASCII folder and file names
+// round-trips through the scanner, BuildManifest and cache.json with the same
+// keys, so a rescan shows no phantom changes.
+func TestUnicodeFolderCacheStable(t *testing.T) {
+	tests := []struct {
+		name string
+		rels []string
+	}{
+		{name: "accented folder", rels: []string{"Drépanö/Kick.wav", "Drépanö/Set.als"}},
+		{name: "upper-case accents", rels: []string{"DRÉPANÖ/ÉCHO.wav"}},
+		{name: "non-Latin", rels: []string{"Ψυχή/Ήχος.wav", "サンプル/Kick.wav"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, rel := range tt.rels {
+				writeTestFile(t, filepath.Join(dir, filepath.FromSlash(rel)), rel)
+			}
+
+			walked, err := scan.WalkProject(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ps, err := BuildManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(walked) != len(tt.rels) || len(ps.Files) != len(tt.rels) {
+				t.Fatalf("walked %d, manifest %d files; want %d", len(walked), len(ps.Files), len(tt.rels))
+			}
+			for i, f := range ps.Files {
+				if f.Path != walked[i].Rel {
+					t.Errorf("manifest key %q, scanner key %q", f.Path, walked[i].Rel)
+				}
+				if f.Path != normalizeKey(f.Path) {
+					t.Errorf("manifest key %q isn't normalized (%q)", f.Path, normalizeKey(f.Path))
+				}
+			}
+
+			if err := WriteCacheFromState(dir, ps, ps.Algo); err != nil {
+				t.Fatal(err)
+			}
+			lc, err := LoadLocalCache(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := BuildManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changes := DiffManifests(ManifestFromState(again), lc.Hashes()); len(changes) != 0 {
+				t.Errorf("rescan against the cache shows %v", changes)
+			}
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package backend
 
 import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"log"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"sync"
 )
 
 type ProjectChange struct {
@@ -18,41 +24,61 @@ type ProjectChange struct {
 // diffs against .portsy/cache.json, and returns a stable, sorted list
 // of projects that have at least one change.
 func ChangedProjectsSinceCache(root string) ([]ProjectChange, error) {
+	return ChangedProjectsSinceCacheN(root, 0)
+}
+
+// ChangedProjectsSinceCacheN is ChangedProjectsSinceCache with a bounded
+// worker pool; each project's manifest build is independent.
+// workers <= 0 picks a default based on CPU count.
+func ChangedProjectsSinceCacheN(root string, workers int) ([]ProjectChange, error) {
 	projs, err := ScanProjects(root)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]ProjectChange, 0, len(projs))
+	if workers <= 0 {
+		workers = max(2, runtime.NumCPU()/2)
+	}
 
-	for _, p := range projs {
-		pp := filepath.Join(root, p.Name)
+	// One slot per project; nil = unchanged or unreadable.
+	results := make([]*ProjectChange, len(projs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				p := projs[i]
+				pp := filepath.Join(root, p.Name)
 
-		ps, err := BuildManifest(pp)
-		if err != nil {
-			continue
-		}
+				ps, err := BuildManifest(pp)
+				if err != nil {
+					continue
+				}
 
-		cur := ManifestFromState(ps)
+				cur := ManifestFromState(ps)
 
-		lc, _ := LoadLocalCache(pp)
-		changes := DiffManifests(cur, lc.Manifest)
-		if len(changes) == 0 {
-			continue
-		}
-
-		pc := ProjectChange{Name: p.Name, Path: pp}
-		for _, c := range changes {
-			switch c.Type {
-			case "added":
-				pc.Added++
-			case "modified":
-				pc.Modified++
-			case "deleted":
-				pc.Deleted++
+				lc, _ := LoadLocalCache(pp)
+				changes := DiffManifests(cur, lc.Hashes())
+				if len(changes) == 0 {
+					continue
+				}
+				pc := summarizeChanges(p.Name, pp, changes)
+				results[i] = &pc
 			}
+		}()
+	}
+	for i := range projs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	out := make([]ProjectChange, 0, len(projs))
+	for _, pc := range results {
+		if pc != nil {
+			out = append(out, *pc)
 		}
-		pc.Total = pc.Added + pc.Modified + pc.Deleted
-		out = append(out, pc)
 	}
 
 	// Deterministic ordering helps the UI and tests (prevents list jitter)
@@ -65,3 +91,84 @@ func ChangedProjectsSinceCache(root string) ([]ProjectChange, error) {
 
 	return out, nil
 }
+
+// ProjectChangesSinceCache is the single-project version of
+// ChangedProjectsSinceCache. The result has Total == 0 when nothing changed.
+func ProjectChangesSinceCache(name, path string) (ProjectChange, error) {
+	ps, err := BuildManifest(path)
+	if err != nil {
+		return ProjectChange{Name: name, Path: path}, err
+	}
+	lc, _ := LoadLocalCache(path)
+	return summarizeChanges(name, path, DiffManifests(ManifestFromState(ps), lc.Hashes())), nil
+}
+
+// LocalManifests is the two sides ProjectChangesSinceCache diffs: the files
+// on disk (path -> hash, plus sizes) and the cache.json baseline. An
+// unreadable cache is logged and treated as empty. The GUI's change detector
+// is handed this so it reports exactly what the CLI does.
+func LocalManifests(projectPath string) (current, baseline map[string]string, sizes map[string]int64, err error) {
+	ps, err := BuildManifest(projectPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	current = ManifestFromState(ps)
+	sizes = make(map[string]int64, len(ps.Files))
+	for _, f := range ps.Files {
+		sizes[normalizeKey(f.Path)] = f.Size
+	}
+	baseline = map[string]string{}
+	if lc, err := LoadLocalCache(projectPath); err != nil {
+		log.Printf("changes %s: %v", projectPath, err)
+	} else {
+		baseline = lc.Hashes()
+	}
+	return current, baseline, sizes, nil
+}
+
+// DiffAgainstRemote diffs the files on disk against remoteProject's HEAD
+// instead of the local cache: what differs from what's actually pushed,
+// even when the cache is stale or missing. A project with no commits yet
+// shows everything as added.
+func DiffAgainstRemote(ctx context.Context, meta *remote.MetaStore, projectPath, remoteProject string) ([]FileChange, error) {
+	ps, err := BuildManifest(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	head, _, err := meta.GetLatestState(ctx, remoteProject)
+	if err != nil {
+		return nil, fmt.Errorf("diff: read %q head: %w", remoteProject, err)
+	}
+	var pushed map[string]string
+	if head != nil {
+		local, theirs := ps.Algo, head.Algo
+		if local == "" {
+			local = "sha256"
+		}
+		if theirs == "" {
+			theirs = "sha256"
+		}
+		if local != theirs {
+			return nil, fmt.Errorf("diff: remote HEAD uses %s hashes, local files %s", theirs, local)
+		}
+		pushed = ManifestFromState(*head)
+	}
+	return DiffManifests(ManifestFromState(ps), pushed), nil
+}
+
+// summarizeChanges counts a project's file changes by type.
+func summarizeChanges(name, path string, changes []FileChange) ProjectChange {
+	pc := ProjectChange{Name: name, Path: path}
+	for _, c := range changes {
+		switch c.Type {
+		case "added":
+			pc.Added++
+		case "modified":
+			pc.Modified++
+		case "deleted":
+			pc.Deleted++
+		}
+	}
+	pc.Total = pc.Added + pc.Modified + pc.Deleted
+	return pc
+}
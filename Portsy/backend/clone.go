@@ -0,0 +1,88 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CloneProject creates newProject as a separate remote project whose first
+// commit has srcCommit's file list ("" = HEAD, short IDs allowed), e.g. to
+// start a remix from a finished version.
+//
+// Nothing is re-uploaded. The clone keeps the source state's key scheme, so
+// under KeySchemeGlobalBlobs the blobs are simply shared; per-project blobs
+// are server-side copied into newProject's namespace. Fails if newProject
+// already exists.
+func CloneProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, srcProject, srcCommit, newProject string) error {
+	srcCommit, err := resolveCommitID(ctx, meta, srcProject, srcCommit)
+	if err != nil {
+		return fmt.Errorf("clone: %w", err)
+	}
+	var (
+		st *ProjectState
+		cm *CommitMeta
+	)
+	if srcCommit == "" {
+		st, cm, err = meta.GetLatestState(ctx, srcProject)
+	} else {
+		st, cm, err = meta.GetStateByCommit(ctx, srcProject, srcCommit)
+	}
+	if err != nil {
+		return fmt.Errorf("clone: %w", err)
+	}
+	if st == nil || cm == nil {
+		return fmt.Errorf("clone: project %q has no commits", srcProject)
+	}
+	if cm.Status == "pending" {
+		return fmt.Errorf("clone: commit %s is still pending", cm.ID)
+	}
+
+	// Register the name first so a clash fails before any copying.
+	if _, err := meta.CreateProject(ctx, newProject); err != nil {
+		return fmt.Errorf("clone: %w", err)
+	}
+	scheme := st.KeyScheme.Resolve()
+	if err := meta.SetKeyScheme(ctx, newProject, scheme); err != nil {
+		return fmt.Errorf("clone: set key scheme: %w", err)
+	}
+
+	copied := map[string]bool{}
+	for i := range st.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fe := &st.Files[i]
+		from := r2.KeyFor(st, srcProject, *fe)
+		to := r2.BuildKeyScheme(scheme, newProject, fe.Hash)
+		if from != to && !copied[to] {
+			if err := r2.CopyIfMissing(ctx, from, to); err != nil {
+				return fmt.Errorf("clone: copy %s -> %s: %w", from, to, err)
+			}
+			copied[to] = true
+		}
+		fe.R2Key = to
+	}
+
+	st.ProjectName = newProject
+	st.ProjectPath = ""
+	st.KeyScheme = scheme
+	st.CreatedAt = time.Now().Unix()
+	clone := CommitMeta{
+		ID:        uuid.NewString(),
+		Message:   fmt.Sprintf("Clone of %s@%s", srcProject, ShortCommitID(cm.ID)),
+		Timestamp: time.Now().Unix(),
+		Status:    "pending",
+	}
+	if err := meta.BeginCommit(ctx, newProject, clone, *st); err != nil {
+		return fmt.Errorf("clone: begin commit: %w", err)
+	}
+	if err := meta.FinalizeCommit(ctx, newProject, clone, *st, r2.BlobVerifier(scheme, newProject)); err != nil {
+		return fmt.Errorf("clone: finalize commit: %w", err)
+	}
+	_ = meta.RecordAudit(ctx, newProject, remote.AuditClone, srcProject+"@"+cm.ID) // best-effort
+	return nil
+}
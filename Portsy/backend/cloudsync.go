@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// cloudSyncDirs are folder names that sync clients create as their root.
+// OneDrive business roots look like "OneDrive - Contoso", matched by prefix.
+var cloudSyncDirs = map[string]string{
+	"dropbox":             "Dropbox",
+	"onedrive":            "OneDrive",
+	"google drive":        "Google Drive",
+	"my drive":            "Google Drive",
+	"icloud drive":        "iCloud Drive",
+	"com~apple~clouddocs": "iCloud Drive",
+}
+
+// cloudSyncMarkers are files/dirs a sync client drops at (or under) its root.
+var cloudSyncMarkers = map[string]string{
+	".dropbox":           "Dropbox",
+	".dropbox.cache":     "Dropbox",
+	".tmp.drivedownload": "Google Drive",
+	".tmp.driveupload":   "Google Drive",
+}
+
+// DetectCloudSyncConflict reports whether projectPath lives inside a folder
+// managed by Dropbox, OneDrive, Google Drive or iCloud. Those clients lock and
+// rewrite files behind our back, which shows up as partial writes and phantom
+// diffs. The returned string is a user-facing warning ("" when ok is false).
+func DetectCloudSyncConflict(projectPath string) (string, bool) {
+	abs, err := filepath.Abs(projectPath)
+	if err != nil {
+		return "", false
+	}
+	abs = filepath.Clean(abs)
+
+	// Windows exposes the OneDrive roots via env; cheapest and most reliable check.
+	if runtime.GOOS == "windows" {
+		for _, env := range []string{"OneDrive", "OneDriveConsumer", "OneDriveCommercial"} {
+			if root := os.Getenv(env); root != "" && pathWithin(abs, root) {
+				return cloudSyncWarning("OneDrive", root), true
+			}
+		}
+	}
+
+	for dir := abs; ; {
+		base := strings.ToLower(filepath.Base(dir))
+		if svc, ok := cloudSyncDirs[base]; ok {
+			return cloudSyncWarning(svc, dir), true
+		}
+		if strings.HasPrefix(base, "onedrive - ") {
+			return cloudSyncWarning("OneDrive", dir), true
+		}
+		// macOS File Provider: ~/Library/CloudStorage/<Provider>-<account>
+		if parent := filepath.Dir(dir); strings.EqualFold(filepath.Base(parent), "CloudStorage") {
+			return cloudSyncWarning(cloudStorageProvider(base), dir), true
+		}
+		for marker, svc := range cloudSyncMarkers {
+			if _, err := os.Lstat(filepath.Join(dir, marker)); err == nil {
+				return cloudSyncWarning(svc, dir), true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return "", false
+}
+
+func cloudStorageProvider(base string) string {
+	switch {
+	case strings.HasPrefix(base, "dropbox"):
+		return "Dropbox"
+	case strings.HasPrefix(base, "onedrive"):
+		return "OneDrive"
+	case strings.HasPrefix(base, "googledrive"):
+		return "Google Drive"
+	}
+	return "a cloud-sync client"
+}
+
+func cloudSyncWarning(service, root string) string {
+	return fmt.Sprintf("project is inside a folder synced by %s (%s); the sync client can lock or rewrite files while Portsy reads them. Move the project outside the synced folder.", service, root)
+}
+
+// pathWithin reports whether p is root or below it (case-insensitive on Windows).
+func pathWithin(p, root string) bool {
+	root = filepath.Clean(root)
+	if runtime.GOOS == "windows" {
+		p, root = strings.ToLower(p), strings.ToLower(root)
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -2,6 +2,7 @@ package backend
 
 import (
 	corehash "Portsy/backend/internal/core/hash"
+	"Portsy/backend/internal/core/scan"
 	"bufio"
 	"bytes"
 	"compress/gzip"
@@ -17,13 +18,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	stdruntime "runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
-	wruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 var (
@@ -49,26 +49,39 @@ type ALSLogicalDiff struct {
 		RemovedClips []string `json:"removedClips"`
 		ChangedClips []string `json:"changedClips"`
 	} `json:"midi"`
+
+	// ParseError is set when either side's .als couldn't be decoded (corrupt,
+	// truncated, or a Live version we don't understand). The lists above are
+	// then incomplete or empty, rather than meaning "nothing changed".
+	ParseError string `json:"parseError,omitempty"`
 }
 
 type HashLookup func(relPath string) string
 
 // ComputeALSLogicalDiff compares PREV vs CURR ALS content and produces a logical diff.
+// Decode failures are reported in ALSLogicalDiff.ParseError, not as an error.
 // - prevALS: ungzipped XML bytes of previously committed .als (pass nil if none)
 // - crrALSPath: path to CURR .als (gzipped on disk). We'll ungzip internally.
 // - projectRoot: needed to resolve realtive sample paths and hash current sample files.
 // - prevHash: lookup function to get previous content hash for a sample rel path (from your last commit manifest)
 func ComputeALSLogicalDiff(prevALS []byte, currALSPath, projectRoot string, prevHash HashLookup) (*ALSLogicalDiff, error) {
+	diff := &ALSLogicalDiff{}
 	currXML, err := ungzipALS(currALSPath)
 	if err != nil {
-		return nil, err
+		diff.ParseError = fmt.Sprintf("current project file: %v", err)
+		return diff, nil
+	}
+	prevIdx, prevErr := buildALSIndex(prevALS, projectRoot)
+	currIdx, currErr := buildALSIndex(currXML, projectRoot)
+	switch {
+	case currErr != nil:
+		diff.ParseError = fmt.Sprintf("current project file: %v", currErr)
+	case prevErr != nil:
+		diff.ParseError = fmt.Sprintf("previous project file: %v", prevErr)
 	}
-	prevIdx := buildALSIndex(prevALS, projectRoot)
-	currIdx := buildALSIndex(currXML, projectRoot)
 
 	// Samples add/remove
 	ps, cs := toSet(prevIdx.samplePaths), toSet(currIdx.samplePaths)
-	diff := &ALSLogicalDiff{}
 	for p := range cs {
 		if _, ok := ps[p]; !ok {
 			diff.Samples.Added = append(diff.Samples.Added, p)
@@ -119,28 +132,51 @@ func ComputeALSLogicalDiff(prevALS []byte, currALSPath, projectRoot string, prev
 	return diff, nil
 }
 
+// DiffALSFiles is ComputeALSLogicalDiff for two .als files on disk (say the
+// current set and a backup), no R2 needed. With no manifest for the older
+// file, sample content changes can't be told apart: Samples.Changed stays
+// empty. projectRoot resolves relative sample paths ("" = currALSPath's dir).
+func DiffALSFiles(prevALSPath, currALSPath, projectRoot string) (*ALSLogicalDiff, error) {
+	for _, p := range []string{prevALSPath, currALSPath} {
+		if _, err := os.Stat(p); err != nil {
+			return nil, fmt.Errorf("als diff: %w", err)
+		}
+	}
+	if projectRoot == "" {
+		projectRoot = filepath.Dir(currALSPath)
+	}
+	prevXML, prevErr := ungzipALS(prevALSPath)
+	diff, err := ComputeALSLogicalDiff(prevXML, currALSPath, projectRoot, nil)
+	if err == nil && diff.ParseError == "" && prevErr != nil {
+		diff.ParseError = fmt.Sprintf("previous project file: %v", prevErr)
+	}
+	return diff, err
+}
+
 type alsIndex struct {
 	samplePaths []string          // normalized, relaive if under project
 	midiHash    map[string]string // clip-name -> sha256(notes-subtree)
 }
 
 // buildALSIndex constructs an alsIndex from UNGZIPPED xml bytes.
-// If xml==nil, returns an empty index.
-func buildALSIndex(xml []byte, projectRoot string) alsIndex {
+// If xml==nil, returns an empty index. On a decode error the index holds
+// whatever was read before it.
+func buildALSIndex(xml []byte, projectRoot string) (alsIndex, error) {
 	idx := alsIndex{
 		samplePaths: nil,
 		midiHash:    map[string]string{},
 	}
 	if len(xml) == 0 {
-		return idx
+		return idx, nil
 	}
 	// 1) samples: reuse existing extractor
 	paths := extractSamplePaths(xml)
 	idx.samplePaths = normalizeRelPaths(paths, projectRoot)
 
 	// 2) MIDI: hash each MidiCLips Notes subtree
-	idx.midiHash = midiNotesHashes(xml)
-	return idx
+	var err error
+	idx.midiHash, err = midiNotesHashes(xml)
+	return idx, err
 }
 
 func normalizeRelPaths(paths []string, projectRoot string) []string {
@@ -162,9 +198,7 @@ func normalizeRelPaths(paths []string, projectRoot string) []string {
 			// keep as-is if not under project (still useful for UI)
 			pp = filepath.ToSlash(pp)
 		}
-		if stdruntime.GOOS == "windows" {
-			pp = strings.ToLower(pp)
-		}
+		pp = scan.FoldCase(pp)
 		if _, ok := seen[pp]; ok {
 			continue
 		}
@@ -198,7 +232,9 @@ func alreadyImported(importDir, srcHash string) bool {
 	return false
 }
 
-func midiNotesHashes(xmlBytes []byte) map[string]string {
+// midiNotesHashes walks the whole document, so it also validates it: the root
+// must be <Ableton> and the XML must decode to the end.
+func midiNotesHashes(xmlBytes []byte) (map[string]string, error) {
 	out := map[string]string{}
 	dec := xml.NewDecoder(bytes.NewReader(xmlBytes))
 	dec.Strict = false
@@ -212,16 +248,23 @@ func midiNotesHashes(xmlBytes []byte) map[string]string {
 		return "", false
 	}
 
+	sawRoot := false
 	for {
 		tok, err := dec.Token()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return out
+			return out, fmt.Errorf("decode XML: %w", err)
 		}
 		switch t := tok.(type) {
 		case xml.StartElement:
+			if !sawRoot {
+				if t.Name.Local != "Ableton" {
+					return out, fmt.Errorf("unexpected root element <%s> (not an Ableton Live set?)", t.Name.Local)
+				}
+				sawRoot = true
+			}
 			if t.Name.Local == "MidiClip" {
 				var name string
 				h := sha256.New()
@@ -280,7 +323,10 @@ func midiNotesHashes(xmlBytes []byte) map[string]string {
 			}
 		}
 	}
-	return out
+	if !sawRoot {
+		return out, errors.New("no <Ableton> root element")
+	}
+	return out, nil
 }
 
 func hashCurrentSample(projectRoot, relOrAbs string) string {
@@ -305,14 +351,50 @@ func hashCurrentSample(projectRoot, relOrAbs string) string {
 // We do NOT modify the .als. We keep the original .als on disk.
 // The ungzipped XML is never written to disk (memory only).
 func CollectNewSamples(ctx context.Context, projectPath, alsPath string) ([]string, error) {
+	res, err := CollectNewSamplesResult(ctx, projectPath, alsPath)
+	if res == nil {
+		return nil, err
+	}
+	return res.Copied, err
+}
+
+// Reasons a referenced sample was not copied (SkippedSample.Reason).
+const (
+	SkipAlreadyImported  = "already in Samples/Imported"
+	SkipInProject        = "already inside the project"
+	SkipDuplicateContent = "same content as another sample"
+	SkipUnreadable       = "unreadable"
+	SkipCopyFailed       = "copy failed"
+)
+
+// SkippedSample is a referenced sample that resolved but wasn't copied.
+type SkippedSample struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// CollectResult is the full outcome of a collect pass.
+// Unresolved lists references whose file doesn't exist on this machine:
+// the project will open with missing samples, so surface these loudly.
+type CollectResult struct {
+	Copied     []string        `json:"copied"`
+	Skipped    []SkippedSample `json:"skipped,omitempty"`
+	Unresolved []string        `json:"unresolved,omitempty"`
+}
+
+// CollectNewSamplesResult is CollectNewSamples with every non-copied
+// reference accounted for (see CollectResult).
+func CollectNewSamplesResult(ctx context.Context, projectPath, alsPath string) (*CollectResult, error) {
 	xmlBytes, err := ungzipALS(alsPath)
 	if err != nil {
 		return nil, fmt.Errorf("ungzip als: %w", err)
 	}
 
+	res := &CollectResult{Copied: []string{}}
 	paths := extractSamplePaths(xmlBytes)
 	if len(paths) == 0 {
-		return nil, nil
+		return res, nil
 	}
 
 	importDir := filepath.Join(projectPath, "Samples", "Imported")
@@ -320,50 +402,44 @@ func CollectNewSamples(ctx context.Context, projectPath, alsPath string) ([]stri
 		return nil, fmt.Errorf("mkdir Imported: %w", err)
 	}
 
-	copied := make([]string, 0, len(paths))
+	tempDir := projectTempDir(projectPath, "")
 	seenHash := map[string]struct{}{}
+	skip := func(abs, reason, detail string) {
+		res.Skipped = append(res.Skipped, SkippedSample{Path: abs, Reason: reason, Detail: detail})
+	}
 
 	for _, p := range paths {
 		select {
 		case <-ctx.Done():
-			return copied, ctx.Err()
+			return res, ctx.Err()
 		default:
 		}
 
-		// Normalize & absolutize
-		abs := p
-		if !filepath.IsAbs(abs) {
-			abs = filepath.Join(projectPath, filepath.FromSlash(p))
-		}
-		abs = filepath.Clean(abs)
-
-		// Skip non-existent files quietly
-		srcInfo, err := os.Stat(abs)
-		if err != nil || srcInfo.IsDir() {
-			continue
-		}
-
-		// If already under Samples/Imported, skip
-		if isSubpath(abs, importDir) {
+		abs, _, reason := resolveSample(p, projectPath, importDir)
+		switch reason {
+		case "":
+		case sampleMissing:
+			res.Unresolved = append(res.Unresolved, abs)
 			continue
-		}
-		// If already inside the project (but not in Samples/**), we *currently* skip copying;
-		// Portsy will sync it anyway. Flip this if you prefer strict collecting.
-		if isSubpath(abs, projectPath) && !strings.Contains(strings.ToLower(abs), string(filepath.Separator)+"samples"+string(filepath.Separator)) {
+		default:
+			skip(abs, reason, "")
 			continue
 		}
 
 		// Dedup by content hash
 		srcHash, err := fileSHA256(abs)
 		if err != nil {
+			skip(abs, SkipUnreadable, err.Error())
 			continue
 		}
 		if _, ok := seenHash[srcHash]; ok {
+			skip(abs, SkipDuplicateContent, "")
 			continue
 		}
 
 		if alreadyImported(importDir, srcHash) {
 			seenHash[srcHash] = struct{}{}
+			skip(abs, SkipAlreadyImported, "")
 			continue
 		}
 
@@ -374,19 +450,123 @@ func CollectNewSamples(ctx context.Context, projectPath, alsPath string) ([]stri
 		if dstInfo, err := os.Stat(destPath); err == nil && !dstInfo.IsDir() {
 			if dstHash, _ := fileSHA256(destPath); dstHash == srcHash {
 				seenHash[srcHash] = struct{}{}
+				skip(abs, SkipAlreadyImported, "")
 				continue
 			}
 			destPath = nextSuffixPath(importDir, destBase)
 		}
 
-		if err := copyFile(abs, destPath); err != nil {
+		if err := copyFileVia(abs, destPath, tempDir); err != nil {
+			skip(abs, SkipCopyFailed, err.Error())
 			continue
 		}
 		seenHash[srcHash] = struct{}{}
-		copied = append(copied, destPath)
+		res.Copied = append(res.Copied, destPath)
 	}
 
-	return copied, nil
+	return res, nil
+}
+
+// PreviewSample is one referenced sample as PreviewCollect sees it.
+type PreviewSample struct {
+	Ref       string `json:"ref"`      // as written in the .als
+	Resolved  string `json:"resolved"` // absolute path on this machine
+	Exists    bool   `json:"exists"`
+	InProject bool   `json:"inProject"` // already inside the project / Samples/Imported
+	Size      int64  `json:"size"`
+}
+
+// CollectPreview summarizes what a collect pass would do.
+type CollectPreview struct {
+	Samples      []PreviewSample `json:"samples"`
+	External     int             `json:"external"`     // would be collected
+	ExternalSize int64           `json:"externalSize"` // bytes those would add
+	Missing      int             `json:"missing"`
+}
+
+// PreviewCollect is the read-only half of CollectNewSamples: same extraction
+// and resolution, no copies, no mkdir. It doesn't hash, so a sample that is
+// byte-identical to something already imported still counts as external.
+// alsPath "" means the project's top-level .als.
+func PreviewCollect(alsPath, projectPath string) (*CollectPreview, error) {
+	if alsPath == "" {
+		p, err := findTopLevelALS(projectPath)
+		if err != nil {
+			return nil, err
+		}
+		alsPath = p
+	}
+	xmlBytes, err := ungzipALS(alsPath)
+	if err != nil {
+		return nil, fmt.Errorf("ungzip als: %w", err)
+	}
+	importDir := filepath.Join(projectPath, "Samples", "Imported")
+
+	pv := &CollectPreview{Samples: []PreviewSample{}}
+	for _, ref := range extractSamplePaths(xmlBytes) {
+		abs, info, reason := resolveSample(ref, projectPath, importDir)
+		s := PreviewSample{Ref: ref, Resolved: abs}
+		if info != nil {
+			s.Exists = true
+			s.Size = info.Size()
+		}
+		switch reason {
+		case sampleMissing:
+			pv.Missing++
+		case SkipAlreadyImported, SkipInProject:
+			s.InProject = true
+		default:
+			pv.External++
+			pv.ExternalSize += s.Size
+		}
+		pv.Samples = append(pv.Samples, s)
+	}
+	return pv, nil
+}
+
+// sampleMissing is resolveSample's reason for a reference with no file behind it.
+const sampleMissing = "missing"
+
+// resolveSample absolutizes a referenced path and decides whether it is a
+// copy candidate. reason is "" for candidates, sampleMissing when nothing is
+// on disk, or one of the Skip* reasons.
+func resolveSample(p, projectPath, importDir string) (abs string, info os.FileInfo, reason string) {
+	// Normalize & absolutize
+	abs = p
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(projectPath, filepath.FromSlash(p))
+	}
+	abs = filepath.Clean(abs)
+
+	info, err := os.Stat(longPath(abs))
+	if err != nil || info.IsDir() {
+		return abs, nil, sampleMissing
+	}
+
+	// If already under Samples/Imported, skip
+	if isSubpath(abs, importDir) {
+		return abs, info, SkipAlreadyImported
+	}
+	// If already inside the project (but not in Samples/**), we *currently* skip copying;
+	// Portsy will sync it anyway. Flip this if you prefer strict collecting.
+	if isSubpath(abs, projectPath) && !strings.Contains(strings.ToLower(abs), string(filepath.Separator)+"samples"+string(filepath.Separator)) {
+		return abs, info, SkipInProject
+	}
+	return abs, info, ""
+}
+
+// ErrCorruptALS means an .als doesn't decompress, or decompresses to too
+// little to be a Live set: typically a save cut short by a crash.
+var ErrCorruptALS = errors.New("corrupt .als")
+
+// minALSXMLSize is well below the XML of an empty Live set (tens of KiB).
+const minALSXMLSize = 4 << 10
+
+// CheckALS reports whether alsPath is a readable Live set. Damage is an
+// ErrCorruptALS; other errors (file missing, permissions) are returned as is.
+func CheckALS(alsPath string) error {
+	_, err := ungzipALS(alsPath)
+	return err
 }
 
 func ungzipALS(alsPath string) ([]byte, error) {
@@ -396,15 +576,21 @@ func ungzipALS(alsPath string) ([]byte, error) {
 	}
 	defer f.Close()
 
+	corrupt := func(detail any) error {
+		return fmt.Errorf("%s: %w: %v", filepath.Base(alsPath), ErrCorruptALS, detail)
+	}
 	gr, err := gzip.NewReader(bufio.NewReader(f))
 	if err != nil {
-		return nil, err
+		return nil, corrupt(err)
 	}
 	defer gr.Close()
 
 	var buf bytes.Buffer
 	if _, err := io.Copy(&buf, gr); err != nil {
-		return nil, err
+		return nil, corrupt(err) // truncated stream or bad checksum
+	}
+	if buf.Len() < minALSXMLSize {
+		return nil, corrupt(fmt.Sprintf("only %d bytes of XML", buf.Len()))
 	}
 	return buf.Bytes(), nil
 }
@@ -490,6 +676,7 @@ func nextSuffixPath(dir, base string) string {
 }
 
 func copyFile(src, dst string) error {
+	src, dst = longPath(src), longPath(dst)
 	in, err := os.Open(src)
 	if err != nil {
 		return err
@@ -511,8 +698,22 @@ func copyFile(src, dst string) error {
 	return out.Sync()
 }
 
+// copyFileVia copies src to dst through a temp file in tempDir ("" = next
+// to dst) and a rename, so a half-copied sample never appears under dst.
+func copyFileVia(src, dst, tempDir string) error {
+	in, err := os.Open(longPath(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileAtomicIn(tempDir, dst, func(out *os.File) error {
+		_, err := io.Copy(out, in)
+		return err
+	})
+}
+
 func fileSHA256(p string) (string, error) {
-	return corehash.FileHash(p)
+	return corehash.FileHash(longPath(p))
 }
 
 func isSubpath(child, parent string) bool {
@@ -529,6 +730,8 @@ func isSubpath(child, parent string) bool {
 
 // WatchAllProjects watches 'root' for any immediate child folder that contains a top-level .als.
 // It spawns a WatchProjectALS for each, and picks up new projects created later.
+// Once ctx is done it returns only after every project watcher has exited,
+// including any onSave call (collect, push) still running.
 func WatchAllProjects(
 	ctx context.Context,
 	root string,
@@ -546,24 +749,51 @@ func WatchAllProjects(
 		return fmt.Errorf("watch root: %w", err)
 	}
 
-	type cancelFn = context.CancelFunc
-	watchers := map[string]cancelFn{} // key: projectPath
+	// watchers is shared by the select loop and the debounced rescan (a
+	// timer goroutine), and entries remove themselves when their watcher
+	// exits (e.g. the folder was deleted), so a re-created project gets a
+	// fresh watcher. All access goes through mu; start is safe to call from
+	// any goroutine.
+	type watcher struct{ cancel context.CancelFunc }
+	var (
+		mu       sync.Mutex
+		watchers = map[string]*watcher{} // key: projectPath
+		wg       sync.WaitGroup          // one per WatchProjectALS goroutine
+	)
 
 	start := func(projectPath string) {
 		projectPath = filepath.Clean(projectPath)
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return // shutting down; don't outlive the loop
+		}
 		if _, ok := watchers[projectPath]; ok {
 			return
 		}
 		name := filepath.Base(projectPath)
-		wruntime.EventsEmit(ctx, "log", fmt.Sprintf("[WatchAll] start %s (%s)", name, projectPath))
+		emitLog(ctx, fmt.Sprintf("[WatchAll] start %s (%s)", name, projectPath))
 		log.Printf("[WatchAll] start %s (%s)", name, projectPath)
+		if warn, ok := DetectCloudSyncConflict(projectPath); ok {
+			log.Printf("[WatchAll] WARNING %s: %s", name, warn)
+			emitLog(ctx, fmt.Sprintf("[WatchAll] WARNING %s: %s", name, warn))
+		}
 
 		cctx, cancel := context.WithCancel(ctx)
-		watchers[projectPath] = cancel
+		self := &watcher{cancel: cancel}
+		watchers[projectPath] = self
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			err := WatchProjectALS(cctx, name, projectPath, debounce, onSave)
+			cancel()
+			mu.Lock()
+			if watchers[projectPath] == self {
+				delete(watchers, projectPath)
+			}
+			mu.Unlock()
 			log.Printf("[WatchAll] WatchProjectALS exit %s err=%v", name, err)
-			wruntime.EventsEmit(ctx, "log", fmt.Sprintf("[WatchAll] WatchProjectALS exit %s err=%v", name, err))
+			emitLog(ctx, fmt.Sprintf("[WatchAll] WatchProjectALS exit %s err=%v", name, err))
 		}()
 	}
 
@@ -575,7 +805,7 @@ func WatchAllProjects(
 	}
 
 	// DEBUG_________________________________
-	wruntime.EventsEmit(ctx, "log", "[WatchAll] initial scan complete")
+	emitLog(ctx, "[WatchAll] initial scan complete")
 
 	// Debounced rescan on root changes
 	var rescanT *time.Timer
@@ -592,14 +822,20 @@ func WatchAllProjects(
 		})
 	}
 
-	wruntime.EventsEmit(ctx, "log", "[WatchAll] rescan triggered")
+	emitLog(ctx, "[WatchAll] rescan triggered")
 
 	for {
 		select {
 		case <-ctx.Done():
-			for _, cancel := range watchers {
-				cancel()
+			if rescanT != nil {
+				rescanT.Stop()
+			}
+			mu.Lock()
+			for _, wt := range watchers {
+				wt.cancel()
 			}
+			mu.Unlock()
+			wg.Wait() // start refuses new watchers once ctx is done
 			return ctx.Err()
 		case ev := <-w.Events:
 			// Any creation/rename of an .als one level below the root triggers rescan
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package backend
+
+import "syscall"
+
+var errCrossDevice error = syscall.EXDEV
@@ -5,11 +5,13 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"Portsy/backend/internal/midi"
 	syn "Portsy/backend/internal/sync"
 )
 
@@ -29,9 +31,19 @@ type DiffJSON struct {
 	Changed []DiffPath      `json:"changed"` // Corresponds to "modified"
 	Removed []DiffPath      `json:"removed"`
 	Logical *ALSLogicalDiff `json:"logical,omitempty"`
+	MIDI    []MIDIFileDiff  `json:"midi,omitempty"` // note-level view of changed .mid files
 }
 
-// BuildDiffJSON produces UI-ready diff output, including ALS logical info if possible.
+// MIDIFileDiff is the note-level diff of one changed standalone .mid file.
+type MIDIFileDiff struct {
+	Path         string `json:"path"`
+	NotesAdded   int    `json:"notesAdded"`
+	NotesRemoved int    `json:"notesRemoved"`
+	TempoChanged bool   `json:"tempoChanged"`
+	Summary      string `json:"summary"` // e.g. "+5 notes, tempo unchanged"
+}
+
+// BuildDiffJSON produces UI-ready diff output, including ALS and MIDI logical info if possible.
 // - projectName: used to build the R2 key for prev ALS
 // - projectPath: local path to the project folder
 // - current: current manifest (path -> sha) computed from disk
@@ -68,6 +80,9 @@ func BuildDiffJSON(
 		out.Logical = logical
 	}
 
+	// Note-level diffs for changed .mid files (non-fatal, per file).
+	out.MIDI = enrichMIDI(ctx, projectName, projectPath, cached, blobs, changedPaths)
+
 	// Deterministic ordering
 	sort.Slice(out.Added, func(i, j int) bool { return out.Added[i].Path < out.Added[j].Path })
 	sort.Slice(out.Changed, func(i, j int) bool { return out.Changed[i].Path < out.Changed[j].Path })
@@ -111,6 +126,7 @@ func enrichALS(
 
 	// prev ALS (ungzipped XML) from R2 using cached manifest hash (if any)
 	var prevXML []byte
+	var prevErr error // the previous ALS was fetched but couldn't be decoded
 	if prevSHA := cached[alsRel]; prevSHA != "" {
 		key := BuildR2Key(projectName, alsRel, prevSHA)
 		var gz bytes.Buffer
@@ -122,10 +138,15 @@ func enrichALS(
 			if err == nil {
 				defer gr.Close()
 				limited := io.LimitReader(gr, maxALS+1)
-				b, _ := io.ReadAll(limited)
-				if int64(len(b)) <= maxALS {
+				b, rerr := io.ReadAll(limited)
+				switch {
+				case rerr != nil:
+					prevErr = rerr
+				case int64(len(b)) <= maxALS:
 					prevXML = b
 				}
+			} else {
+				prevErr = err
 			}
 		}
 	}
@@ -142,7 +163,59 @@ func enrichALS(
 	// current ALS path on disk (gz)
 	currALSPath := filepath.Join(projectPath, filepath.FromSlash(alsRel))
 
-	return ComputeALSLogicalDiff(prevXML, currALSPath, projectPath, prevHash)
+	diff, err := ComputeALSLogicalDiff(prevXML, currALSPath, projectPath, prevHash)
+	if err == nil && diff.ParseError == "" && prevErr != nil {
+		diff.ParseError = fmt.Sprintf("previous project file: %v", prevErr)
+	}
+	return diff, err
+}
+
+// enrichMIDI diffs each changed .mid against its previously synced version,
+// fetched from R2 by the cached hash. Files we can't fetch or parse are skipped.
+func enrichMIDI(
+	ctx context.Context,
+	projectName, projectPath string,
+	cached map[string]string,
+	blobs ObjectGetter,
+	changedPaths []string,
+) []MIDIFileDiff {
+	if blobs == nil {
+		return nil
+	}
+	var out []MIDIFileDiff
+	for _, rel := range changedPaths {
+		if !midi.IsMIDI(rel) {
+			continue
+		}
+		prevSHA := cached[rel]
+		if prevSHA == "" {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := blobs.DownloadTo(ctx, BuildR2Key(projectName, rel, prevSHA), &buf); err != nil {
+			continue
+		}
+		prev, err := midi.Parse(&buf)
+		if err != nil {
+			continue
+		}
+		curr, err := midi.Read(filepath.Join(projectPath, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+
+		d := midi.Compare(prev, curr)
+		out = append(out, MIDIFileDiff{
+			Path:         rel,
+			NotesAdded:   d.NotesAdded,
+			NotesRemoved: d.NotesRemoved,
+			TempoChanged: d.TempoChanged,
+			Summary:      d.Summary(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
 }
 
 // topLevelALS picks the main .als: a .als directly under the project root (not in subfolders or Backup/).
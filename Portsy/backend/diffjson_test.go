@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// memBlobs is an ObjectGetter over a map of key -> bytes.
+type memBlobs map[string][]byte
+
+func (m memBlobs) DownloadTo(_ context.Context, key string, w io.Writer) error {
+	b, ok := m[key]
+	if !ok {
+		return errors.New("no such key")
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// testMIDI is a one-track SMF at 96 ticks per quarter holding a tempo and
+// the given events (delta time first), followed by end of track.
+func testMIDI(microsPQN uint32, events ...byte) []byte {
+	trk := []byte{0x00, 0xFF, 0x51, 0x03, byte(microsPQN >> 16), byte(microsPQN >> 8), byte(microsPQN)}
+	trk = append(trk, events...)
+	trk = append(trk, 0x00, 0xFF, 0x2F, 0x00)
+	out := []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0, 96}
+	out = append(out, 'M', 'T', 'r', 'k', 0, 0, byte(len(trk)>>8), byte(len(trk)))
+	return append(out, trk...)
+}
+
+func TestBuildDiffJSONMIDI(t *testing.T) {
+	c4 := []byte{0x00, 0x90, 60, 100, 0x60, 0x80, 60, 0}
+	e4 := []byte{0x00, 0x90, 64, 100, 0x60, 0x80, 64, 0}
+	g4 := []byte{0x00, 0x90, 67, 100, 0x60, 0x80, 67, 0}
+
+	tests := []struct {
+		name string
+		prev []byte // committed version in R2; nil: not there
+		curr []byte
+		want []MIDIFileDiff
+	}{
+		{
+			name: "note added",
+			prev: testMIDI(500000, slices.Concat(c4, e4)...),
+			curr: testMIDI(500000, slices.Concat(c4, e4, g4)...),
+			want: []MIDIFileDiff{{Path: "Parts/bass.mid", NotesAdded: 1, Summary: "+1 note, tempo unchanged"}},
+		},
+		{
+			name: "note replaced and tempo changed",
+			prev: testMIDI(500000, slices.Concat(c4, e4)...),
+			curr: testMIDI(600000, slices.Concat(c4, g4)...),
+			want: []MIDIFileDiff{{Path: "Parts/bass.mid", NotesAdded: 1, NotesRemoved: 1, TempoChanged: true, Summary: "+1 note, -1 note, tempo changed"}},
+		},
+		{
+			name: "same notes, new bytes",
+			prev: testMIDI(500000, slices.Concat(c4, e4)...),
+			curr: testMIDI(500000, slices.Concat([]byte{0x00, 0xB0, 7, 100}, c4, e4)...), // plus a volume CC
+			want: []MIDIFileDiff{{Path: "Parts/bass.mid", Summary: "notes unchanged, tempo unchanged"}},
+		},
+		{
+			name: "previous version missing",
+			curr: testMIDI(500000, c4...),
+		},
+		{
+			name: "previous version malformed",
+			prev: testMIDI(500000, c4...)[:20],
+			curr: testMIDI(500000, c4...),
+		},
+		{
+			name: "current version malformed",
+			prev: testMIDI(500000, c4...),
+			curr: []byte("not midi"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, filepath.Join(dir, "Parts", "bass.mid"), string(tt.curr))
+			current := map[string]string{"Parts/bass.mid": "cur", "Parts/new.mid": "new"}
+			cached := map[string]string{"Parts/bass.mid": "prev"}
+			blobs := memBlobs{}
+			if tt.prev != nil {
+				blobs[BuildR2Key("Proj", "Parts/bass.mid", "prev")] = tt.prev
+			}
+
+			raw, err := BuildDiffJSON(context.Background(), "Proj", dir, current, cached, blobs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got DiffJSON
+			if err := json.Unmarshal(raw, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.MIDI, tt.want) {
+				t.Errorf("MIDI = %+v, want %+v", got.MIDI, tt.want)
+			}
+			if len(got.Changed) != 1 || len(got.Added) != 1 {
+				t.Errorf("changed %v, added %v; want bass.mid changed and new.mid added", got.Changed, got.Added)
+			}
+		})
+	}
+}
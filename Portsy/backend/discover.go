@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiscoverProjects lists the project names present in the bucket, from the
+// top-level prefixes alone, for rebuilding metadata after it was lost or
+// for projects uploaded outside Portsy.
+//
+// A prefix counts as a project when it has a blobs/ or manifests/ folder;
+// the global blob store and anything else (self-test leftovers, stray
+// uploads) is skipped. Projects on the global key scheme whose states were
+// kept in Firestore leave nothing under their own prefix, so they can't be
+// found this way.
+func DiscoverProjects(ctx context.Context, r2 *R2Client) ([]string, error) {
+	root := r2.prefixed("")
+	if root != "" {
+		root += "/"
+	}
+	prefixes, err := r2.ListPrefixes(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("discover: %w", err)
+	}
+	global := r2.prefixed("blobs") + "/"
+
+	var out []string
+	for _, p := range prefixes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if p == global {
+			continue
+		}
+		ok := false
+		for _, sub := range []string{"blobs/", "manifests/"} {
+			if ok, err = r2.hasAnyUnder(ctx, p+sub); err != nil {
+				return nil, fmt.Errorf("discover: %w", err)
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			debugf("discover: skipping %s (no blobs/ or manifests/)", p)
+			continue
+		}
+		out = append(out, strings.TrimSuffix(strings.TrimPrefix(p, root), "/"))
+	}
+	sort.Strings(out)
+	return out, nil
+}
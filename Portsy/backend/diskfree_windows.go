@@ -0,0 +1,19 @@
+//go:build windows
+
+package backend
+
+import "golang.org/x/sys/windows"
+
+// freeBytes is the space available to this user on path's volume (quotas
+// included).
+func freeBytes(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var avail, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &avail, &total, &free); err != nil {
+		return 0, err
+	}
+	return avail, nil
+}
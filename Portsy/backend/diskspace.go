@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrInsufficientSpace is returned by a pull whose downloads won't fit on
+// the destination volume; nothing has been downloaded yet.
+var ErrInsufficientSpace = errors.New("not enough disk space")
+
+// pullBytesNeeded sums the target files that aren't already on disk at the
+// right size: what the pull will download. Same-size files with other
+// content are rare enough to ignore here.
+func pullBytesNeeded(destPath string, files []FileEntry) int64 {
+	var need int64
+	for _, f := range files {
+		fi, err := os.Lstat(longPath(filepath.Join(destPath, filepath.FromSlash(f.Path))))
+		if err != nil || !fi.Mode().IsRegular() || fi.Size() != f.Size {
+			need += f.Size
+		}
+	}
+	return need
+}
+
+// checkPullSpace fails with ErrInsufficientSpace when the files still to
+// download exceed the free space on destPath's volume. If free space can't
+// be read, the pull goes ahead.
+func checkPullSpace(destPath string, files []FileEntry) error {
+	need := pullBytesNeeded(destPath, files)
+	if need == 0 {
+		return nil
+	}
+	free, err := freeBytes(destPath)
+	if err != nil || uint64(need) <= free {
+		return nil
+	}
+	return fmt.Errorf("%w: need %s, have %s free on %s", ErrInsufficientSpace, humanBytes(need), humanBytes(int64(free)), destPath)
+}
@@ -0,0 +1,130 @@
+package backend
+
+import (
+	"Portsy/backend/internal/core/scan"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Rules reported in TrackDecision.Rule, in the order ExplainPath checks them.
+const (
+	TrackRuleMissing    = "missing"      // not on disk
+	TrackRuleBuiltinDir = "builtin-dir"  // under .portsy, .git, Ableton Project Info, top-level Build/Cache...
+	TrackRuleJunk       = "junk"         // .DS_Store, Thumbs.db, desktop.ini
+	TrackRuleSymlink    = "symlink"      // the file, or a folder above it, is a symlink
+	TrackRuleIgnoreFile = "portsyignore" // excluded by a .portsyignore pattern
+	TrackRuleNotFile    = "not-file"     // a folder or other non-regular entry
+	TrackRuleExtension  = "extension"    // not in ProjectSettings.TrackOnlyExtensions
+	TrackRuleSuspect    = "suspect"      // tracked, but push asks first (huge or temp-looking)
+	TrackRuleTracked    = "tracked"
+)
+
+// TrackDecision is why a file is or isn't part of the project's manifest.
+type TrackDecision struct {
+	Path    string       `json:"path"` // normalized rel path
+	Tracked bool         `json:"tracked"`
+	Rule    string       `json:"rule"`
+	Detail  string       `json:"detail"`
+	Pattern string       `json:"pattern,omitempty"` // the deciding .portsyignore line, if any
+	Size    int64        `json:"size,omitempty"`
+	Suspect *SuspectFile `json:"suspect,omitempty"` // set for TrackRuleSuspect
+}
+
+// ExplainPath runs relPath (project-relative, or absolute inside
+// projectPath) through the same filters as BuildManifest and push's suspect
+// check, and reports the first rule that decides its fate. Size limits use
+// DefaultMaxFileSize.
+func ExplainPath(projectPath, relPath string) (*TrackDecision, error) {
+	projectPath = filepath.Clean(projectPath)
+	rel := filepath.FromSlash(relPath)
+	if filepath.IsAbs(rel) {
+		r, err := filepath.Rel(projectPath, rel)
+		if err != nil {
+			return nil, fmt.Errorf("explain: %w", err)
+		}
+		rel = r
+	}
+	rel = filepath.Clean(rel)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("explain: %s is not inside %s", relPath, projectPath)
+	}
+	d := &TrackDecision{Path: normalizeKey(rel)}
+	abs := filepath.Join(projectPath, rel)
+
+	info, err := os.Lstat(longPath(abs))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("explain: %w", err)
+		}
+		d.Rule, d.Detail = TrackRuleMissing, "no such file in the project folder"
+		return d, nil
+	}
+
+	if dir, junk := scan.BuiltinExclusion(d.Path); dir != "" {
+		d.Rule, d.Detail = TrackRuleBuiltinDir, fmt.Sprintf("inside %s/, which Portsy never tracks", dir)
+		return d, nil
+	} else if junk {
+		d.Rule, d.Detail = TrackRuleJunk, "system junk file"
+		return d, nil
+	}
+
+	// The walk doesn't follow symlinked folders, so check every parent.
+	for dir := filepath.Dir(rel); dir != "."; dir = filepath.Dir(dir) {
+		if fi, err := os.Lstat(longPath(filepath.Join(projectPath, dir))); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			d.Rule, d.Detail = TrackRuleSymlink, fmt.Sprintf("%s is a symlinked folder, which the scan doesn't follow", normalizeKey(dir))
+			return d, nil
+		}
+	}
+	ign, err := LoadIgnore(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	// Like the walk: an ignored folder hides everything under it, even
+	// files a later "!" pattern names.
+	for dir := path.Dir(d.Path); dir != "."; dir = path.Dir(dir) {
+		if pat, ignored := ign.Rule(dir, true); ignored {
+			d.Rule, d.Pattern = TrackRuleIgnoreFile, pat
+			d.Detail = fmt.Sprintf("folder %s/ is excluded by %s pattern %q", dir, IgnoreFileName, pat)
+			return d, nil
+		}
+	}
+	pat, ignored := ign.Rule(d.Path, info.IsDir())
+	if ignored {
+		d.Rule, d.Pattern = TrackRuleIgnoreFile, pat
+		d.Detail = fmt.Sprintf("excluded by %s pattern %q", IgnoreFileName, pat)
+		return d, nil
+	}
+	d.Pattern = pat // a "!" re-include, if one matched
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		d.Rule, d.Detail = TrackRuleSymlink, "symlinked files aren't tracked"
+		return d, nil
+	case !info.Mode().IsRegular():
+		d.Rule, d.Detail = TrackRuleNotFile, "not a regular file"
+		return d, nil
+	}
+	d.Size = info.Size()
+
+	s, err := LoadProjectSettings(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	if tracked := s.trackFilter(); tracked != nil && !tracked(d.Path) {
+		d.Rule = TrackRuleExtension
+		d.Detail = fmt.Sprintf("extension not in trackOnlyExtensions %v", s.TrackOnlyExtensions)
+		return d, nil
+	}
+
+	d.Tracked = true
+	if sf, ok := suspectFile(d.Path, d.Size, DefaultMaxFileSize); ok {
+		d.Rule, d.Detail, d.Suspect = TrackRuleSuspect, sf.Detail+"; push asks before including it", &sf
+		return d, nil
+	}
+	d.Rule, d.Detail = TrackRuleTracked, "passes every filter"
+	return d, nil
+}
@@ -0,0 +1,139 @@
+package backend
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	remote "Portsy/backend/remote"
+)
+
+// exportManifestName sits at the zip root, next to the project folder.
+const exportManifestName = "portsy-manifest.json"
+
+// ExportProjectZip writes a commit (HEAD if commitID == "") into a standalone zip:
+//
+//	<project>/<original relative paths...>
+//	portsy-manifest.json
+//
+// Blobs are streamed from R2 straight into the archive (bounded memory), and
+// the zip is built as destZip.part then renamed so a failed export never leaves
+// a half-written archive behind. SHA-256 states are verified while streaming.
+func ExportProjectZip(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, projectName, commitID, destZip string) error {
+	var (
+		st  *ProjectState
+		cm  *CommitMeta
+		err error
+	)
+	if commitID, err = resolveCommitID(ctx, meta, projectName, commitID); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if commitID == "" {
+		st, cm, err = meta.GetLatestState(ctx, projectName)
+	} else {
+		st, cm, err = meta.GetStateByCommit(ctx, projectName, commitID)
+	}
+	if err != nil {
+		return fmt.Errorf("export: read remote state: %w", err)
+	}
+	if st == nil {
+		return fmt.Errorf("export: no remote state found for %q (commit=%q)", projectName, commitID)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destZip), 0o755); err != nil {
+		return fmt.Errorf("export: mkdir: %w", err)
+	}
+	tmp := destZip + ".part"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("export: create temp: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+	}()
+
+	zw := zip.NewWriter(f)
+	for _, rf := range st.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key := r2.KeyFor(st, projectName, rf)
+		if err := exportEntry(ctx, zw, r2, path.Join(projectName, rf.Path), key, rf, st.Algo); err != nil {
+			return fmt.Errorf("export %s: %w", rf.Path, err)
+		}
+	}
+
+	// Manifest last: it describes exactly what landed in the archive.
+	mw, err := zw.Create(exportManifestName)
+	if err != nil {
+		return fmt.Errorf("export: manifest entry: %w", err)
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(struct {
+		Project    string        `json:"project"`
+		Commit     *CommitMeta   `json:"commit,omitempty"`
+		State      *ProjectState `json:"state"`
+		ExportedAt string        `json:"exportedAt"`
+	}{projectName, cm, st, time.Now().UTC().Format(time.RFC3339)}); err != nil {
+		return fmt.Errorf("export: write manifest: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("export: finalize zip: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("export: sync: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("export: close: %w", err)
+	}
+	if err := moveFile(tmp, destZip); err != nil {
+		return fmt.Errorf("export: rename: %w", err)
+	}
+	return nil
+}
+
+func exportEntry(ctx context.Context, zw *zip.Writer, r2 *R2Client, name, key string, rf FileEntry, algo string) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	if rf.Modified > 0 {
+		hdr.Modified = time.Unix(rf.Modified, 0)
+	}
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	body, err := r2.OpenReader(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// Verify in-stream for SHA-256 manifests (the default algo); others are copied as-is.
+	var h hash.Hash
+	src := io.Reader(body)
+	if algo == "" || algo == "sha256" || algo == "SHA-256" {
+		h = sha256.New()
+		src = io.TeeReader(body, h)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	if h != nil {
+		if got := hex.EncodeToString(h.Sum(nil)); got != rf.Hash && !blobSameAudio(ctx, r2, key, rf) {
+			return fmt.Errorf("hash mismatch for key %s", key)
+		}
+	}
+	return nil
+}
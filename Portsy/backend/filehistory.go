@@ -0,0 +1,99 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+)
+
+// File change kinds reported in FileVersion.Change.
+const (
+	FileAdded    = "added"
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+)
+
+// FileVersion is one commit that changed a file (blame-style history).
+type FileVersion struct {
+	CommitID  string `json:"commitId"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+	Change    string `json:"change"`
+	Hash      string `json:"hash,omitempty"` // empty when deleted
+	Size      int64  `json:"size,omitempty"`
+}
+
+// defaultFileHistoryCommits bounds FileHistory's walk.
+const defaultFileHistoryCommits = 100
+
+// FileHistory returns the commits, newest first, in which relPath's content
+// changed (added, modified or deleted), looking at the last 100 commits.
+func FileHistory(ctx context.Context, meta *remote.MetaStore, project, relPath string) ([]FileVersion, error) {
+	return FileHistoryN(ctx, meta, project, relPath, 0)
+}
+
+// FileHistoryN is FileHistory over the last limit commits (<= 0 = default).
+// Each commit's state is read once; pending commits and commits whose state
+// can't be read are passed over.
+func FileHistoryN(ctx context.Context, meta *remote.MetaStore, project, relPath string, limit int) ([]FileVersion, error) {
+	if limit <= 0 {
+		limit = defaultFileHistoryCommits
+	}
+	key := normalizeKey(relPath)
+
+	commits, err := meta.GetCommitHistory(ctx, project, limit)
+	if err != nil {
+		return nil, fmt.Errorf("file history: %w", err)
+	}
+
+	// Walk oldest -> newest so each state compares against its predecessor.
+	var (
+		out  []FileVersion
+		prev *FileEntry
+	)
+	for i := len(commits) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cm := commits[i]
+		if cm.Status == "pending" {
+			continue
+		}
+		st, err := meta.GetState(ctx, project, cm.ID)
+		if err != nil {
+			continue
+		}
+
+		var cur *FileEntry
+		for j := range st.Files {
+			if normalizeKey(st.Files[j].Path) == key {
+				cur = &st.Files[j]
+				break
+			}
+		}
+
+		v := FileVersion{CommitID: cm.ID, Message: cm.Message, Timestamp: cm.Timestamp}
+		switch {
+		case cur != nil && prev == nil:
+			// Also the oldest commit in the window if the file predates it.
+			v.Change = FileAdded
+		case cur != nil && prev.Hash != cur.Hash:
+			v.Change = FileModified
+		case cur == nil && prev != nil:
+			v.Change = FileDeleted
+		}
+		if cur != nil {
+			v.Hash, v.Size = cur.Hash, cur.Size
+		}
+		if v.Change != "" {
+			out = append(out, v)
+		}
+		prev = cur
+	}
+
+	// Newest first, like the commit history.
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+)
+
+// First-push limits: a never-pushed folder bigger than this is more likely a
+// wrong directory (a whole Music folder) than a Live project.
+const (
+	DefaultFirstPushMaxFiles       = 5000
+	DefaultFirstPushMaxBytes int64 = 20 << 30
+)
+
+// FirstPushCheck describes a never-pushed project that tripped the sanity
+// check; Reasons says which limits it broke.
+type FirstPushCheck struct {
+	Project string   `json:"project"`
+	Files   int      `json:"files"`
+	Bytes   int64    `json:"bytes"`
+	HasALS  bool     `json:"hasAls"`
+	Reasons []string `json:"reasons"`
+}
+
+// ErrFirstPushUnconfirmed is returned by PushProjectWithOptions when a
+// project's first push tripped the sanity check and
+// PushOptions.ConfirmFirstPush was nil or declined.
+var ErrFirstPushUnconfirmed = errors.New("first push not confirmed")
+
+// checkFirstPush flags a first push with no top-level .als, more than
+// maxFiles files or more than maxBytes in total (0 = defaults, < 0 = no
+// limit). ok is false when nothing looks wrong.
+func checkFirstPush(project string, files []FileEntry, maxFiles int, maxBytes int64) (FirstPushCheck, bool) {
+	if maxFiles == 0 {
+		maxFiles = DefaultFirstPushMaxFiles
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultFirstPushMaxBytes
+	}
+	c := FirstPushCheck{Project: project, Files: len(files)}
+	manifest := make(map[string]string, len(files))
+	for _, f := range files {
+		c.Bytes += f.Size
+		manifest[f.Path] = f.Hash
+	}
+	c.HasALS = topLevelALS(manifest) != ""
+
+	if !c.HasALS {
+		c.Reasons = append(c.Reasons, "no .als in the folder; is this a Live project?")
+	}
+	if maxFiles > 0 && c.Files > maxFiles {
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%d files (limit %d)", c.Files, maxFiles))
+	}
+	if maxBytes > 0 && c.Bytes > maxBytes {
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%s in total (limit %s)", humanBytes(c.Bytes), humanBytes(maxBytes)))
+	}
+	return c, len(c.Reasons) > 0
+}
@@ -0,0 +1,310 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultGCMinAge keeps blobs younger than this out of GC: a push uploads
+// its blobs before any commit references them.
+const DefaultGCMinAge = 24 * time.Hour
+
+// GCOptions controls GCProject.
+type GCOptions struct {
+	// DryRun analyzes and reports without deleting anything.
+	DryRun bool
+
+	// KeepRecent is how many of the newest final commits keep their blobs;
+	// 0 = every commit does, so only unreferenced blobs are collected. Blobs
+	// referenced only by older commits are collected and those commits can
+	// no longer be pulled or rolled back to.
+	KeepRecent int
+
+	// Protect lists extra commit IDs whose blobs are never collected (e.g.
+	// commits a user pinned for a release).
+	Protect []string
+
+	// MinAge skips blobs written more recently (0 = DefaultGCMinAge,
+	// < 0 = no age check).
+	MinAge time.Duration
+
+	// DeletableOnly leaves kept blobs out of GCReport.Candidates, so the
+	// report stays small on a large bucket. They're still counted in Stored.
+	DeletableOnly bool
+}
+
+// Reasons a commit's blobs are kept, in GCRef.Keep.
+const (
+	GCKeepHead      = "head"
+	GCKeepPending   = "pending"
+	GCKeepProtected = "protected"
+	GCKeepRecent    = "recent"
+)
+
+// GCRef is one commit referencing a blob.
+type GCRef struct {
+	CommitID  string `json:"commitId"`
+	Status    string `json:"status"` // "final" or "pending"
+	Timestamp int64  `json:"timestamp"`
+	Keep      string `json:"keep,omitempty"` // GCKeep*; "" = expired under KeepRecent
+}
+
+// GCCandidate is a stored blob under the project's prefix and the verdict.
+type GCCandidate struct {
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	Modified  time.Time `json:"modified"`
+	Refs      []GCRef   `json:"refs"`
+	Deletable bool      `json:"deletable"`
+	Reason    string    `json:"reason"`
+}
+
+// GCReport is GCProject's full analysis: every stored blob with the commits
+// that reference it, so a dry run can be audited before the real one.
+type GCReport struct {
+	Project    string        `json:"project"`
+	DryRun     bool          `json:"dryRun"`
+	HeadID     string        `json:"headId"`
+	Commits    int           `json:"commits"`
+	Stored     int           `json:"stored"` // blobs under the project's prefix
+	Candidates []GCCandidate `json:"candidates"`
+
+	DeletableCount int      `json:"deletableCount"`
+	DeletableBytes int64    `json:"deletableBytes"`
+	Deleted        []string `json:"deleted,omitempty"`
+
+	// Set when states use KeySchemeGlobalBlobs: those blobs are shared with
+	// other projects and never collected here.
+	GlobalBlobs bool `json:"globalBlobs,omitempty"`
+}
+
+// GCProject collects blobs under the project's R2 prefix that no kept commit
+// references. Every state is read, and a blob is refused as deletable if
+// any pending commit, HEAD, a Protect commit or one of the KeepRecent newest
+// commits references it, or it is younger than MinAge. An unreadable state
+// or commit aborts the whole run rather than guess. Deletes only happen
+// without DryRun, after the analysis is complete.
+//
+// A push running meanwhile can find an old unreferenced blob by HEAD, skip
+// its upload and commit a reference to it. So right before each delete the
+// commit history is re-read, and blobs referenced by commits written since
+// the analysis are kept. That still leaves a window: a push that HEAD-checked
+// the blob before its delete but commits after that last re-check (or with a
+// clock so far behind that its commit sorts below the known ones) points at
+// a deleted blob. Its VerifyAfter pass (or a later VerifyCommit) reports the
+// blob missing, and pushing again re-uploads it.
+func GCProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project string, opt GCOptions) (*GCReport, error) {
+	if opt.MinAge == 0 {
+		opt.MinAge = DefaultGCMinAge
+	}
+	rep := &GCReport{Project: project, DryRun: opt.DryRun}
+
+	_, head, err := meta.GetLatestState(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("gc: read head: %w", err)
+	}
+	if head != nil {
+		rep.HeadID = head.ID
+	}
+
+	// Commits as of the analysis, so the deletes can tell which came later.
+	known := map[string]bool{}
+	if !opt.DryRun {
+		history, err := meta.GetCommitHistory(ctx, project, 0)
+		if err != nil {
+			return nil, fmt.Errorf("gc: %w", err)
+		}
+		for _, cm := range history {
+			known[cm.ID] = true
+		}
+	}
+
+	ids, err := meta.ListStateIDs(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("gc: %w", err)
+	}
+	rep.Commits = len(ids)
+
+	// Every state with its commit, and which keys each references.
+	type commitRefs struct {
+		ref  GCRef
+		keys []string
+	}
+	all := make([]*commitRefs, 0, len(ids))
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		st, cm, err := meta.GetStateByCommit(ctx, project, id)
+		if err != nil {
+			return nil, fmt.Errorf("gc: %w", err)
+		}
+		cr := &commitRefs{ref: GCRef{CommitID: id, Status: "final", Timestamp: cm.Timestamp}}
+		if cm.Status != "" && cm.Status != "final" {
+			cr.ref.Status = "pending"
+		}
+		if st.KeyScheme.Resolve() == KeySchemeGlobalBlobs {
+			rep.GlobalBlobs = true
+		}
+		seen := map[string]bool{}
+		for _, fe := range st.Files {
+			if k := r2.KeyFor(st, project, fe); !seen[k] {
+				seen[k] = true
+				cr.keys = append(cr.keys, k)
+			}
+		}
+		all = append(all, cr)
+	}
+
+	// Decide which commits are kept, strongest reason first.
+	protect := map[string]bool{}
+	for _, id := range opt.Protect {
+		protect[id] = true
+	}
+	var final []*commitRefs
+	for _, cr := range all {
+		switch {
+		case cr.ref.CommitID == rep.HeadID:
+			cr.ref.Keep = GCKeepHead
+		case cr.ref.Status == "pending":
+			cr.ref.Keep = GCKeepPending
+		case protect[cr.ref.CommitID]:
+			cr.ref.Keep = GCKeepProtected
+		default:
+			final = append(final, cr)
+		}
+	}
+	sort.SliceStable(final, func(i, j int) bool { return final[i].ref.Timestamp > final[j].ref.Timestamp })
+	for i, cr := range final {
+		if opt.KeepRecent <= 0 || i < opt.KeepRecent {
+			cr.ref.Keep = GCKeepRecent
+		}
+	}
+
+	refs := map[string][]GCRef{}
+	for _, cr := range all {
+		for _, k := range cr.keys {
+			refs[k] = append(refs[k], cr.ref)
+		}
+	}
+
+	// Page through the blobs rather than listing the whole prefix up front.
+	now := time.Now()
+	err = r2.WalkObjects(ctx, r2.ProjectBlobPrefix(project), func(o StoredObject) error {
+		rep.Stored++
+		c := GCCandidate{Key: o.Key, Size: o.Size, Modified: o.Modified, Refs: refs[o.Key]}
+		sort.Slice(c.Refs, func(i, j int) bool { return c.Refs[i].Timestamp > c.Refs[j].Timestamp })
+		c.Deletable, c.Reason = gcVerdict(c, opt.MinAge, now)
+		if c.Deletable {
+			rep.DeletableCount++
+			rep.DeletableBytes += c.Size
+		} else if opt.DeletableOnly {
+			return nil
+		}
+		rep.Candidates = append(rep.Candidates, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gc: %w", err)
+	}
+	sort.Slice(rep.Candidates, func(i, j int) bool { return rep.Candidates[i].Key < rep.Candidates[j].Key })
+
+	if opt.DryRun {
+		return rep, nil
+	}
+	for _, id := range ids {
+		known[id] = true
+	}
+	lateRefs := map[string]string{} // key -> commit written since the analysis
+	for i := range rep.Candidates {
+		c := &rep.Candidates[i]
+		if !c.Deletable {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		if err := gcRecheck(ctx, meta, r2, project, known, lateRefs); err != nil {
+			return rep, fmt.Errorf("gc: recheck: %w", err)
+		}
+		if id, ok := lateRefs[c.Key]; ok {
+			c.Deletable, c.Reason = false, "referenced by commit "+id+" written during GC"
+			rep.DeletableCount--
+			rep.DeletableBytes -= c.Size
+			continue
+		}
+		if err := r2.Delete(ctx, c.Key); err != nil {
+			return rep, fmt.Errorf("gc: %w", err)
+		}
+		rep.Deleted = append(rep.Deleted, c.Key)
+	}
+	if len(rep.Deleted) > 0 {
+		_ = meta.RecordAudit(ctx, project, remote.AuditGC, fmt.Sprintf("%d blob(s), %s", len(rep.Deleted), humanBytes(rep.DeletableBytes)))
+	}
+	return rep, nil
+}
+
+// gcRecheck reads the commits written since known was filled in (newest
+// first, until it reaches a known one) and adds the keys their states
+// reference to lateRefs.
+func gcRecheck(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project string, known map[string]bool, lateRefs map[string]string) error {
+	var fresh []string
+	for limit := 8; ; limit *= 2 {
+		history, err := meta.GetCommitHistory(ctx, project, limit)
+		if err != nil {
+			return err
+		}
+		var complete bool
+		fresh, complete = unseenCommits(history, known)
+		if complete || len(history) < limit {
+			break
+		}
+	}
+	for _, id := range fresh {
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			return err
+		}
+		for _, fe := range st.Files {
+			lateRefs[r2.KeyFor(st, project, fe)] = id
+		}
+		known[id] = true
+	}
+	return nil
+}
+
+// unseenCommits returns the IDs at the front of history (newest first) that
+// aren't in known, and whether it reached a known commit.
+func unseenCommits(history []CommitMeta, known map[string]bool) (ids []string, complete bool) {
+	for _, cm := range history {
+		if known[cm.ID] {
+			return ids, true
+		}
+		ids = append(ids, cm.ID)
+	}
+	return ids, false
+}
+
+// gcVerdict refuses c if any kept commit references it or it's too new.
+func gcVerdict(c GCCandidate, minAge time.Duration, now time.Time) (bool, string) {
+	for _, r := range c.Refs {
+		if r.Keep != "" {
+			return false, fmt.Sprintf("referenced by %s commit %s", r.Keep, r.CommitID)
+		}
+	}
+	if minAge > 0 && now.Sub(c.Modified) < minAge {
+		return false, fmt.Sprintf("written %s ago (min age %s)", now.Sub(c.Modified).Round(time.Minute), minAge)
+	}
+	if len(c.Refs) == 0 {
+		return true, "unreferenced"
+	}
+	ids := make([]string, len(c.Refs))
+	for i, r := range c.Refs {
+		ids[i] = r.CommitID
+	}
+	return true, "only referenced by expired commits " + strings.Join(ids, ", ")
+}
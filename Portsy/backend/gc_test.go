@@ -0,0 +1,37 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUnseenCommits(t *testing.T) {
+	known := map[string]bool{"c1": true, "c2": true}
+	history := func(ids ...string) []CommitMeta {
+		out := make([]CommitMeta, len(ids))
+		for i, id := range ids {
+			out[i] = CommitMeta{ID: id}
+		}
+		return out
+	}
+	tests := []struct {
+		name         string
+		history      []CommitMeta
+		want         []string
+		wantComplete bool
+	}{
+		{name: "nothing new", history: history("c2", "c1"), wantComplete: true},
+		{name: "new on top", history: history("c4", "c3", "c2", "c1"), want: []string{"c4", "c3"}, wantComplete: true},
+		{name: "page ends before a known commit", history: history("c5", "c4", "c3"), want: []string{"c5", "c4", "c3"}},
+		{name: "empty history"},
+		{name: "stops at the first known", history: history("c3", "c2", "c9"), want: []string{"c3"}, wantComplete: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, complete := unseenCommits(tt.history, known)
+			if !slices.Equal(got, tt.want) || complete != tt.wantComplete {
+				t.Errorf("unseenCommits = %v, %v; want %v, %v", got, complete, tt.want, tt.wantComplete)
+			}
+		})
+	}
+}
@@ -5,14 +5,19 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	corehash "Portsy/backend/internal/core/hash"
+	"Portsy/backend/internal/core/scan"
+	"Portsy/backend/remote"
 )
 
 // HashFileSHA256 returns (hashHex, sizeBytes, mtimeUnixSec) using SHA-256 ONLY.
 func HashFileSHA256(path string) (string, int64, int64, error) {
+	path = longPath(path)
 	info, err := os.Lstat(path)
 	if err != nil {
 		return "", 0, 0, err
@@ -30,75 +35,216 @@ func HashFileSHA256(path string) (string, int64, int64, error) {
 }
 
 // BuildManifest walks projectPath and returns a ProjectState of all tracked files.
-// - Skips .portsy internals, common build/cache & VCS/IDE dirs.
-// - Skips platform junk files (.DS_Store, Thumbs.db, desktop.ini).
-// - Normalizes paths to forward slashes; lowercases on Windows (NTFS semantics).
+// - What counts as tracked (ignores, junk, symlinks, normalization) is scan.WalkProject's call.
+// - Files unchanged since .portsy/cache.json was written keep their cached hash.
 // - Sorts entries by Path for deterministic output.
 func BuildManifest(projectPath string) (ProjectState, error) {
-	projectPath = filepath.Clean(projectPath)
+	res, err := buildManifest(projectPath, loadManifestCache(projectPath), nil)
+	return res.State, err
+}
 
-	var files []FileEntry
+// BuildManifestWithCache is BuildManifest against a cache the caller already
+// loaded; nil rehashes every file.
+func BuildManifestWithCache(projectPath string, lc *LocalCache) (ProjectState, error) {
+	res, err := buildManifest(projectPath, lc, nil)
+	return res.State, err
+}
 
-	err := filepath.WalkDir(projectPath, func(p string, d os.DirEntry, walkErr error) error {
-		if walkErr != nil {
-			// Silently skip unreadable entries to match previous behavior.
-			return nil
-		}
+// SkipInfo is a file BuildManifest could not include.
+type SkipInfo struct {
+	Path   string `json:"path"` // normalized rel path (or the raw path if it couldn't be made relative)
+	Reason string `json:"reason"`
+}
 
-		name := d.Name()
-		if d.IsDir() {
-			// Skip known internal & noisy dirs at the top level of each subtree.
-			switch name {
-			case ".portsy", "Build", "Cache", ".git", ".idea", ".vs", ".svn", ".hg", "Ableton Project Info":
-				return filepath.SkipDir
-			}
-			return nil
-		}
+// BuildManifestResult is the manifest plus every file that was left out of it.
+type BuildManifestResult struct {
+	State   ProjectState `json:"state"`
+	Skipped []SkipInfo   `json:"skipped,omitempty"`
+}
 
-		// Skip symlinked files (avoid cross-tree surprises)
-		if d.Type()&os.ModeSymlink != 0 {
-			return nil
-		}
+// BuildManifestDetailed is BuildManifest without the silent skipping: files
+// that could not be walked or hashed (permissions, locks, long paths) are
+// reported in Skipped instead of just vanishing from the manifest.
+func BuildManifestDetailed(projectPath string) (BuildManifestResult, error) {
+	return buildManifest(projectPath, loadManifestCache(projectPath), nil)
+}
+
+// loadManifestCache is the project's local cache, or nil (full rehash) if it
+// can't be read.
+func loadManifestCache(projectPath string) *LocalCache {
+	lc, err := LoadLocalCache(projectPath)
+	if err != nil {
+		return nil
+	}
+	return lc
+}
+
+// manifestFile is a tracked file found by walkManifestFiles, not yet hashed.
+type manifestFile struct {
+	abs   string
+	rel   string // normalized
+	size  int64
+	mtime int64 // unix nano
+}
 
-		// Skip platform junk
-		if name == ".DS_Store" || name == "Thumbs.db" || name == "desktop.ini" {
-			return nil
+// buildManifest hashes every tracked file, calling onFile (if set) after each
+// one with (done, total) so long first-time scans can report progress.
+//
+// Files whose size and mtime match their lc.Manifest entry carry the cached
+// hash forward instead of being reread; the rest are hashed by
+// runtime.NumCPU() workers. The result is the same as a full rehash, as long
+// as nothing rewrites a file while keeping both its size and its mtime.
+func buildManifest(projectPath string, lc *LocalCache, onFile func(done, total int, f manifestFile)) (BuildManifestResult, error) {
+	projectPath = filepath.Clean(projectPath)
+
+	found, skipped, err := walkManifestFiles(projectPath)
+	if err != nil {
+		return BuildManifestResult{}, err
+	}
+
+	type hashed struct {
+		entry FileEntry
+		err   error
+	}
+	results := make([]hashed, len(found))
+	done := 0
+	progress := func(mf manifestFile) {
+		done++
+		if onFile != nil {
+			onFile(done, len(found), mf)
 		}
+	}
 
-		rel, err := filepath.Rel(projectPath, p)
-		if err != nil {
-			return nil
+	// Fast path: unchanged since the cache was written.
+	var todo []int
+	for i, mf := range found {
+		if h, ok := cachedHash(lc, mf); ok {
+			results[i].entry = FileEntry{Path: mf.rel, Hash: h, Size: mf.size, Modified: mf.mtime / int64(time.Second)}
+			progress(mf)
+			continue
 		}
+		todo = append(todo, i)
+	}
 
-		// Normalize relative path
-		rel = filepath.ToSlash(rel)
-		if runtime.GOOS == "windows" {
-			rel = strings.ToLower(rel)
+	if len(todo) > 0 {
+		workers := min(runtime.NumCPU(), len(todo))
+		jobs := make(chan int, workers)
+		finished := make(chan int, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					hash, size, mod, err := HashFileSHA256(found[i].abs)
+					results[i] = hashed{
+						entry: FileEntry{Path: found[i].rel, Hash: hash, Size: size, Modified: mod},
+						err:   err,
+					}
+					finished <- i
+				}
+			}()
 		}
+		go func() {
+			for _, i := range todo {
+				jobs <- i
+			}
+			close(jobs)
+			wg.Wait()
+			close(finished)
+		}()
+		// Progress is reported from this goroutine only.
+		for i := range finished {
+			progress(found[i])
+		}
+	}
 
-		hash, size, mod, err := HashFileSHA256(p)
-		if err != nil {
+	files := make([]FileEntry, 0, len(found))
+	for i, r := range results {
+		if r.err != nil {
 			// Skip files we couldn't hash (permissions, transient IO, etc.)
-			return nil
+			skipped = append(skipped, SkipInfo{Path: found[i].rel, Reason: r.err.Error()})
+			continue
 		}
-
-		files = append(files, FileEntry{
-			Path:     rel,
-			Hash:     hash,
-			Size:     size,
-			Modified: mod,
-		})
-		return nil
-	})
-	if err != nil {
-		return ProjectState{}, err
+		files = append(files, r.entry)
 	}
 
 	// Deterministic ordering helps diffs & tests.
 	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
 
-	return ProjectState{
-		Files:     files,
-		CreatedAt: time.Now().Unix(),
+	return BuildManifestResult{
+		State: ProjectState{
+			Files:     files,
+			CreatedAt: time.Now().Unix(),
+		},
+		Skipped: skipped,
 	}, nil
 }
+
+// cachedHash returns lc's hash for mf if the file's size and mtime still
+// match what was recorded. Entries whose mtime isn't safely before the cache
+// write are distrusted: the file may have changed again within the same
+// second (mtimes are whole seconds in the cache).
+func cachedHash(lc *LocalCache, mf manifestFile) (string, bool) {
+	if lc == nil || lc.Algo != "sha256" {
+		return "", false
+	}
+	e, ok := lc.Manifest[mf.rel]
+	if !ok || e.ModUnix == 0 || e.Size != mf.size || e.ModUnix != mf.mtime/int64(time.Second) {
+		return "", false
+	}
+	if e.ModUnix >= lc.UpdatedAt.Unix()-1 {
+		return "", false
+	}
+	if e.Hash == "" || strings.HasPrefix(e.Hash, "legacy:") {
+		return "", false
+	}
+	return e.Hash, true
+}
+
+// walkManifestFiles lists tracked files via scan.WalkProjectSkipping, the one
+// source of truth for ignore rules and path normalization (shared with
+// DetectChanges). Unreadable entries are returned as skipped.
+func walkManifestFiles(projectPath string) ([]manifestFile, []SkipInfo, error) {
+	entries, walkSkipped, err := scan.WalkProjectSkipping(projectPath, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tracked func(string) bool
+	if s, err := LoadProjectSettings(projectPath); err == nil {
+		tracked = s.trackFilter()
+	}
+	out := make([]manifestFile, 0, len(entries))
+	for _, e := range entries {
+		if tracked != nil && !tracked(e.Rel) {
+			continue // not in TrackOnlyExtensions
+		}
+		out = append(out, manifestFile{abs: e.Abs, rel: e.Rel, size: e.Size, mtime: e.Mt})
+	}
+	var skipped []SkipInfo
+	for _, sk := range walkSkipped {
+		skipped = append(skipped, SkipInfo{Path: sk.Rel, Reason: sk.Reason})
+	}
+	return out, skipped, nil
+}
+
+// ComputeManifestHash returns the integrity hash over a state's sorted
+// (path, hash) pairs. Stored as ProjectState.ManifestHash at push time and
+// re-checked by the MetaStore on every state read.
+func ComputeManifestHash(ps ProjectState) string {
+	return remote.ComputeManifestHash(ps)
+}
+
+// SetCaseSensitivePaths switches the process-wide path key policy; see
+// scan.SetCaseSensitive for the cross-platform trade-offs.
+func SetCaseSensitivePaths(on bool) { scan.SetCaseSensitive(on) }
+
+// CaseSensitivePathsEnv is read by SetCaseSensitivePathsFromEnv ("1"/"true").
+const CaseSensitivePathsEnv = "PORTSY_CASE_SENSITIVE_PATHS"
+
+// SetCaseSensitivePathsFromEnv applies CaseSensitivePathsEnv. The GUI and the
+// CLI it spawns share the environment, so both end up on the same policy.
+func SetCaseSensitivePathsFromEnv() {
+	on, _ := strconv.ParseBool(os.Getenv(CaseSensitivePathsEnv))
+	scan.SetCaseSensitive(on)
+}
@@ -0,0 +1,225 @@
+package backend
+
+import (
+	"Portsy/backend/internal/core/scan"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedHash(t *testing.T) {
+	written := time.Unix(1_700_000_000, 0)
+	mf := manifestFile{rel: "Set.als", size: 3, mtime: written.Add(-time.Hour).UnixNano()}
+	entry := CacheEntry{Hash: "aa", Size: 3, ModUnix: written.Add(-time.Hour).Unix()}
+	tests := []struct {
+		name  string
+		algo  string
+		entry *CacheEntry // nil: no entry for the file
+		mtime int64       // overrides mf.mtime (unix nano) when set
+		want  bool
+	}{
+		{name: "unchanged", entry: &entry, want: true},
+		{name: "no entry"},
+		{name: "other algo", algo: "blake3", entry: &entry},
+		{name: "size differs", entry: &CacheEntry{Hash: "aa", Size: 4, ModUnix: entry.ModUnix}},
+		{name: "mtime differs", entry: &entry, mtime: written.Add(-30 * time.Minute).UnixNano()},
+		{name: "unknown stat", entry: &CacheEntry{Hash: "aa", Size: 3}},
+		{name: "legacy hash", entry: &CacheEntry{Hash: "legacy:aa", Size: 3, ModUnix: entry.ModUnix}},
+		{
+			name:  "same second as cache write",
+			entry: &CacheEntry{Hash: "aa", Size: 3, ModUnix: written.Unix()},
+			mtime: written.UnixNano(),
+		},
+		{
+			name:  "second before cache write",
+			entry: &CacheEntry{Hash: "aa", Size: 3, ModUnix: written.Unix() - 1},
+			mtime: written.Add(-time.Second).UnixNano(),
+		},
+		{
+			name:  "two seconds before cache write",
+			entry: &CacheEntry{Hash: "aa", Size: 3, ModUnix: written.Unix() - 2},
+			mtime: written.Add(-2 * time.Second).UnixNano(),
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := &LocalCache{Algo: "sha256", UpdatedAt: written, Manifest: map[string]CacheEntry{}}
+			if tt.algo != "" {
+				lc.Algo = tt.algo
+			}
+			if tt.entry != nil {
+				lc.Manifest[mf.rel] = *tt.entry
+			}
+			f := mf
+			if tt.mtime != 0 {
+				f.mtime = tt.mtime
+			}
+			h, ok := cachedHash(lc, f)
+			if ok != tt.want {
+				t.Fatalf("cachedHash = %q, %v; want ok %v", h, ok, tt.want)
+			}
+			if ok && h != tt.entry.Hash {
+				t.Errorf("hash = %q, want %q", h, tt.entry.Hash)
+			}
+		})
+	}
+}
+
+// TestBuildManifestRehashesSameSecondRewrite: a file rewritten in the second
+// the cache was saved, keeping its size and mtime, must not keep the cached
+// hash.
+func TestBuildManifestRehashesSameSecondRewrite(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "Set.als")
+	writeTestFile(t, p, "take one")
+	ps, err := BuildManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCacheFromState(dir, ps, ps.Algo); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, p, "take two") // same length
+	if err := os.Chtimes(p, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	want, _, _, err := HashFileSHA256(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := BuildManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Files) != 1 || got.Files[0].Hash != want {
+		t.Fatalf("manifest = %+v, want Set.als with the rewritten hash %s", got.Files, want)
+	}
+}
+
+// BenchmarkBuildManifest compares a first scan (cold: every file hashed)
+// with a rescan against a fresh cache (warm: only stat calls).
+func BenchmarkBuildManifest(b *testing.B) {
+	dir := b.TempDir()
+	old := time.Now().Add(-time.Hour)
+	body := strings.Repeat("x", 256<<10)
+	for i := 0; i < 64; i++ {
+		p := filepath.Join(dir, "Samples", fmt.Sprintf("%02d.wav", i))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body+fmt.Sprint(i)), 0o644); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.Chtimes(p, old, old); err != nil {
+			b.Fatal(err)
+		}
+	}
+	ps, err := BuildManifest(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := WriteCacheFromState(dir, ps, ps.Algo); err != nil {
+		b.Fatal(err)
+	}
+	lc, err := LoadLocalCache(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bc := range []struct {
+		name string
+		lc   *LocalCache
+	}{
+		{"cold", nil},
+		{"warm", lc},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(ps.Files) * len(body)))
+			for i := 0; i < b.N; i++ {
+				if _, err := BuildManifestWithCache(dir, bc.lc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestBuildManifestMatchesWalkProject cross-checks the file set push uses
+// (BuildManifest) against the scanner DetectChanges used, and against
+// ExplainPath, over every built-in ignore rule.
+func TestBuildManifestMatchesWalkProject(t *testing.T) {
+	tests := []struct {
+		rel     string
+		tracked bool
+	}{
+		{"Set.als", true},
+		{"Samples/Imported/kick.wav", true},
+		{"Ableton Project Info/Project8_1.cfg", false},
+		{"Samples/Ableton Project Info/nested.cfg", false}, // at any depth
+		{"Build/out.wav", false},                           // top-level only
+		{"Samples/Build/kick.wav", true},
+		{"Cache/peak.asd", false},
+		{"Samples/Cache/snare.wav", true},
+		{".portsy/cache.json", false},
+		{".git/HEAD", false},
+		{"Samples/.DS_Store", false},
+		{"Thumbs.db", false},
+		{"desktop.ini", false},
+		{"Bounces/mix.tmp", false}, // .portsyignore
+		{"Bounces/mix.wav", true},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(tt.rel)), tt.rel)
+	}
+	writeTestFile(t, filepath.Join(dir, IgnoreFileName), "*.tmp\n")
+
+	ps, err := BuildManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walked, err := scan.WalkProject(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inManifest := map[string]bool{}
+	for _, f := range ps.Files {
+		inManifest[f.Path] = true
+	}
+	inWalk := map[string]bool{}
+	for _, e := range walked {
+		inWalk[e.Rel] = true
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			key := normalizeKey(tt.rel)
+			if inManifest[key] != tt.tracked {
+				t.Errorf("in BuildManifest = %v, want %v", inManifest[key], tt.tracked)
+			}
+			if inWalk[key] != tt.tracked {
+				t.Errorf("in WalkProject = %v, want %v", inWalk[key], tt.tracked)
+			}
+			d, err := ExplainPath(dir, tt.rel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Tracked != tt.tracked {
+				t.Errorf("ExplainPath tracked = %v (%s), want %v", d.Tracked, d.Rule, tt.tracked)
+			}
+		})
+	}
+	// .portsyignore itself is an ordinary project file.
+	if len(ps.Files) != len(walked) {
+		t.Errorf("BuildManifest has %d files, WalkProject %d", len(ps.Files), len(walked))
+	}
+}
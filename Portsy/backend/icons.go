@@ -0,0 +1,46 @@
+package backend
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// projectIconRel is where Live keeps a project's folder icon.
+const projectIconRel = "Ableton Project Info/AProject.ico"
+
+// FolderIconEnv turns folder icons on ("1") or off ("0") when
+// PullOptions.SetFolderIcon is unset; by default only Windows sets them.
+const FolderIconEnv = "PORTSY_FOLDER_ICON"
+
+// defaultFolderIcon is the SetFolderIcon default: FolderIconEnv if it
+// parses, else on for Windows only.
+func defaultFolderIcon() bool {
+	if on, err := strconv.ParseBool(os.Getenv(FolderIconEnv)); err == nil {
+		return on
+	}
+	return runtime.GOOS == "windows"
+}
+
+// EnsureFolderIcon gives projectPath the project's Ableton icon where the
+// platform has custom folder icons (desktop.ini on Windows, the Finder's
+// custom icon on macOS; a no-op elsewhere). Projects without an icon file
+// are left alone.
+func EnsureFolderIcon(projectPath string) error { return ensureFolderIcon(projectPath) }
+
+// EnsureAbletonFolderIcon is the former name of EnsureFolderIcon.
+//
+// Deprecated: use EnsureFolderIcon.
+func EnsureAbletonFolderIcon(projectPath string) error { return EnsureFolderIcon(projectPath) }
+
+// desktopINI is the desktop.ini that points Explorer at iconRel (relative,
+// backslash-separated): CRLF lines, no indentation, as Explorer expects.
+// IconFile/IconIndex are kept for pre-Vista shells that ignore IconResource.
+func desktopINI(iconRel string) []byte {
+	iconRel = strings.ReplaceAll(iconRel, "/", `\`)
+	return []byte("[.ShellClassInfo]\r\n" +
+		"IconResource=" + iconRel + ",0\r\n" +
+		"IconFile=" + iconRel + "\r\n" +
+		"IconIndex=0\r\n")
+}
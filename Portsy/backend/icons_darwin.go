@@ -0,0 +1,51 @@
+//go:build darwin
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// setIconJXA sets argv[1]'s custom icon from the image file argv[0] through
+// NSWorkspace, which writes the hidden "Icon\r" file and the folder's
+// custom-icon Finder flag. NSImage reads Live's .ico as is.
+const setIconJXA = `ObjC.import("AppKit");
+function run(argv) {
+	const img = $.NSImage.alloc.initWithContentsOfFile(argv[0]);
+	if (img.isNil()) throw new Error("cannot read " + argv[0]);
+	if (!$.NSWorkspace.sharedWorkspace.setIconForFileOptions(img, argv[1], 0)) throw new Error("Finder refused the icon");
+}`
+
+// folderIconTimeout bounds the osascript call.
+const folderIconTimeout = 15 * time.Second
+
+// ensureFolderIcon goes through osascript's JavaScript bridge rather than
+// cgo, so the CLI still cross-compiles. A folder that already has a custom
+// icon is left as is.
+func ensureFolderIcon(projectPath string) error {
+	projectPath = filepath.Clean(projectPath)
+	iconPath := filepath.Join(projectPath, filepath.FromSlash(projectIconRel))
+	if _, err := os.Stat(iconPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("stat icon: %w", err)
+	}
+	if _, err := os.Lstat(filepath.Join(projectPath, "Icon\r")); err == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), folderIconTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "osascript", "-l", "JavaScript", "-e", setIconJXA, iconPath, projectPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("set folder icon: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
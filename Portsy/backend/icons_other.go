@@ -1,5 +1,5 @@
-//go:build !windows
+//go:build !windows && !darwin
 
 package backend
 
-func EnsureAbletonFolderIcon(projectPath string) error { return nil }
+func ensureFolderIcon(projectPath string) error { return nil }
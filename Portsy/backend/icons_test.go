@@ -0,0 +1,67 @@
+package backend
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPullOptionsFolderIcon(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name string
+		opt  *bool
+		env  string
+		want bool
+	}{
+		{name: "explicit on", opt: &on, env: "0", want: true},
+		{name: "explicit off", opt: &off, env: "1", want: false},
+		{name: "env on", env: "1", want: true},
+		{name: "env off", env: "false", want: false},
+		{name: "default", want: runtime.GOOS == "windows"},
+		{name: "unparseable env", env: "sometimes", want: runtime.GOOS == "windows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(FolderIconEnv, tt.env)
+			if got := (PullOptions{SetFolderIcon: tt.opt}).folderIcon(); got != tt.want {
+				t.Errorf("folderIcon() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDesktopINIHasNoIndentation: Explorer skips keys with leading
+// whitespace, which is how the old template lost the icon.
+func TestDesktopINIHasNoIndentation(t *testing.T) {
+	for _, line := range strings.Split(string(desktopINI(projectIconRel)), "\r\n") {
+		if strings.TrimLeft(line, " \t") != line {
+			t.Errorf("indented line %q", line)
+		}
+		if strings.Contains(line, "\t") {
+			t.Errorf("tab in line %q", line)
+		}
+	}
+}
+
+func TestDesktopINI(t *testing.T) {
+	want := "[.ShellClassInfo]\r\n" +
+		"IconResource=Ableton Project Info\\AProject.ico,0\r\n" +
+		"IconFile=Ableton Project Info\\AProject.ico\r\n" +
+		"IconIndex=0\r\n"
+	tests := []struct {
+		name    string
+		iconRel string
+	}{
+		{name: "slash path", iconRel: "Ableton Project Info/AProject.ico"},
+		{name: "backslash path", iconRel: `Ableton Project Info\AProject.ico`},
+		{name: "default", iconRel: projectIconRel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(desktopINI(tt.iconRel)); got != want {
+				t.Errorf("desktopINI(%q) =\n%q\nwant\n%q", tt.iconRel, got, want)
+			}
+		})
+	}
+}
@@ -6,16 +6,24 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"unsafe"
 
 	"golang.org/x/sys/windows"
 )
 
-func EnsureAbletonFolderIcon(projectPath string) error {
+var procSHChangeNotify = windows.NewLazySystemDLL("shell32.dll").NewProc("SHChangeNotify")
+
+// SHChangeNotify event/flags (shlobj_core.h).
+const (
+	shcneUpdateDir = 0x00001000
+	shcnfPathW     = 0x0005
+)
+
+func ensureFolderIcon(projectPath string) error {
 	projectPath = filepath.Clean(projectPath)
 
 	// Default icon location inside the project
-	iconRel := filepath.Join("Ableton Project Info", "AProject.ico")
-	iconPath := filepath.Join(projectPath, iconRel)
+	iconPath := filepath.Join(projectPath, filepath.FromSlash(projectIconRel))
 
 	// If no icon exists, nothing to do.
 	if _, err := os.Stat(iconPath); err != nil {
@@ -27,18 +35,18 @@ func EnsureAbletonFolderIcon(projectPath string) error {
 
 	// Ensure desktop.ini exists with the right contents.
 	iniPath := filepath.Join(projectPath, "desktop.ini")
-	content := []byte(fmt.Sprintf(`[.ShellClassInfo]
-	IconResource=%s,0
-	IconFile=%s
-	IconIndex=0
-	`, filepath.ToSlash(iconRel), filepath.ToSlash(iconRel)))
+	content := desktopINI(projectIconRel)
 
-	// Write or update desktop.ini only if needed.
+	// Write or update desktop.ini only if needed. An existing one is
+	// READONLY (we set it below), so clear that first or the write fails.
 	needWrite := true
 	if b, err := os.ReadFile(iniPath); err == nil && string(b) == string(content) {
 		needWrite = false
 	}
 	if needWrite {
+		if p, err := windows.UTF16PtrFromString(iniPath); err == nil {
+			_ = windows.SetFileAttributes(p, windows.FILE_ATTRIBUTE_NORMAL)
+		}
 		if err := os.WriteFile(iniPath, content, 0o644); err != nil {
 			return fmt.Errorf("write desktop.ini: %w", err)
 		}
@@ -47,13 +55,20 @@ func EnsureAbletonFolderIcon(projectPath string) error {
 	// Set attributes:
 	// - Folder must have SYSTEM for Windows to honor desktop.ini customization.
 	// - desktop.ini should be HIDDEN | SYSTEM | READONLY (conventional).
-	if err := setFileAttrs(projectPath, windows.FILE_ATTRIBUTE_DIRECTORY|windows.FILE_ATTRIBUTE_SYSTEM); err != nil {
+	if err := setFileAttrs(projectPath, windows.FILE_ATTRIBUTE_SYSTEM); err != nil {
 		return fmt.Errorf("set folder attrs: %w", err)
 	}
 	if err := setFileAttrs(iniPath, windows.FILE_ATTRIBUTE_HIDDEN|windows.FILE_ATTRIBUTE_SYSTEM|windows.FILE_ATTRIBUTE_READONLY); err != nil {
 		return fmt.Errorf("set desktop.ini attrs: %w", err)
 	}
 
+	// Explorer caches folder icons; tell it this one changed.
+	if needWrite {
+		if p, err := windows.UTF16PtrFromString(projectPath); err == nil {
+			_, _, _ = procSHChangeNotify.Call(shcneUpdateDir, shcnfPathW, uintptr(unsafe.Pointer(p)), 0)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,73 @@
+//go:build windows
+
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestEnsureFolderIconWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		icon    bool
+		wantINI bool
+	}{
+		{name: "project with icon", icon: true, wantINI: true},
+		{name: "no icon", icon: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.icon {
+				writeTestFile(t, filepath.Join(dir, filepath.FromSlash(projectIconRel)), "ico")
+			}
+			// Twice: the second run has to get past the READONLY ini.
+			for i := 0; i < 2; i++ {
+				if err := EnsureFolderIcon(dir); err != nil {
+					t.Fatalf("EnsureFolderIcon (run %d): %v", i+1, err)
+				}
+			}
+
+			iniPath := filepath.Join(dir, "desktop.ini")
+			b, err := os.ReadFile(iniPath)
+			if !tt.wantINI {
+				if err == nil {
+					t.Fatalf("desktop.ini written for a project without an icon")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != string(desktopINI(projectIconRel)) {
+				t.Errorf("desktop.ini = %q", b)
+			}
+			if !hasAttrs(t, dir, windows.FILE_ATTRIBUTE_SYSTEM) {
+				t.Error("folder is missing FILE_ATTRIBUTE_SYSTEM")
+			}
+			if !hasAttrs(t, iniPath, windows.FILE_ATTRIBUTE_HIDDEN|windows.FILE_ATTRIBUTE_SYSTEM) {
+				t.Error("desktop.ini is missing HIDDEN|SYSTEM")
+			}
+			// Let TempDir cleanup delete it.
+			p, _ := windows.UTF16PtrFromString(iniPath)
+			_ = windows.SetFileAttributes(p, windows.FILE_ATTRIBUTE_NORMAL)
+		})
+	}
+}
+
+func hasAttrs(t *testing.T, path string, want uint32) bool {
+	t.Helper()
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := windows.GetFileAttributes(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got&want == want
+}
@@ -0,0 +1,17 @@
+package backend
+
+import "Portsy/backend/internal/core/scan"
+
+// IgnoreMatcher is a project's compiled .portsyignore; see scan.IgnoreMatcher
+// for the pattern syntax. Match/Rule take normalized rel paths.
+type IgnoreMatcher = scan.IgnoreMatcher
+
+// IgnoreFileName is the ignore list's name at the project root.
+const IgnoreFileName = scan.IgnoreFile
+
+// LoadIgnore compiles projectPath's .portsyignore, the same rules the scan and
+// BuildManifest apply, so the UI can show what it excludes. Returns nil (which
+// matches nothing) when the project has none.
+func LoadIgnore(projectPath string) (*IgnoreMatcher, error) {
+	return scan.LoadIgnore(projectPath)
+}
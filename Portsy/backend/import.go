@@ -0,0 +1,171 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Import phases reported through ImportProgress.Phase.
+const (
+	ImportHashing    = "hashing"
+	ImportUploading  = "uploading"
+	ImportCommitting = "committing"
+	ImportDone       = "done"
+)
+
+// ImportProgress is one progress event from ImportAndPush.
+// Done/Total count files for the current phase; Bytes/TotalBytes are only
+// meaningful while uploading.
+type ImportProgress struct {
+	Phase      string `json:"phase"`
+	Path       string `json:"path,omitempty"`
+	Done       int    `json:"done"`
+	Total      int    `json:"total"`
+	Bytes      int64  `json:"bytes"`
+	TotalBytes int64  `json:"totalBytes"`
+	CommitID   string `json:"commitId,omitempty"`
+	Skipped    int    `json:"skipped,omitempty"` // files left out of the manifest (see BuildManifestDetailed)
+}
+
+// ImportAndPush onboards a local project in one call: hash every file, upload
+// the blobs, then write the first commit (BeginCommit -> FinalizeCommit) and
+// seed the local cache. onProgress may be nil; it is called from a single
+// goroutine, so callers don't need to lock.
+func ImportAndPush(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, projectPath, project, msg string, onProgress func(ImportProgress)) error {
+	emit := func(p ImportProgress) {
+		if onProgress != nil {
+			onProgress(p)
+		}
+	}
+
+	// 1) Hashing
+	res, err := buildManifest(projectPath, loadManifestCache(projectPath), func(done, total int, f manifestFile) {
+		emit(ImportProgress{Phase: ImportHashing, Path: f.rel, Done: done, Total: total})
+	})
+	if err != nil {
+		return fmt.Errorf("manifest: %w", err)
+	}
+	st := res.State
+	for _, sk := range res.Skipped {
+		log.Printf("import: skipped %s: %s", sk.Path, sk.Reason)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	st.ProjectName = project
+	st.ProjectPath = projectPath
+	if st.Algo == "" {
+		st.Algo = "sha256"
+	}
+	scheme, err := meta.GetKeyScheme(ctx, project)
+	if err != nil {
+		return err
+	}
+	st.KeyScheme = scheme
+
+	// 2) Uploading (one job per distinct blob; duplicates share a key)
+	var totalBytes int64
+	byKey := map[string]int{} // key -> first file index
+	var order []string
+	for i := range st.Files {
+		fe := &st.Files[i]
+		fe.R2Key = r2.BuildKeyScheme(scheme, project, fe.Hash)
+		if _, ok := byKey[fe.R2Key]; ok {
+			continue
+		}
+		byKey[fe.R2Key] = i
+		order = append(order, fe.R2Key)
+		totalBytes += fe.Size
+	}
+	emit(ImportProgress{Phase: ImportUploading, Total: len(order), TotalBytes: totalBytes})
+
+	type result struct {
+		key  string
+		etag string
+		err  error
+	}
+	jobs := make(chan string)
+	results := make(chan result)
+	var wg sync.WaitGroup
+
+	workers := max(2, runtime.NumCPU()/2)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				if err := ctx.Err(); err != nil {
+					results <- result{key: key, err: err}
+					continue
+				}
+				fe := st.Files[byKey[key]]
+				abs := filepath.Join(projectPath, filepath.FromSlash(fe.Path))
+				etag, err := r2.UploadIfMissingETag(ctx, abs, key)
+				results <- result{key: key, etag: etag, err: err}
+			}
+		}()
+	}
+	go func() {
+		for _, k := range order {
+			jobs <- k
+		}
+		close(jobs)
+	}()
+
+	var firstErr error
+	var sent int64
+	etags := make(map[string]string, len(order))
+	for i := 0; i < len(order); i++ {
+		r := <-results
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("upload %s: %w", r.key, r.err)
+			}
+			continue
+		}
+		etags[r.key] = r.etag
+		fe := st.Files[byKey[r.key]]
+		sent += fe.Size
+		emit(ImportProgress{Phase: ImportUploading, Path: fe.Path, Done: i + 1, Total: len(order), Bytes: sent, TotalBytes: totalBytes})
+	}
+	wg.Wait()
+	close(results)
+	if firstErr != nil {
+		return firstErr
+	}
+	for i := range st.Files {
+		st.Files[i].ETag = etags[st.Files[i].R2Key]
+	}
+
+	// 3) Committing
+	cm := CommitMeta{
+		ID:        uuid.NewString(),
+		Message:   msg,
+		Timestamp: time.Now().Unix(),
+		Status:    "pending",
+	}
+	emit(ImportProgress{Phase: ImportCommitting, CommitID: cm.ID})
+	if err := meta.BeginCommit(ctx, project, cm, st); err != nil {
+		return fmt.Errorf("begin commit: %w", err)
+	}
+	if err := meta.FinalizeCommit(ctx, project, cm, st, r2.BlobVerifier(scheme, project)); err != nil {
+		return fmt.Errorf("finalize commit: %w", err)
+	}
+
+	if err := WriteCacheFromState(projectPath, st, st.Algo); err != nil {
+		return fmt.Errorf("write cache: %w", err)
+	}
+	if err := recordSyncedCommit(projectPath, cm.ID, st); err != nil {
+		return fmt.Errorf("write cache: %w", err)
+	}
+	emit(ImportProgress{Phase: ImportDone, Done: len(st.Files), Total: len(st.Files), Bytes: sent, TotalBytes: totalBytes, CommitID: cm.ID, Skipped: len(res.Skipped)})
+	return nil
+}
@@ -0,0 +1,40 @@
+package backend
+
+import "sync"
+
+// inFlightFreeSize is the upload size below which files don't count against
+// PushOptions.MaxInFlightBytes: they go up in a single small request.
+const inFlightFreeSize int64 = 8 << 20
+
+// byteBudget caps the bytes of large uploads in flight at once.
+type byteBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	max   int64
+	inUse int64
+}
+
+func newByteBudget(max int64) *byteBudget {
+	b := &byteBudget{max: max}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire blocks until n more bytes fit in the budget. A file bigger than the
+// whole budget is let through once nothing else is in flight, so it runs
+// alone instead of never.
+func (b *byteBudget) acquire(n int64) {
+	b.mu.Lock()
+	for b.inUse > 0 && b.inUse+n > b.max {
+		b.cond.Wait()
+	}
+	b.inUse += n
+	b.mu.Unlock()
+}
+
+func (b *byteBudget) release(n int64) {
+	b.mu.Lock()
+	b.inUse -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
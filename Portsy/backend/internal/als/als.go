@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -17,6 +18,7 @@ func IsALS(p string) bool {
 type Meta struct {
 	DetectedSamples []string // project-relative if we can resolve them later
 	RawXML          []byte   // optional, for debug or future diffs
+	LiveVersion     string   // e.g. "11.3.4"; "" if the root element didn't say
 }
 
 // Read parses a gzipped .als and extracts sample references.
@@ -39,7 +41,101 @@ func Read(path string) (*Meta, error) {
 	}
 
 	refs := extractSampleRefs(xmlBytes)
-	return &Meta{DetectedSamples: refs, RawXML: xmlBytes}, nil
+	ver, _ := liveVersionFrom(bytes.NewReader(xmlBytes))
+	return &Meta{DetectedSamples: refs, RawXML: xmlBytes, LiveVersion: ver}, nil
+}
+
+// ReadLiveVersion returns the Live version that saved the set, decoding only
+// up to the <Ableton> root element (cheap even for huge sets).
+func ReadLiveVersion(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer gr.Close()
+	return liveVersionFrom(gr)
+}
+
+// liveVersionFrom reads the root element's Creator ("Ableton Live 11.3.4"),
+// falling back to MinorVersion ("11.0_433" -> "11.0") on older/odd sets.
+func liveVersionFrom(r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if se.Name.Local != "Ableton" {
+			return "", nil
+		}
+		var creator, minor string
+		for _, a := range se.Attr {
+			switch a.Name.Local {
+			case "Creator":
+				creator = strings.TrimSpace(a.Value)
+			case "MinorVersion":
+				minor = strings.TrimSpace(a.Value)
+			}
+		}
+		if v := strings.TrimSpace(strings.TrimPrefix(creator, "Ableton Live")); v != "" {
+			return v, nil
+		}
+		if i := strings.IndexByte(minor, '_'); i >= 0 {
+			minor = minor[:i]
+		}
+		return minor, nil
+	}
+}
+
+// CompareLiveVersions compares dotted versions numerically ("11.10" > "11.9");
+// suffixes like "b5" are ignored. Returns -1, 0 or 1.
+func CompareLiveVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	var out []int
+	for _, s := range strings.Split(strings.TrimSpace(v), ".") {
+		end := 0
+		for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(s[:end])
+		if err != nil {
+			break
+		}
+		out = append(out, n)
+		if end < len(s) {
+			break // "0b5": stop at the pre-release suffix
+		}
+	}
+	return out
 }
 
 // Ableton XML is huge; we only stream for tags that matter.
@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Info is what an audio file's header says about it.
+type Info struct {
+	Format     string  // "wav", "aiff" or "flac"
+	Duration   float64 // seconds
+	SampleRate int
+	Channels   int
+	BitDepth   int
+}
+
+// InfoSupported reports whether ReadInfo understands files with p's extension.
+func InfoSupported(p string) bool {
+	return Supported(p) || strings.EqualFold(filepath.Ext(p), ".flac")
+}
+
+// ReadInfo reads duration, rate, channels and bit depth from a WAV, AIFF(-C)
+// or FLAC header without decoding any audio.
+func ReadInfo(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadInfoReader(bufio.NewReader(f))
+}
+
+// ReadInfoReader is ReadInfo over an already-open stream.
+func ReadInfoReader(r io.Reader) (*Info, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:4]); err != nil {
+		return nil, ErrUnsupported
+	}
+	if string(hdr[0:4]) == "fLaC" {
+		return flacInfo(r)
+	}
+	if _, err := io.ReadFull(r, hdr[4:]); err != nil {
+		return nil, ErrUnsupported
+	}
+	switch {
+	case string(hdr[0:4]) == "RIFF" && string(hdr[8:12]) == "WAVE":
+		return chunkInfo(r, binary.LittleEndian, "fmt ", "data", "wav")
+	case string(hdr[0:4]) == "FORM" && (string(hdr[8:12]) == "AIFF" || string(hdr[8:12]) == "AIFC"):
+		return chunkInfo(r, binary.BigEndian, "COMM", "SSND", "aiff")
+	}
+	return nil, ErrUnsupported
+}
+
+// chunkInfo walks IFF-style chunks up to the sample chunk, whose size gives
+// a WAV's length (AIFF has the frame count in COMM).
+func chunkInfo(r io.Reader, order binary.ByteOrder, fmtID, dataID, format string) (*Info, error) {
+	var info *Info
+	var frames int64 = -1
+	for {
+		var ch [8]byte
+		if _, err := io.ReadFull(r, ch[:]); err != nil {
+			break
+		}
+		id := string(ch[0:4])
+		size := int64(order.Uint32(ch[4:8]))
+		switch id {
+		case fmtID:
+			b := make([]byte, size)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, fmt.Errorf("audio: read %q chunk: %w", id, err)
+			}
+			if format == "wav" {
+				if len(b) < 16 {
+					return nil, fmt.Errorf("%w: short fmt chunk", ErrUnsupported)
+				}
+				info = &Info{
+					Format:     format,
+					Channels:   int(order.Uint16(b[2:4])),
+					SampleRate: int(order.Uint32(b[4:8])),
+					BitDepth:   int(order.Uint16(b[14:16])),
+				}
+			} else {
+				if len(b) < 18 {
+					return nil, fmt.Errorf("%w: short COMM chunk", ErrUnsupported)
+				}
+				info = &Info{
+					Format:     format,
+					Channels:   int(int16(order.Uint16(b[0:2]))),
+					BitDepth:   int(int16(order.Uint16(b[6:8]))),
+					SampleRate: int(extendedToFloat(b[8:18])),
+				}
+				frames = int64(order.Uint32(b[2:6]))
+			}
+			if size&1 == 1 {
+				_, _ = io.CopyN(io.Discard, r, 1)
+			}
+			continue
+		case dataID:
+			if info == nil {
+				return nil, fmt.Errorf("%w: %q chunk before %q", ErrUnsupported, dataID, fmtID)
+			}
+			if frames < 0 && info.Channels > 0 && info.BitDepth > 0 {
+				frames = size / int64(info.Channels*((info.BitDepth+7)/8))
+			}
+			if frames >= 0 && info.SampleRate > 0 {
+				info.Duration = float64(frames) / float64(info.SampleRate)
+			}
+			return info, nil
+		}
+		if _, err := io.CopyN(io.Discard, r, size+size&1); err != nil {
+			break
+		}
+	}
+	if info != nil {
+		return info, nil // no sample chunk: format known, length not
+	}
+	return nil, fmt.Errorf("%w: missing %q chunk", ErrUnsupported, fmtID)
+}
+
+// flacInfo reads the STREAMINFO block, which FLAC requires to come first.
+func flacInfo(r io.Reader) (*Info, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil || hdr[0]&0x7F != 0 {
+		return nil, fmt.Errorf("%w: no FLAC STREAMINFO", ErrUnsupported)
+	}
+	var b [34]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, fmt.Errorf("%w: short FLAC STREAMINFO", ErrUnsupported)
+	}
+	// Bytes 10..17: rate (20 bits), channels-1 (3), bits-1 (5), samples (36).
+	v := binary.BigEndian.Uint64(b[10:18])
+	info := &Info{
+		Format:     "flac",
+		SampleRate: int(v >> 44),
+		Channels:   int(v>>41&0x7) + 1,
+		BitDepth:   int(v>>36&0x1F) + 1,
+	}
+	if frames := int64(v & (1<<36 - 1)); frames > 0 && info.SampleRate > 0 {
+		info.Duration = float64(frames) / float64(info.SampleRate)
+	}
+	return info, nil
+}
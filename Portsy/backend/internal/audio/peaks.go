@@ -0,0 +1,246 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// PeakData is a waveform overview: Peaks holds one min,max pair per
+// SamplesPerPixel frames, taken across all channels and scaled to -1..1.
+type PeakData struct {
+	SampleRate      int       `json:"sampleRate"`
+	Channels        int       `json:"channels"`
+	Frames          int64     `json:"frames"`
+	SamplesPerPixel int       `json:"samplesPerPixel"`
+	Peaks           []float32 `json:"peaks"`
+}
+
+// pcmFormat is what Peaks needs from a fmt/COMM chunk.
+type pcmFormat struct {
+	channels  int
+	bits      int
+	rate      int
+	float     bool
+	unsigned8 bool // WAV stores 8-bit samples unsigned
+	order     binary.ByteOrder
+}
+
+// Peaks decodes a PCM or float WAV/AIFF(-C) file into min/max peaks.
+func Peaks(path string, samplesPerPixel int) (*PeakData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return PeaksReader(bufio.NewReaderSize(f, 1<<20), samplesPerPixel)
+}
+
+// PeaksReader is Peaks over an already-open stream.
+func PeaksReader(r io.Reader, samplesPerPixel int) (*PeakData, error) {
+	if samplesPerPixel <= 0 {
+		return nil, fmt.Errorf("audio: samples per pixel must be positive, got %d", samplesPerPixel)
+	}
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, ErrUnsupported
+	}
+	switch {
+	case string(hdr[0:4]) == "RIFF" && string(hdr[8:12]) == "WAVE":
+		return peakChunks(r, binary.LittleEndian, "fmt ", "data", parseWAVFormat, samplesPerPixel)
+	case string(hdr[0:4]) == "FORM" && (string(hdr[8:12]) == "AIFF" || string(hdr[8:12]) == "AIFC"):
+		aifc := string(hdr[8:12]) == "AIFC"
+		parse := func(b []byte) (*pcmFormat, error) { return parseAIFFComm(b, aifc) }
+		return peakChunks(r, binary.BigEndian, "COMM", "SSND", parse, samplesPerPixel)
+	}
+	return nil, ErrUnsupported
+}
+
+// peakChunks walks IFF-style chunks like walkChunks, parsing the format
+// chunk and decoding the sample chunk that follows it.
+func peakChunks(r io.Reader, order binary.ByteOrder, fmtID, dataID string, parse func([]byte) (*pcmFormat, error), spp int) (*PeakData, error) {
+	var f *pcmFormat
+	for {
+		var ch [8]byte
+		if _, err := io.ReadFull(r, ch[:]); err != nil {
+			break
+		}
+		id := string(ch[0:4])
+		size := int64(order.Uint32(ch[4:8]))
+		pad := size & 1
+
+		switch id {
+		case fmtID:
+			b := make([]byte, size)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, fmt.Errorf("audio: read %q chunk: %w", id, err)
+			}
+			var err error
+			if f, err = parse(b); err != nil {
+				return nil, err
+			}
+		case dataID:
+			if f == nil {
+				return nil, fmt.Errorf("%w: %q chunk before %q", ErrUnsupported, dataID, fmtID)
+			}
+			data := io.LimitReader(r, size)
+			if dataID == "SSND" {
+				var off [8]byte // offset, block size
+				if _, err := io.ReadFull(data, off[:]); err != nil {
+					return nil, fmt.Errorf("audio: read %q chunk: %w", id, err)
+				}
+				if _, err := io.CopyN(io.Discard, data, int64(binary.BigEndian.Uint32(off[0:4]))); err != nil {
+					return nil, fmt.Errorf("audio: read %q chunk: %w", id, err)
+				}
+			}
+			return f.peaks(data, spp)
+		default:
+			pad += size
+		}
+		if pad > 0 {
+			if _, err := io.CopyN(io.Discard, r, pad); err != nil {
+				break
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: missing %q or %q chunk", ErrUnsupported, fmtID, dataID)
+}
+
+func (f *pcmFormat) peaks(r io.Reader, spp int) (*PeakData, error) {
+	width := f.bits / 8
+	frame := make([]byte, width*f.channels)
+	d := &PeakData{SampleRate: f.rate, Channels: f.channels, SamplesPerPixel: spp}
+	lo, hi, n := float32(1), float32(-1), 0
+	for {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break // a short last frame is dropped
+			}
+			return nil, err
+		}
+		for c := 0; c < f.channels; c++ {
+			v := f.sample(frame[c*width:])
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		d.Frames++
+		if n++; n == spp {
+			d.Peaks = append(d.Peaks, lo, hi)
+			lo, hi, n = 1, -1, 0
+		}
+	}
+	if n > 0 {
+		d.Peaks = append(d.Peaks, lo, hi)
+	}
+	return d, nil
+}
+
+// sample reads one sample at the start of b, scaled to -1..1.
+func (f *pcmFormat) sample(b []byte) float32 {
+	switch {
+	case f.float && f.bits == 32:
+		return math.Float32frombits(f.order.Uint32(b))
+	case f.float:
+		return float32(math.Float64frombits(f.order.Uint64(b)))
+	case f.bits == 8 && f.unsigned8:
+		return (float32(b[0]) - 128) / 128
+	case f.bits == 8:
+		return float32(int8(b[0])) / 128
+	case f.bits == 16:
+		return float32(int16(f.order.Uint16(b))) / 32768
+	case f.bits == 24:
+		var v int32
+		if f.order == binary.LittleEndian {
+			v = int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		} else {
+			v = int32(int8(b[0]))<<16 | int32(b[1])<<8 | int32(b[2])
+		}
+		return float32(v) / (1 << 23)
+	default:
+		return float32(int32(f.order.Uint32(b))) / (1 << 31)
+	}
+}
+
+func (f *pcmFormat) check() (*pcmFormat, error) {
+	ok := f.channels > 0 && f.rate > 0
+	if f.float {
+		ok = ok && (f.bits == 32 || f.bits == 64)
+	} else {
+		ok = ok && (f.bits == 8 || f.bits == 16 || f.bits == 24 || f.bits == 32)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %d channel(s), %d-bit, %d Hz", ErrUnsupported, f.channels, f.bits, f.rate)
+	}
+	return f, nil
+}
+
+// parseWAVFormat reads a WAVE fmt chunk (PCM, IEEE float or extensible).
+func parseWAVFormat(b []byte) (*pcmFormat, error) {
+	if len(b) < 16 {
+		return nil, fmt.Errorf("%w: short fmt chunk", ErrUnsupported)
+	}
+	le := binary.LittleEndian
+	tag := le.Uint16(b[0:2])
+	if tag == 0xFFFE && len(b) >= 26 {
+		tag = le.Uint16(b[24:26]) // WAVE_FORMAT_EXTENSIBLE: sub-format GUID
+	}
+	f := &pcmFormat{
+		channels:  int(le.Uint16(b[2:4])),
+		rate:      int(le.Uint32(b[4:8])),
+		bits:      int(le.Uint16(b[14:16])),
+		unsigned8: true,
+		order:     le,
+	}
+	switch tag {
+	case 1: // PCM
+	case 3: // IEEE float
+		f.float = true
+	default:
+		return nil, fmt.Errorf("%w: WAV format tag %#x", ErrUnsupported, tag)
+	}
+	return f.check()
+}
+
+// parseAIFFComm reads an AIFF/AIFC COMM chunk. AIFC is decoded only when
+// uncompressed (NONE/twos, little-endian sowt, fl32/fl64).
+func parseAIFFComm(b []byte, aifc bool) (*pcmFormat, error) {
+	if len(b) < 18 {
+		return nil, fmt.Errorf("%w: short COMM chunk", ErrUnsupported)
+	}
+	be := binary.BigEndian
+	f := &pcmFormat{
+		channels: int(int16(be.Uint16(b[0:2]))),
+		bits:     int(int16(be.Uint16(b[6:8]))),
+		rate:     int(extendedToFloat(b[8:18])),
+		order:    be,
+	}
+	if aifc && len(b) >= 22 {
+		switch comp := string(b[18:22]); comp {
+		case "NONE", "twos":
+		case "sowt":
+			f.order = binary.LittleEndian
+		case "fl32", "FL32":
+			f.float, f.bits = true, 32
+		case "fl64", "FL64":
+			f.float, f.bits = true, 64
+		default:
+			return nil, fmt.Errorf("%w: AIFC compression %q", ErrUnsupported, comp)
+		}
+	}
+	// Sample sizes that aren't whole bytes are stored padded to the next byte.
+	f.bits = (f.bits + 7) / 8 * 8
+	return f.check()
+}
+
+// extendedToFloat decodes the 80-bit IEEE extended AIFF uses for its rate.
+func extendedToFloat(b []byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b[0:2]) & 0x7FFF)
+	mant := binary.BigEndian.Uint64(b[2:10])
+	if exp == 0 && mant == 0 {
+		return 0
+	}
+	return math.Ldexp(float64(mant), exp-16383-63)
+}
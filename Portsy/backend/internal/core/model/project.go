@@ -6,4 +6,6 @@ type ProjectDoc struct {
 	LastCommitID string   `firestore:"lastCommitId" json:"lastCommitId,omitempty"`
 	LastCommitAt int64    `firestore:"lastCommitAt" json:"lastCommitAt,omitempty"`
 	Last5        []string `firestore:"last5"        json:"last5,omitempty"`
+	Empty        bool     `firestore:"-"            json:"empty,omitempty"` // created but never committed
+	LiveVersion  string   `firestore:"liveVersion"  json:"liveVersion,omitempty"`
 }
@@ -0,0 +1,159 @@
+package scan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the per-project ignore list, at the project root.
+const IgnoreFile = ".portsyignore"
+
+// IgnoreMatcher is a compiled .portsyignore: gitignore-style globs matched
+// against normalized forward-slash rel paths, applied after the built-in
+// ignores. Supported:
+//   - "#" comments and blank lines
+//   - "!pattern" re-includes what an earlier pattern excluded (last match wins)
+//   - "dir/" matches directories only (and so everything under them)
+//   - a pattern with a "/" in it (other than a trailing one) is anchored to the
+//     project root; without one it matches the name at any depth
+//   - "*", "?", "[...]" within a segment and "**" across segments
+//
+// As in git, a file under an excluded directory can't be re-included: the
+// walk never enters the directory.
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	line    string   // the pattern as written, for reporting
+	segs    []string // anchored pattern segments, case-folded
+	negate  bool
+	dirOnly bool
+}
+
+// ParseIgnore compiles .portsyignore content. A malformed glob is an error
+// naming its line, rather than a pattern that silently never matches.
+func ParseIgnore(r io.Reader) (*IgnoreMatcher, error) {
+	m := &IgnoreMatcher{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{line: line}
+		p := line
+		if strings.HasPrefix(p, "!") {
+			rule.negate, p = true, p[1:]
+		} else if strings.HasPrefix(p, `\`) {
+			p = p[1:] // "\#file" / "\!file": literal first character
+		}
+		if strings.HasSuffix(p, "/") {
+			rule.dirOnly, p = true, strings.TrimRight(p, "/")
+		}
+		if !strings.Contains(p, "/") {
+			p = "**/" + p // unanchored: any depth
+		}
+		p = strings.TrimPrefix(p, "/")
+		if p == "" || p == "**/" {
+			continue // nothing left ("/", "!")
+		}
+		for _, seg := range strings.Split(FoldCase(p), "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("%s line %d: %q: %w", IgnoreFile, n, line, err)
+			}
+			rule.segs = append(rule.segs, seg)
+		}
+		m.rules = append(m.rules, rule)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadIgnore reads root/.portsyignore; nil, nil when there isn't one.
+func LoadIgnore(root string) (*IgnoreMatcher, error) {
+	f, err := os.Open(filepath.Join(root, IgnoreFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return ParseIgnore(f)
+}
+
+// Match reports whether rel (normalized, forward slashes) is ignored.
+// isDir says whether rel is a directory, for "dir/" patterns. A nil matcher
+// ignores nothing.
+func (m *IgnoreMatcher) Match(rel string, isDir bool) bool {
+	_, ignored := m.Rule(rel, isDir)
+	return ignored
+}
+
+// Rule is Match plus the deciding pattern as written: the last one matching
+// rel, so "" when none does. A negated rule reports "!pattern" with ignored
+// false.
+func (m *IgnoreMatcher) Rule(rel string, isDir bool) (pattern string, ignored bool) {
+	if m == nil {
+		return "", false
+	}
+	segs := strings.Split(rel, "/")
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if matchSegs(r.segs, segs) {
+			pattern, ignored = r.line, !r.negate
+		}
+	}
+	return pattern, ignored
+}
+
+// Patterns returns the rules as written, in file order.
+func (m *IgnoreMatcher) Patterns() []string {
+	if m == nil {
+		return nil
+	}
+	out := make([]string, len(m.rules))
+	for i, r := range m.rules {
+		out[i] = r.line
+	}
+	return out
+}
+
+// matchSegs matches path segments against pattern segments, "**" standing
+// for zero or more whole segments; a trailing "**" needs at least one, so
+// "foo/**" matches what's inside foo but not foo itself.
+func matchSegs(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			if len(rest) == 0 {
+				return len(segs) > 0
+			}
+			for i := 0; i <= len(segs); i++ {
+				if matchSegs(rest, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
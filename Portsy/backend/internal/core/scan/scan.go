@@ -3,10 +3,12 @@ package scan
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+	"sync/atomic"
 )
 
 type FileEntry struct {
@@ -16,24 +18,71 @@ type FileEntry struct {
 	Mt   int64 // unix nano
 }
 
+// Skipped is an entry the walk could not read.
+type Skipped struct {
+	Rel    string
+	Reason string
+}
+
 // WalkProject walks root and returns a stable, normalized list of files.
-// - Skips .portsy, Build, Cache, VCS/IDE dirs by default.
-// - Skips common junk (.DS_Store).
+// This is the single definition of "tracked file" (BuildManifest uses it too):
+// - Skips .portsy, VCS/IDE dirs and "Ableton Project Info" at any depth.
+// - Skips Build and Cache only as top-level dirs (deeper ones are ordinary content).
+// - Skips common junk (.DS_Store, Thumbs.db, desktop.ini).
 // - Skips symlinked dirs (prevents loops) and symlinked files by default.
-// - Normalizes rel paths to forward slashes; lowercases on Windows (NTFS semantics).
+// - Then applies the project's .portsyignore, if any (see IgnoreMatcher).
+// - Normalizes rel paths to forward slashes; case per FoldCase.
 // - Returns results sorted by Rel for deterministic behavior.
+//
+// The first unreadable entry aborts the walk; see WalkProjectSkipping.
 func WalkProject(root string, ignores map[string]struct{}) ([]FileEntry, error) {
+	return walk(root, ignores, func(p string, err error) error {
+		// Surface which path caused trouble; helpful in UI toasts.
+		return fmt.Errorf("scan: %s: %w", p, err)
+	})
+}
+
+// WalkProjectSkipping is WalkProject that steps over unreadable entries
+// (permissions, locks, long paths) and reports them instead of failing.
+func WalkProjectSkipping(root string, ignores map[string]struct{}) ([]FileEntry, []Skipped, error) {
+	var skipped []Skipped
+	out, err := walk(root, ignores, func(p string, err error) error {
+		rel := p
+		if r, rerr := filepath.Rel(root, p); rerr == nil {
+			rel = normalizeRel(r)
+		}
+		skipped = append(skipped, Skipped{Rel: rel, Reason: err.Error()})
+		return nil
+	})
+	return out, skipped, err
+}
+
+// walk does the traversal; onErr decides whether an unreadable entry aborts
+// (non-nil return) or is stepped over.
+func walk(root string, ignores map[string]struct{}, onErr func(p string, err error) error) ([]FileEntry, error) {
 	var out []FileEntry
 
-	err := filepath.WalkDir(root, func(p string, d os.DirEntry, walkErr error) error {
+	// Parsed once per walk; a bad .portsyignore fails the scan rather than
+	// syncing files the user meant to exclude.
+	userIgnore, err := LoadIgnore(root)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	err = filepath.WalkDir(root, func(p string, d os.DirEntry, walkErr error) error {
 		if walkErr != nil {
-			// Surface which path caused trouble; helpful in UI toasts.
-			return fmt.Errorf("scan: %s: %w", p, walkErr)
+			if err := onErr(p, walkErr); err != nil {
+				return err
+			}
+			if d != nil && d.IsDir() && p != root {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		rel, err := filepath.Rel(root, p)
 		if err != nil {
-			return fmt.Errorf("scan: rel of %q: %w", p, err)
+			return onErr(p, err)
 		}
 
 		// Normalize early so ignore checks are consistent.
@@ -48,7 +97,6 @@ func WalkProject(root string, ignores map[string]struct{}) ([]FileEntry, error)
 
 		// Skip symlinked directories to avoid cycles.
 		if d.IsDir() {
-			// Quick dir ignore (first path segment).
 			if shouldIgnoreDir(rel) {
 				return filepath.SkipDir
 			}
@@ -56,17 +104,20 @@ func WalkProject(root string, ignores map[string]struct{}) ([]FileEntry, error)
 			if isSymlink(d) {
 				return filepath.SkipDir
 			}
+			if userIgnore.Match(rel, true) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
 		// Ignore files: junk, explicit ignores, and symlinked files.
-		if shouldIgnoreFile(rel, ignores) || isSymlink(d) {
+		if shouldIgnoreFile(rel, ignores) || isSymlink(d) || userIgnore.Match(rel, false) {
 			return nil
 		}
 
 		info, e := d.Info()
 		if e != nil {
-			return fmt.Errorf("scan: info %q: %w", p, e)
+			return onErr(p, e)
 		}
 
 		out = append(out, FileEntry{
@@ -97,28 +148,69 @@ func normalizeRel(rel string) string {
 	}
 	// Trim accidental leading "./"
 	rel = strings.TrimPrefix(rel, "./")
-	// NTFS is case-insensitive; lower-casing avoids duplicate logical paths.
-	if runtime.GOOS == "windows" {
-		rel = strings.ToLower(rel)
+	return FoldCase(rel)
+}
+
+// FoldCase is the single case policy for manifest/cache keys: NTFS is
+// case-insensitive, so on Windows paths are lowercased (Unicode-aware, so
+// "Drépanö" and "DRÉPANÖ" agree); elsewhere they are left alone. Every
+// producer of path keys (scanner, BuildManifest, local cache) must use it,
+// or the same file shows up as a phantom add/delete pair.
+//
+// With SetCaseSensitive(true) keys keep their original case on every OS.
+func FoldCase(rel string) string {
+	if runtime.GOOS == "windows" && !caseSensitive.Load() {
+		return strings.ToLower(rel)
 	}
 	return rel
 }
 
+var caseSensitive atomic.Bool
+
+// SetCaseSensitive turns off Windows case folding for path keys.
+//
+// Use it when a project is shared between macOS/Linux and Windows and holds
+// files that differ only by case ("Kick.wav" vs "kick.wav"): folded keys
+// collapse those into one entry and every sync flip-flops between them.
+// Every collaborator must use the same setting, or the same project hashes
+// to different manifests. On Windows two such files still can't coexist on
+// disk; the pull that brings the second one overwrites the first.
+func SetCaseSensitive(on bool) { caseSensitive.Store(on) }
+
+// CaseSensitive reports the current policy (see SetCaseSensitive).
+func CaseSensitive() bool { return caseSensitive.Load() }
+
+// BuiltinExclusion reports which built-in rule drops rel (a normalized file
+// path) from WalkProject: dir is the ignored ancestor directory, junk means
+// the file name itself is on the junk list. Symlinks are not checked here.
+func BuiltinExclusion(rel string) (dir string, junk bool) {
+	segs := strings.Split(rel, "/")
+	for i := 1; i < len(segs); i++ {
+		if prefix := strings.Join(segs[:i], "/"); shouldIgnoreDir(prefix) {
+			return prefix, false
+		}
+	}
+	return "", shouldIgnoreFile(rel, nil)
+}
+
 func shouldIgnoreDir(rel string) bool {
-	// We only need the first path segment to decide “project-level” ignores.
 	rel = strings.ReplaceAll(rel, "\\", "/")
-	first := rel
-	if i := strings.IndexByte(rel, '/'); i >= 0 {
-		first = rel[:i]
-	}
-	switch first {
-	case ".portsy", "Build", "Cache", ".git", ".idea", ".vs", ".svn", ".hg":
+	name := path.Base(rel)
+	switch name {
+	case ".portsy", ".git", ".idea", ".vs", ".svn", ".hg":
 		return true
 	}
-	// Ableton-specific caches (add more as needed)
-	if first == "Ableton Project Info" {
+	// Ableton-specific caches (add more as needed). Nested projects carry their own.
+	if strings.EqualFold(name, "Ableton Project Info") {
 		return true
 	}
+	// Project-level build output only (first path segment).
+	if !strings.Contains(rel, "/") {
+		switch name {
+		case FoldCase("Build"), FoldCase("Cache"):
+			return true
+		}
+	}
 	return false
 }
 
@@ -126,7 +218,8 @@ func shouldIgnoreFile(rel string, ignores map[string]struct{}) bool {
 	rel = strings.ReplaceAll(rel, "\\", "/")
 
 	// Junk / platform artifacts
-	if strings.HasSuffix(rel, ".DS_Store") || strings.HasSuffix(rel, "Thumbs.db") || strings.HasSuffix(rel, "desktop.ini") {
+	switch strings.ToLower(path.Base(rel)) {
+	case ".ds_store", "thumbs.db", "desktop.ini":
 		return true
 	}
 
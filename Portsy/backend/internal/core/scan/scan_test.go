@@ -0,0 +1,173 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFoldCase(t *testing.T) {
+	tests := []struct {
+		in          string
+		wantWindows string // folded
+	}{
+		{in: "Samples/Kick.wav", wantWindows: "samples/kick.wav"},
+		{in: "Drépanö/Set.als", wantWindows: "drépanö/set.als"},
+		{in: "DRÉPANÖ/SET.ALS", wantWindows: "drépanö/set.als"},
+		{in: "Ψυχή/Ήχος.wav", wantWindows: "ψυχή/ήχος.wav"},
+		{in: "サンプル/Kick.wav", wantWindows: "サンプル/kick.wav"},
+		{in: "already/lower.wav", wantWindows: "already/lower.wav"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			want := tt.in
+			if runtime.GOOS == "windows" {
+				want = tt.wantWindows
+			}
+			if got := FoldCase(tt.in); got != want {
+				t.Errorf("FoldCase(%q) = %q, want %q", tt.in, got, want)
+			}
+
+			SetCaseSensitive(true)
+			defer SetCaseSensitive(false)
+			if got := FoldCase(tt.in); got != tt.in {
+				t.Errorf("case-sensitive FoldCase(%q) = %q, want it unchanged", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestParseIgnore(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string // Patterns
+		wantErr string   // substring of the error
+	}{
+		{name: "empty"},
+		{
+			name: "comments and blank lines",
+			in:   "# bounces\n\n*.tmp  \n\t\nRenders/\r\n",
+			want: []string{"*.tmp", "Renders/"},
+		},
+		{name: "escaped hash is a pattern", in: "\\#notes.txt\n", want: []string{"\\#notes.txt"}},
+		{name: "bare slash", in: "/\n!\n*.wav\n", want: []string{"*.wav"}},
+		{name: "malformed glob", in: "*.tmp\nSamples/[kick\n", wantErr: "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseIgnore(strings.NewReader(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseIgnore err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Patterns(); !slices.Equal(got, tt.want) {
+				t.Errorf("Patterns = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns string
+		rel      string
+		isDir    bool
+		want     bool
+		wantRule string
+	}{
+		{name: "no rules", rel: "Set.als"},
+		{name: "unanchored at root", patterns: "*.tmp", rel: "mix.tmp", want: true, wantRule: "*.tmp"},
+		{name: "unanchored at depth", patterns: "*.tmp", rel: "Bounces/old/mix.tmp", want: true, wantRule: "*.tmp"},
+		{name: "unanchored name", patterns: "Freeze", rel: "Samples/Freeze", isDir: true, want: true, wantRule: "Freeze"},
+		{name: "leading slash anchors", patterns: "/Build", rel: "Build", isDir: true, want: true, wantRule: "/Build"},
+		{name: "leading slash not nested", patterns: "/Build", rel: "Samples/Build", isDir: true},
+		{name: "inner slash anchors", patterns: "Samples/*.wav", rel: "Samples/kick.wav", want: true, wantRule: "Samples/*.wav"},
+		{name: "inner slash not nested", patterns: "Samples/*.wav", rel: "Old/Samples/kick.wav"},
+		{name: "star stays in its segment", patterns: "Samples/*.wav", rel: "Samples/Drums/kick.wav"},
+		{name: "dir pattern on dir", patterns: "Renders/", rel: "Renders", isDir: true, want: true, wantRule: "Renders/"},
+		{name: "dir pattern at depth", patterns: "Renders/", rel: "Stems/Renders", isDir: true, want: true, wantRule: "Renders/"},
+		{name: "dir pattern on file", patterns: "Renders/", rel: "Renders"},
+		{name: "leading doublestar at root", patterns: "**/Freeze/*.wav", rel: "Freeze/a.wav", want: true, wantRule: "**/Freeze/*.wav"},
+		{name: "leading doublestar nested", patterns: "**/Freeze/*.wav", rel: "a/b/Freeze/a.wav", want: true, wantRule: "**/Freeze/*.wav"},
+		{name: "inner doublestar, no segments", patterns: "Samples/**/kick.wav", rel: "Samples/kick.wav", want: true, wantRule: "Samples/**/kick.wav"},
+		{name: "inner doublestar, several", patterns: "Samples/**/kick.wav", rel: "Samples/a/b/kick.wav", want: true, wantRule: "Samples/**/kick.wav"},
+		{name: "trailing doublestar inside", patterns: "Renders/**", rel: "Renders/a.wav", want: true, wantRule: "Renders/**"},
+		{name: "trailing doublestar deep", patterns: "Renders/**", rel: "Renders/old/a.wav", want: true, wantRule: "Renders/**"},
+		{name: "trailing doublestar not the dir itself", patterns: "Renders/**", rel: "Renders", isDir: true},
+		{name: "negation after", patterns: "*.wav\n!keep.wav", rel: "keep.wav", wantRule: "!keep.wav"},
+		{name: "negation leaves others", patterns: "*.wav\n!keep.wav", rel: "drop.wav", want: true, wantRule: "*.wav"},
+		{name: "negation before loses", patterns: "!keep.wav\n*.wav", rel: "keep.wav", want: true, wantRule: "*.wav"},
+		{name: "re-excluded", patterns: "*.wav\n!keep.wav\nkeep.wav", rel: "keep.wav", want: true, wantRule: "keep.wav"},
+		{name: "escaped hash", patterns: "\\#notes.txt", rel: "#notes.txt", want: true, wantRule: "\\#notes.txt"},
+		{name: "escaped bang", patterns: "\\!important.wav", rel: "!important.wav", want: true, wantRule: "\\!important.wav"},
+		{name: "escaped bang not a negation", patterns: "*.wav\n\\!important.wav", rel: "important.wav", want: true, wantRule: "*.wav"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseIgnore(strings.NewReader(tt.patterns))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel := FoldCase(tt.rel) // as the walk normalizes it
+			if got := m.Match(rel, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+			}
+			if rule, _ := m.Rule(rel, tt.isDir); rule != tt.wantRule {
+				t.Errorf("Rule(%q) = %q, want %q", tt.rel, rule, tt.wantRule)
+			}
+		})
+	}
+
+	var m *IgnoreMatcher
+	if m.Match("Set.als", false) {
+		t.Error("nil matcher ignores Set.als")
+	}
+}
+
+// TestWalkProjectIgnore re-includes one render out of an ignored set: the
+// walk has to enter Renders/ for the negation to apply.
+func TestWalkProjectIgnore(t *testing.T) {
+	dir := t.TempDir()
+	for rel, body := range map[string]string{
+		"Set.als":            "als",
+		"Renders/mix.wav":    "mix",
+		"Renders/keep.wav":   "keep",
+		"Renders/Old/v1.wav": "v1",
+		IgnoreFile:           "Renders/*.wav\n!Renders/keep.wav\n",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := WalkProject(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want []string
+	for _, e := range entries {
+		got = append(got, e.Rel)
+	}
+	for _, rel := range []string{IgnoreFile, "Renders/Old/v1.wav", "Renders/keep.wav", "Set.als"} {
+		want = append(want, FoldCase(rel))
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("WalkProject = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,340 @@
+package midi
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func IsMIDI(p string) bool {
+	ext := filepath.Ext(p)
+	return strings.EqualFold(ext, ".mid") || strings.EqualFold(ext, ".midi")
+}
+
+// Note is one resolved note (note-on paired with its note-off), in ticks.
+type Note struct {
+	Channel  uint8  `json:"channel"`
+	Key      uint8  `json:"key"`
+	Velocity uint8  `json:"velocity"`
+	Start    uint64 `json:"start"`
+	Duration uint64 `json:"duration"`
+}
+
+// Tempo is a set-tempo meta event (microseconds per quarter note).
+type Tempo struct {
+	Tick      uint64 `json:"tick"`
+	MicrosPQN uint32 `json:"microsPerQuarter"`
+}
+
+type File struct {
+	Format   uint16
+	Division uint16 // ticks per quarter note (or SMPTE, kept raw)
+	Notes    []Note // sorted by Start, Channel, Key
+	Tempos   []Tempo
+}
+
+var ErrNotMIDI = errors.New("midi: not a standard MIDI file")
+
+// Read parses a Standard MIDI File from disk.
+func Read(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(bufio.NewReader(f))
+}
+
+// Parse reads an SMF (format 0/1/2) and extracts notes and tempo changes.
+// Anything we don't need (CCs, pitch bend, sysex, other meta) is skipped.
+func Parse(r io.Reader) (*File, error) {
+	id, body, err := readChunk(r)
+	if err != nil {
+		return nil, err
+	}
+	if id != "MThd" || len(body) < 6 {
+		return nil, ErrNotMIDI
+	}
+	out := &File{
+		Format:   binary.BigEndian.Uint16(body[0:2]),
+		Division: binary.BigEndian.Uint16(body[4:6]),
+	}
+	ntrks := int(binary.BigEndian.Uint16(body[2:4]))
+
+	for i := 0; i < ntrks; {
+		id, body, err := readChunk(r)
+		if err == io.EOF {
+			break // tolerate files that overstate the track count
+		}
+		if err != nil {
+			return nil, err
+		}
+		if id != "MTrk" {
+			continue // unknown chunk types must be ignored per spec
+		}
+		if err := out.parseTrack(body); err != nil {
+			return nil, fmt.Errorf("midi: track %d: %w", i, err)
+		}
+		i++
+	}
+
+	sort.Slice(out.Notes, func(i, j int) bool {
+		a, b := out.Notes[i], out.Notes[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Duration < b.Duration
+	})
+	sort.SliceStable(out.Tempos, func(i, j int) bool { return out.Tempos[i].Tick < out.Tempos[j].Tick })
+	return out, nil
+}
+
+func readChunk(r io.Reader) (string, []byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return "", nil, ErrNotMIDI
+		}
+		return "", nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[4:8])
+	const maxChunk = 64 << 20
+	if n > maxChunk {
+		return "", nil, fmt.Errorf("midi: chunk %q too large (%d bytes)", hdr[:4], n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return "", nil, fmt.Errorf("midi: truncated %q chunk: %w", hdr[:4], err)
+	}
+	return string(hdr[:4]), body, nil
+}
+
+func (f *File) parseTrack(b []byte) error {
+	type noteKey struct{ ch, key uint8 }
+	open := map[noteKey][]Note{} // FIFO of sounding notes per channel/key
+
+	closeNote := func(k noteKey, tick uint64) {
+		q := open[k]
+		if len(q) == 0 {
+			return
+		}
+		n := q[0]
+		open[k] = q[1:]
+		n.Duration = tick - n.Start
+		f.Notes = append(f.Notes, n)
+	}
+
+	var (
+		pos     int
+		tick    uint64
+		running byte
+	)
+	for pos < len(b) {
+		delta, n, err := readVarLen(b[pos:])
+		if err != nil {
+			return err
+		}
+		pos += n
+		tick += uint64(delta)
+		if pos >= len(b) {
+			return io.ErrUnexpectedEOF
+		}
+
+		status := b[pos]
+		switch {
+		case status == 0xFF: // meta
+			if pos+2 > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			typ := b[pos+1]
+			l, n, err := readVarLen(b[pos+2:])
+			if err != nil {
+				return err
+			}
+			start := pos + 2 + n
+			end := start + int(l)
+			if end > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			if typ == 0x51 && l == 3 {
+				d := b[start:end]
+				f.Tempos = append(f.Tempos, Tempo{Tick: tick, MicrosPQN: uint32(d[0])<<16 | uint32(d[1])<<8 | uint32(d[2])})
+			}
+			pos = end
+			if typ == 0x2F { // end of track
+				pos = len(b)
+			}
+			running = 0
+			continue
+		case status == 0xF0 || status == 0xF7: // sysex
+			l, n, err := readVarLen(b[pos+1:])
+			if err != nil {
+				return err
+			}
+			pos += 1 + n + int(l)
+			if pos > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			running = 0
+			continue
+		case status&0x80 != 0:
+			running = status
+			pos++
+		case running == 0:
+			return errors.New("data byte without running status")
+		}
+
+		var dataLen int
+		switch running & 0xF0 {
+		case 0xC0, 0xD0:
+			dataLen = 1
+		default:
+			dataLen = 2
+		}
+		if pos+dataLen > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		data := b[pos : pos+dataLen]
+		pos += dataLen
+
+		ch := running & 0x0F
+		switch running & 0xF0 {
+		case 0x90:
+			k := noteKey{ch, data[0]}
+			if data[1] == 0 { // note-on with velocity 0 == note-off
+				closeNote(k, tick)
+			} else {
+				open[k] = append(open[k], Note{Channel: ch, Key: data[0], Velocity: data[1], Start: tick})
+			}
+		case 0x80:
+			closeNote(noteKey{ch, data[0]}, tick)
+		}
+	}
+
+	// Notes left hanging at end of track end there.
+	for k := range open {
+		for len(open[k]) > 0 {
+			closeNote(k, tick)
+		}
+	}
+	return nil
+}
+
+func readVarLen(b []byte) (uint32, int, error) {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		if i >= len(b) {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		v = v<<7 | uint32(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("variable-length quantity too long")
+}
+
+// NotesHash is a stable fingerprint of the note content (ignores track layout,
+// CCs and meta text), so re-exports with identical notes compare equal.
+func (f *File) NotesHash() string {
+	h := sha256.New()
+	var buf [19]byte
+	for _, n := range f.Notes {
+		buf[0], buf[1], buf[2] = n.Channel, n.Key, n.Velocity
+		binary.BigEndian.PutUint64(buf[3:11], n.Start)
+		binary.BigEndian.PutUint64(buf[11:19], n.Duration)
+		h.Write(buf[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NoteDiff summarizes note-level changes between two versions of a MIDI file.
+type NoteDiff struct {
+	NotesAdded   int  `json:"notesAdded"`
+	NotesRemoved int  `json:"notesRemoved"`
+	TempoChanged bool `json:"tempoChanged"`
+}
+
+// Compare diffs prev against curr as note multisets. A moved note counts as
+// one removed plus one added.
+func Compare(prev, curr *File) NoteDiff {
+	var d NoteDiff
+	counts := map[Note]int{}
+	if prev != nil {
+		for _, n := range prev.Notes {
+			counts[n]++
+		}
+	}
+	if curr != nil {
+		for _, n := range curr.Notes {
+			if counts[n] > 0 {
+				counts[n]--
+				continue
+			}
+			d.NotesAdded++
+		}
+	}
+	for _, c := range counts {
+		d.NotesRemoved += c
+	}
+
+	var pt, ct []Tempo
+	if prev != nil {
+		pt = prev.Tempos
+	}
+	if curr != nil {
+		ct = curr.Tempos
+	}
+	if len(pt) != len(ct) {
+		d.TempoChanged = true
+	} else {
+		for i := range pt {
+			if pt[i] != ct[i] {
+				d.TempoChanged = true
+				break
+			}
+		}
+	}
+	return d
+}
+
+// Summary renders the diff for humans, e.g. "+5 notes, tempo unchanged".
+func (d NoteDiff) Summary() string {
+	var parts []string
+	if d.NotesAdded > 0 {
+		parts = append(parts, fmt.Sprintf("+%d %s", d.NotesAdded, plural(d.NotesAdded, "note")))
+	}
+	if d.NotesRemoved > 0 {
+		parts = append(parts, fmt.Sprintf("-%d %s", d.NotesRemoved, plural(d.NotesRemoved, "note")))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "notes unchanged")
+	}
+	if d.TempoChanged {
+		parts = append(parts, "tempo changed")
+	} else {
+		parts = append(parts, "tempo unchanged")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, s string) string {
+	if n == 1 {
+		return s
+	}
+	return s + "s"
+}
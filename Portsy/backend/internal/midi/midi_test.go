@@ -0,0 +1,209 @@
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chunk frames body as an SMF chunk.
+func chunk(id string, body ...byte) []byte {
+	return append(binary.BigEndian.AppendUint32([]byte(id), uint32(len(body))), body...)
+}
+
+// smf is a format-1 file at 96 ticks per quarter with the given chunks
+// following the header, which claims ntrks tracks.
+func smf(ntrks uint16, chunks ...[]byte) []byte {
+	out := chunk("MThd", 0, 1, byte(ntrks>>8), byte(ntrks), 0, 96)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// Track events: delta time, then the event bytes.
+var (
+	tempo120 = []byte{0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20} // 500000 µs/quarter
+	endTrack = []byte{0x00, 0xFF, 0x2F, 0x00}
+)
+
+func track(events ...[]byte) []byte {
+	return chunk("MTrk", bytes.Join(events, nil)...)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         []byte
+		wantNotes  []Note
+		wantTempos []Tempo
+		wantErr    error  // errors.Is target
+		wantErrMsg string // substring, when there's no sentinel
+	}{
+		{
+			name: "notes and tempo",
+			in: smf(1, track(
+				tempo120,
+				[]byte{0x00, 0x90, 60, 100}, // C4 on
+				[]byte{0x60, 0x80, 60, 0},   // off one quarter later
+				[]byte{0x00, 0x91, 64, 90},  // E4 on, channel 2
+				[]byte{0x30, 0x81, 64, 0},
+				endTrack,
+			)),
+			wantNotes: []Note{
+				{Channel: 0, Key: 60, Velocity: 100, Start: 0, Duration: 96},
+				{Channel: 1, Key: 64, Velocity: 90, Start: 96, Duration: 48},
+			},
+			wantTempos: []Tempo{{Tick: 0, MicrosPQN: 500000}},
+		},
+		{
+			name: "running status and velocity-0 note-off",
+			in: smf(1, track(
+				[]byte{0x00, 0x90, 60, 100},
+				[]byte{0x00, 64, 100}, // running status: second note-on
+				[]byte{0x60, 60, 0},   // velocity 0 closes C4
+				[]byte{0x00, 64, 0},
+				endTrack,
+			)),
+			wantNotes: []Note{
+				{Key: 60, Velocity: 100, Duration: 96},
+				{Key: 64, Velocity: 100, Duration: 96},
+			},
+		},
+		{
+			name: "notes across tracks sorted, tempo change",
+			in: smf(2,
+				track(tempo120, []byte{0x83, 0x00, 0xFF, 0x51, 0x03, 0x09, 0x27, 0xC0}, endTrack), // 600000 at tick 384
+				track([]byte{0x60, 0x90, 62, 80}, []byte{0x60, 0x80, 62, 0}, []byte{0x00, 0x90, 60, 80}, []byte{0x10, 0x80, 60, 0}, endTrack),
+			),
+			wantNotes: []Note{
+				{Key: 62, Velocity: 80, Start: 96, Duration: 96},
+				{Key: 60, Velocity: 80, Start: 192, Duration: 16},
+			},
+			wantTempos: []Tempo{{Tick: 0, MicrosPQN: 500000}, {Tick: 384, MicrosPQN: 600000}},
+		},
+		{
+			name:      "hanging note ends with the track",
+			in:        smf(1, track([]byte{0x00, 0x90, 60, 100}, []byte{0x40, 0xFF, 0x2F, 0x00})),
+			wantNotes: []Note{{Key: 60, Velocity: 100, Duration: 64}},
+		},
+		{
+			name: "skipped events",
+			in: smf(1, track(
+				[]byte{0x00, 0xB0, 7, 100},                         // CC
+				[]byte{0x00, 0xC0, 5},                              // program change, one data byte
+				[]byte{0x00, 0xF0, 0x03, 0x43, 0x12, 0xF7},         // sysex
+				[]byte{0x00, 0xFF, 0x03, 0x04, 'B', 'a', 's', 's'}, // track name
+				[]byte{0x00, 0x90, 36, 127},
+				[]byte{0x18, 0x80, 36, 0},
+				endTrack,
+			)),
+			wantNotes: []Note{{Key: 36, Velocity: 127, Duration: 24}},
+		},
+		{
+			name:      "unknown chunk between tracks",
+			in:        smf(1, chunk("XFIH", 1, 2, 3), track([]byte{0x00, 0x90, 60, 1}, []byte{0x01, 0x80, 60, 0}, endTrack)),
+			wantNotes: []Note{{Key: 60, Velocity: 1, Duration: 1}},
+		},
+		{
+			name:      "track count overstated",
+			in:        smf(3, track([]byte{0x00, 0x90, 60, 1}, []byte{0x01, 0x80, 60, 0}, endTrack)),
+			wantNotes: []Note{{Key: 60, Velocity: 1, Duration: 1}},
+		},
+		{name: "empty", in: nil, wantErr: io.EOF},
+		{name: "not midi", in: chunk("RIFF", 'W', 'A', 'V', 'E', 0, 0), wantErr: ErrNotMIDI},
+		{name: "short header chunk", in: chunk("MThd", 0, 1, 0, 1), wantErr: ErrNotMIDI},
+		{name: "truncated chunk header", in: []byte("MThd\x00\x00"), wantErr: ErrNotMIDI},
+		{name: "truncated header body", in: chunk("MThd", 0, 1, 0, 1, 0, 96)[:10], wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated track chunk", in: smf(1, track([]byte{0x00, 0x90, 60, 100}, endTrack)[:12]), wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated track header", in: append(smf(1), 'M', 'T', 'r', 'k', 0), wantErr: ErrNotMIDI},
+		{name: "oversized chunk", in: smf(1, []byte{'M', 'T', 'r', 'k', 0x7F, 0xFF, 0xFF, 0xFF}), wantErrMsg: "too large"},
+		{name: "data byte without status", in: smf(1, track([]byte{0x00, 60, 100})), wantErrMsg: "running status"},
+		{name: "event cut off", in: smf(1, track([]byte{0x00, 0x90, 60})), wantErr: io.ErrUnexpectedEOF},
+		{name: "delta without event", in: smf(1, track([]byte{0x00})), wantErr: io.ErrUnexpectedEOF},
+		{name: "meta length past track end", in: smf(1, track([]byte{0x00, 0xFF, 0x03, 0x10, 'x'})), wantErr: io.ErrUnexpectedEOF},
+		{name: "sysex past track end", in: smf(1, track([]byte{0x00, 0xF0, 0x10, 0x43})), wantErr: io.ErrUnexpectedEOF},
+		{name: "varlen too long", in: smf(1, track([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x00})), wantErrMsg: "too long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(bytes.NewReader(tt.in))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantErrMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("Parse err = %v, want it to mention %q", err, tt.wantErrMsg)
+				}
+				return
+			case err != nil:
+				t.Fatalf("Parse: %v", err)
+			}
+			if f.Format != 1 || f.Division != 96 {
+				t.Errorf("header = format %d, division %d; want 1, 96", f.Format, f.Division)
+			}
+			if !reflect.DeepEqual(f.Notes, tt.wantNotes) {
+				t.Errorf("Notes = %+v, want %+v", f.Notes, tt.wantNotes)
+			}
+			if !reflect.DeepEqual(f.Tempos, tt.wantTempos) {
+				t.Errorf("Tempos = %+v, want %+v", f.Tempos, tt.wantTempos)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c4 := Note{Key: 60, Velocity: 100, Duration: 96}
+	e4 := Note{Key: 64, Velocity: 100, Duration: 96}
+	moved := c4
+	moved.Start = 96
+	tests := []struct {
+		name        string
+		prev, curr  *File
+		want        NoteDiff
+		wantSummary string
+	}{
+		{
+			name: "identical", prev: &File{Notes: []Note{c4, e4}}, curr: &File{Notes: []Note{c4, e4}},
+			wantSummary: "notes unchanged, tempo unchanged",
+		},
+		{
+			name: "added", prev: &File{Notes: []Note{c4}}, curr: &File{Notes: []Note{c4, e4}},
+			want: NoteDiff{NotesAdded: 1}, wantSummary: "+1 note, tempo unchanged",
+		},
+		{
+			name: "moved", prev: &File{Notes: []Note{c4, e4}}, curr: &File{Notes: []Note{moved, e4}},
+			want: NoteDiff{NotesAdded: 1, NotesRemoved: 1}, wantSummary: "+1 note, -1 note, tempo unchanged",
+		},
+		{
+			name: "doubled note", prev: &File{Notes: []Note{c4}}, curr: &File{Notes: []Note{c4, c4, c4}},
+			want: NoteDiff{NotesAdded: 2}, wantSummary: "+2 notes, tempo unchanged",
+		},
+		{
+			name: "tempo", prev: &File{Tempos: []Tempo{{MicrosPQN: 500000}}}, curr: &File{Tempos: []Tempo{{MicrosPQN: 600000}}},
+			want: NoteDiff{TempoChanged: true}, wantSummary: "notes unchanged, tempo changed",
+		},
+		{
+			name: "no previous", curr: &File{Notes: []Note{c4, e4}, Tempos: []Tempo{{MicrosPQN: 500000}}},
+			want: NoteDiff{NotesAdded: 2, TempoChanged: true}, wantSummary: "+2 notes, tempo changed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Compare(tt.prev, tt.curr)
+			if d != tt.want {
+				t.Errorf("Compare = %+v, want %+v", d, tt.want)
+			}
+			if got := d.Summary(); got != tt.wantSummary {
+				t.Errorf("Summary = %q, want %q", got, tt.wantSummary)
+			}
+		})
+	}
+}
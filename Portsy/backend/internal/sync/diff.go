@@ -10,15 +10,16 @@ const (
 	Added    ChangeType = "added"
 	Modified ChangeType = "modified"
 	Deleted  ChangeType = "deleted"
-	Renamed  ChangeType = "renamed" // reserved for later
+	Renamed  ChangeType = "renamed" // same content, new path (see pairRenames)
 )
 
 type Change struct {
 	Path      string
+	OldPath   string // Renamed only: the path before the rename
 	Type      ChangeType
 	OldHash   string
 	NewHash   string
-	ByteDelta int64 // For push: bytes to upload (Added/Modified = size, Deleted = 0)
+	ByteDelta int64 // For push: bytes to upload (Added/Modified = size, Deleted/Renamed = 0)
 }
 
 type ChangeSet struct {
@@ -31,6 +32,7 @@ type ChangeSet struct {
 // - Added: in local, not in remote
 // - Modified: hashes differ
 // - Deleted: in remote, not in local
+// - Renamed: one Deleted and one Added with the same hash
 // sizes: local path -> size (used to estimate upload cost)
 //
 // ByteDelta policy (push-oriented):
@@ -77,6 +79,7 @@ func Diff(local map[string]string, remote map[string]string, sizes map[string]in
 			cs.Counts[Deleted]++
 		}
 	}
+	cs.Files = pairRenames(cs.Files, cs.Counts)
 
 	// Deterministic ordering: Type priority, then path lexicographically.
 	sort.Slice(cs.Files, func(i, j int) bool {
@@ -105,6 +108,50 @@ func Diff(local map[string]string, remote map[string]string, sizes map[string]in
 	return cs
 }
 
+// pairRenames folds a Deleted and an Added change with the same hash into one
+// Renamed change at the new path. A hash shared by several added or several
+// deleted paths is ambiguous and stays as adds/deletes.
+func pairRenames(files []Change, counts map[ChangeType]int) []Change {
+	added := map[string][]int{}
+	deleted := map[string][]int{}
+	for i, c := range files {
+		switch {
+		case c.Type == Added && c.NewHash != "":
+			added[c.NewHash] = append(added[c.NewHash], i)
+		case c.Type == Deleted && c.OldHash != "":
+			deleted[c.OldHash] = append(deleted[c.OldHash], i)
+		}
+	}
+	drop := map[int]bool{}
+	for h, ai := range added {
+		di := deleted[h]
+		if len(ai) != 1 || len(di) != 1 {
+			continue
+		}
+		a := &files[ai[0]]
+		a.Type, a.OldPath, a.OldHash, a.ByteDelta = Renamed, files[di[0]].Path, h, 0
+		drop[di[0]] = true
+		counts[Renamed]++
+		counts[Added]--
+		counts[Deleted]--
+	}
+	if len(drop) == 0 {
+		return files
+	}
+	for _, t := range []ChangeType{Added, Deleted} {
+		if counts[t] == 0 {
+			delete(counts, t)
+		}
+	}
+	out := files[:0]
+	for i, c := range files {
+		if !drop[i] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
 // HasChanges is a convenience for UI logic.
 func (cs ChangeSet) HasChanges() bool {
 	return len(cs.Files) > 0
@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRenames(t *testing.T) {
+	tests := []struct {
+		name       string
+		local      map[string]string
+		remote     map[string]string
+		sizes      map[string]int64
+		want       []Change
+		wantCounts map[ChangeType]int
+	}{
+		{
+			name:   "one to one",
+			local:  map[string]string{"Samples/kick.wav": "aa", "Set.als": "s1"},
+			remote: map[string]string{"kick.wav": "aa", "Set.als": "s1"},
+			sizes:  map[string]int64{"Samples/kick.wav": 100},
+			want: []Change{
+				{Path: "Samples/kick.wav", OldPath: "kick.wav", Type: Renamed, OldHash: "aa", NewHash: "aa"},
+			},
+			wantCounts: map[ChangeType]int{Renamed: 1},
+		},
+		{
+			name:   "rename next to an edit",
+			local:  map[string]string{"Samples/kick.wav": "aa", "Set.als": "s2"},
+			remote: map[string]string{"kick.wav": "aa", "Set.als": "s1"},
+			sizes:  map[string]int64{"Set.als": 7},
+			want: []Change{
+				{Path: "Set.als", Type: Modified, OldHash: "s1", NewHash: "s2", ByteDelta: 7},
+				{Path: "Samples/kick.wav", OldPath: "kick.wav", Type: Renamed, OldHash: "aa", NewHash: "aa"},
+			},
+			wantCounts: map[ChangeType]int{Modified: 1, Renamed: 1},
+		},
+		{
+			name:   "several adds share the deleted hash",
+			local:  map[string]string{"a/kick.wav": "aa", "b/kick.wav": "aa"},
+			remote: map[string]string{"kick.wav": "aa"},
+			sizes:  map[string]int64{"a/kick.wav": 100, "b/kick.wav": 100},
+			want: []Change{
+				{Path: "a/kick.wav", Type: Added, NewHash: "aa", ByteDelta: 100},
+				{Path: "b/kick.wav", Type: Added, NewHash: "aa", ByteDelta: 100},
+				{Path: "kick.wav", Type: Deleted, OldHash: "aa"},
+			},
+			wantCounts: map[ChangeType]int{Added: 2, Deleted: 1},
+		},
+		{
+			name:   "several deletes share the added hash",
+			local:  map[string]string{"kick.wav": "aa"},
+			remote: map[string]string{"a/kick.wav": "aa", "b/kick.wav": "aa"},
+			sizes:  map[string]int64{"kick.wav": 100},
+			want: []Change{
+				{Path: "kick.wav", Type: Added, NewHash: "aa", ByteDelta: 100},
+				{Path: "a/kick.wav", Type: Deleted, OldHash: "aa"},
+				{Path: "b/kick.wav", Type: Deleted, OldHash: "aa"},
+			},
+			wantCounts: map[ChangeType]int{Added: 1, Deleted: 2},
+		},
+		{
+			name:   "different hashes",
+			local:  map[string]string{"new.wav": "bb"},
+			remote: map[string]string{"old.wav": "aa"},
+			sizes:  map[string]int64{"new.wav": 5},
+			want: []Change{
+				{Path: "new.wav", Type: Added, NewHash: "bb", ByteDelta: 5},
+				{Path: "old.wav", Type: Deleted, OldHash: "aa"},
+			},
+			wantCounts: map[ChangeType]int{Added: 1, Deleted: 1},
+		},
+		{
+			name:   "ordered by type then path",
+			local:  map[string]string{"z/b.wav": "bb", "y/a.wav": "aa", "new.wav": "cc", "Set.als": "s2"},
+			remote: map[string]string{"b.wav": "bb", "a.wav": "aa", "gone.wav": "dd", "Set.als": "s1"},
+			sizes:  map[string]int64{"new.wav": 3, "Set.als": 7},
+			want: []Change{
+				{Path: "new.wav", Type: Added, NewHash: "cc", ByteDelta: 3},
+				{Path: "Set.als", Type: Modified, OldHash: "s1", NewHash: "s2", ByteDelta: 7},
+				{Path: "gone.wav", Type: Deleted, OldHash: "dd"},
+				{Path: "y/a.wav", OldPath: "a.wav", Type: Renamed, OldHash: "aa", NewHash: "aa"},
+				{Path: "z/b.wav", OldPath: "b.wav", Type: Renamed, OldHash: "bb", NewHash: "bb"},
+			},
+			wantCounts: map[ChangeType]int{Added: 1, Modified: 1, Deleted: 1, Renamed: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order varies between runs; the result must not.
+			for range 20 {
+				cs := Diff(tt.local, tt.remote, tt.sizes)
+				if !reflect.DeepEqual(cs.Files, tt.want) {
+					t.Fatalf("Files =\n%+v\nwant\n%+v", cs.Files, tt.want)
+				}
+				if !maps.Equal(cs.Counts, tt.wantCounts) {
+					t.Fatalf("Counts = %v, want %v", cs.Counts, tt.wantCounts)
+				}
+			}
+		})
+	}
+}
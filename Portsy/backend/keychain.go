@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeychainService is the service (macOS) / target prefix (Windows) Portsy's
+// secrets are stored under in the OS keychain.
+const KeychainService = "Portsy"
+
+// CredentialSourceEnv selects where credentials come from: "keychain" reads
+// them from the OS keychain first; unset (the default) means env/.env only.
+const CredentialSourceEnv = "PORTSY_CREDENTIALS"
+
+// GCPServiceAccountJSON is the keychain name for the service-account key
+// itself, so the JSON file doesn't have to sit on disk.
+const GCPServiceAccountJSON = "GCP_SERVICE_ACCOUNT_JSON"
+
+// KeychainCredentials are the names LoadKeychainEnv looks up; each one is
+// also the env var it fills.
+var KeychainCredentials = []string{
+	"R2_ACCOUNT_ID", "R2_ACCESS_KEY", "R2_SECRET_KEY", "R2_BUCKET",
+	"GCP_PROJECT_ID", GCPServiceAccountJSON, SigningKeyEnv,
+}
+
+var (
+	ErrCredentialNotFound  = errors.New("credential not found in OS keychain")
+	ErrKeychainUnsupported = errors.New("OS keychain not supported on this platform")
+)
+
+// StoreCredential saves secret under name in the OS keychain, replacing any
+// previous value.
+func StoreCredential(name, secret string) error {
+	if err := keychainStore(KeychainService, name, secret); err != nil {
+		return fmt.Errorf("keychain store %s: %w", name, err)
+	}
+	return nil
+}
+
+// LoadCredential reads name from the OS keychain (ErrCredentialNotFound if
+// it was never stored).
+func LoadCredential(name string) (string, error) {
+	v, err := keychainLoad(KeychainService, name)
+	if err != nil {
+		return "", fmt.Errorf("keychain load %s: %w", name, err)
+	}
+	return v, nil
+}
+
+// UseKeychain reports whether CredentialSourceEnv selects the keychain.
+func UseKeychain() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv(CredentialSourceEnv)), "keychain")
+}
+
+// LoadKeychainEnv sets each of KeychainCredentials that's in the keychain as
+// an env var, overriding .env; names not stored there keep their env value.
+// A no-op unless UseKeychain.
+func LoadKeychainEnv() error {
+	if !UseKeychain() {
+		return nil
+	}
+	for _, name := range KeychainCredentials {
+		v, err := LoadCredential(name)
+		if errors.Is(err, ErrCredentialNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Setenv(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
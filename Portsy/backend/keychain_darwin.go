@@ -0,0 +1,40 @@
+//go:build darwin
+
+package backend
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// macOS Keychain through the security(1) tool.
+
+// errSecItemNotFound is security's exit status for a missing item.
+const errSecItemNotFound = 44
+
+func keychainStore(service, name, secret string) error {
+	// Fed through `security -i` on stdin, hex-encoded (-X), so the secret
+	// never shows up in the process list.
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %q -a %q -X %s\n",
+		service, name, hex.EncodeToString([]byte(secret))))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func keychainLoad(service, name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", name, "-w").Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && ee.ExitCode() == errSecItemNotFound {
+			return "", ErrCredentialNotFound
+		}
+		return "", fmt.Errorf("security: %w", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
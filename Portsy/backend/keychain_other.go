@@ -0,0 +1,7 @@
+//go:build !windows && !darwin
+
+package backend
+
+func keychainStore(service, name, secret string) error { return ErrKeychainUnsupported }
+
+func keychainLoad(service, name string) (string, error) { return "", ErrKeychainUnsupported }
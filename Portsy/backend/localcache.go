@@ -1,9 +1,12 @@
 package backend
 
 import (
+	corehash "Portsy/backend/internal/core/hash"
+	"Portsy/backend/internal/core/scan"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -13,14 +16,46 @@ import (
 
 // LocalCache lives at .portsy/cache.json inside a project.
 type LocalCache struct {
-	Version   int               `json:"version"`   // schema version for migrations
-	Algo      string            `json:"algo"`      // e.g. "sha256" | "blake3"
-	UpdatedAt time.Time         `json:"updatedAt"` // RFC3339 via time.Time marshal
-	Manifest  map[string]string `json:"manifest"`  // path -> content hash (per Algo)
+	Version   int                   `json:"version"`   // schema version for migrations
+	Algo      string                `json:"algo"`      // e.g. "sha256" | "blake3"
+	UpdatedAt time.Time             `json:"updatedAt"` // RFC3339 via time.Time marshal
+	Manifest  map[string]CacheEntry `json:"manifest"`  // path -> content hash (per Algo) plus stat
+
+	// The remote commit this folder was last pushed as or pulled from, and
+	// when. Empty in caches written before sync records existed.
+	LastSyncedCommitID string    `json:"lastSyncedCommitId,omitempty"`
+	LastSyncedAt       time.Time `json:"lastSyncedAt,omitzero"`
+}
+
+// CacheEntry is one cached file: its hash plus the size and mtime it had
+// when hashed, so BuildManifest can skip files that haven't been touched
+// since. ModUnix 0 means the stat is unknown (v1 caches, legacy entries,
+// caches rebuilt from a remote state by RebindProject).
+type CacheEntry struct {
+	Hash    string `json:"hash"`
+	Size    int64  `json:"size,omitempty"`
+	ModUnix int64  `json:"mtime,omitempty"`
+}
+
+// Hashes is the hash-only view of Manifest (path -> hash), the shape
+// DiffManifests and ComputePullStatus compare.
+func (lc *LocalCache) Hashes() map[string]string {
+	m := make(map[string]string, len(lc.Manifest))
+	for k, e := range lc.Manifest {
+		m[k] = e.Hash
+	}
+	return m
 }
 
 // Current schema version for LocalCache.
-const localCacheVersion = 1
+// v2: Manifest entries carry size/mtime (v1 was path -> hash).
+const localCacheVersion = 2
+
+// localCacheV1 decodes a v1 cache, whose manifest was hash-only.
+type localCacheV1 struct {
+	LocalCache
+	Manifest map[string]string `json:"manifest"`
+}
 
 func cacheFile(projectPath string) string {
 	return filepath.Join(projectPath, ".portsy", "cache.json")
@@ -30,8 +65,17 @@ func cacheTmpFile(projectPath string) string {
 	return filepath.Join(projectPath, ".portsy", "cache.json.tmp")
 }
 
+// legacyHashmapFile is the baseline the GUI's change detector used to keep
+// next to cache.json, hashed with corehash.DefaultAlg. Nothing ever wrote it
+// reliably, so rather than maintain two baselines cache.json is the only one:
+// hashmap.json is read once by MigrateLegacyCache and deleted by SaveLocalCache.
+func legacyHashmapFile(projectPath string) string {
+	return filepath.Join(projectPath, ".portsy", "hashmap.json")
+}
+
 // LoadLocalCache reads cache.json, returning an empty cache if it does not exist.
 // Distinguishes ENOENT fom other IO errors and preserves corrupt files for debugging.
+// It never writes the cache; see MigrateLegacyCache for hashmap.json.
 func LoadLocalCache(projectPath string) (*LocalCache, error) {
 	p := cacheFile(projectPath)
 	b, err := os.ReadFile(p)
@@ -40,30 +84,27 @@ func LoadLocalCache(projectPath string) (*LocalCache, error) {
 			return &LocalCache{
 				Version:  localCacheVersion,
 				Algo:     "sha256", // default; caller may override before Save
-				Manifest: map[string]string{},
+				Manifest: map[string]CacheEntry{},
 			}, nil
 		}
 		// Real IO error (permission, transient FS issue) -> surface it.
 		return nil, fmt.Errorf("read local cache: %w", err)
 	}
 
-	var lc LocalCache
-	if err := json.Unmarshal(b, &lc); err != nil {
+	lc, err := decodeLocalCache(b)
+	if err != nil {
 		// Preserve the corrupt file for post-mortem
 		_ = preserveCorruptCache(p, b)
 		return &LocalCache{
 			Version:  localCacheVersion,
 			Algo:     "sha256",
-			Manifest: map[string]string{},
+			Manifest: map[string]CacheEntry{},
 		}, nil
 	}
 
 	// Fill defaults / migrations
 	if lc.Manifest == nil {
-		lc.Manifest = map[string]string{}
-	}
-	if lc.Version == 0 {
-		lc.Version = localCacheVersion
+		lc.Manifest = map[string]CacheEntry{}
 	}
 	if lc.Algo == "" {
 		lc.Algo = "sha256"
@@ -72,9 +113,97 @@ func LoadLocalCache(projectPath string) (*LocalCache, error) {
 	// Normalize keys on load
 	lc.Manifest = normalizeManifestKeys(lc.Manifest)
 
+	return lc, nil
+}
+
+// decodeLocalCache parses cache.json, upgrading older schemas in memory; the
+// next save writes the current version. v1 hashes come over with an unknown
+// stat, so each file is rehashed once.
+func decodeLocalCache(b []byte) (*LocalCache, error) {
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, err
+	}
+	if probe.Version >= 2 {
+		var lc LocalCache
+		if err := json.Unmarshal(b, &lc); err != nil {
+			return nil, err
+		}
+		return &lc, nil
+	}
+
+	var v1 localCacheV1
+	if err := json.Unmarshal(b, &v1); err != nil {
+		return nil, err
+	}
+	lc := v1.LocalCache
+	lc.Version = localCacheVersion
+	lc.Manifest = make(map[string]CacheEntry, len(v1.Manifest))
+	for k, h := range v1.Manifest {
+		lc.Manifest[k] = CacheEntry{Hash: h}
+	}
 	return &lc, nil
 }
 
+// MigrateLegacyCache turns a legacy hashmap.json into cache.json if the
+// project has no cache.json yet, reporting whether it did. It hashes the
+// files hashmap.json lists, so push and pull run it up front instead of every
+// cache read paying for it.
+func MigrateLegacyCache(projectPath string) (bool, error) {
+	if _, err := os.Stat(cacheFile(projectPath)); !errors.Is(err, os.ErrNotExist) {
+		return false, err // cache.json exists (err == nil) or can't be checked
+	}
+	lc, err := migrateHashmap(projectPath)
+	if err != nil {
+		return false, fmt.Errorf("migrate hashmap.json: %w", err)
+	}
+	return lc != nil, nil
+}
+
+// migrateHashmap turns a legacy hashmap.json into cache.json. Its hashes
+// aren't SHA-256, so each entry is checked against the file on disk: files
+// still matching get their SHA-256, the rest keep a "legacy:" value that can
+// never match and so still diff as modified or deleted. Saving the new cache
+// removes the old file. Returns nil, nil when there is none.
+func migrateHashmap(projectPath string) (*LocalCache, error) {
+	p := legacyHashmapFile(projectPath)
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var old map[string]string
+	if err := json.Unmarshal(b, &old); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", p, err)
+	}
+
+	legacy := corehash.New(corehash.DefaultAlg)
+	lc := &LocalCache{
+		Version:  localCacheVersion,
+		Algo:     "sha256",
+		Manifest: make(map[string]CacheEntry, len(old)),
+	}
+	for rel, h := range old {
+		key := normalizeKey(rel)
+		abs := filepath.Join(projectPath, filepath.FromSlash(key))
+		if cur, err := legacy.File(longPath(abs)); err == nil && cur == h {
+			if sum, size, mod, err := HashFileSHA256(abs); err == nil {
+				lc.Manifest[key] = CacheEntry{Hash: sum, Size: size, ModUnix: mod}
+				continue
+			}
+		}
+		lc.Manifest[key] = CacheEntry{Hash: "legacy:" + h}
+	}
+	if err := SaveLocalCache(projectPath, lc); err != nil {
+		return nil, err
+	}
+	return lc, nil
+}
+
 // SaveLocalCache writes the cache atomically: write -> fsync -> rename.
 // This prevents truncated JSON if the process crashes mid-write.
 func SaveLocalCache(projectPath string, lc *LocalCache) error {
@@ -109,7 +238,7 @@ func SaveLocalCache(projectPath string, lc *LocalCache) error {
 	}
 
 	// On POSIX, fsync directory after rename is the belt; file is enough for most cases.
-	if err := os.Rename(tmp, p); err != nil {
+	if err := moveFile(tmp, p); err != nil {
 		return fmt.Errorf("atomic rename cache: %w", err)
 	}
 
@@ -119,6 +248,11 @@ func SaveLocalCache(projectPath string, lc *LocalCache) error {
 		_ = dir.Sync()
 		_ = dir.Close()
 	}
+
+	// cache.json is now the baseline; drop any stale legacy one.
+	if err := os.Remove(legacyHashmapFile(projectPath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("local cache: remove legacy hashmap.json: %v", err)
+	}
 	return nil
 }
 
@@ -143,14 +277,17 @@ func DiffManifests(current, cached map[string]string) (changes []FileChange) {
 	for p, h := range current {
 		cp := normalizeKey(p)
 		if ch, ok := cached[cp]; !ok {
+			debugf("diff: %s: added (%.12s)", cp, h)
 			changes = append(changes, FileChange{Path: cp, Type: "added"})
 		} else if ch != h {
+			debugf("diff: %s: modified (%.12s -> %.12s)", cp, ch, h)
 			changes = append(changes, FileChange{Path: cp, Type: "modified"})
 		}
 		seen[cp] = struct{}{}
 	}
 	for p := range cached {
 		if _, ok := seen[p]; !ok {
+			debugf("diff: %s: deleted", p)
 			changes = append(changes, FileChange{Path: p, Type: "deleted"})
 		}
 	}
@@ -159,8 +296,10 @@ func DiffManifests(current, cached map[string]string) (changes []FileChange) {
 	return
 }
 
-// WriteCacheFromState writes the given state as the latest local cache.
-// The caller should set lc.Algo to the active hashers name if not sha256
+// WriteCacheFromState writes the given state as the latest local cache,
+// keeping the existing sync record (LastSyncedCommitID/At).
+// The caller should set lc.Algo to the active hashers name if not sha256.
+// A failed write is queued for FlushCacheWrites before the error is returned.
 func WriteCacheFromState(projectPath string, ps ProjectState, algo string) error {
 	if algo == "" {
 		algo = "sha256"
@@ -168,7 +307,40 @@ func WriteCacheFromState(projectPath string, ps ProjectState, algo string) error
 	lc := &LocalCache{
 		Version:  localCacheVersion,
 		Algo:     algo,
-		Manifest: ManifestFromState(ps),
+		Manifest: make(map[string]CacheEntry, len(ps.Files)),
+	}
+	for _, f := range ps.Files {
+		lc.Manifest[normalizeKey(f.Path)] = CacheEntry{Hash: f.Hash, Size: f.Size, ModUnix: f.Modified}
+	}
+	if old, err := LoadLocalCache(projectPath); err == nil {
+		lc.LastSyncedCommitID, lc.LastSyncedAt = old.LastSyncedCommitID, old.LastSyncedAt
+	}
+	if err := SaveLocalCache(projectPath, lc); err != nil {
+		QueueCacheWrite(projectPath, ps, algo)
+		return err
+	}
+	forgetCacheWrite(projectPath)
+	return nil
+}
+
+// recordSyncedCommit stamps the local cache with the remote commit the
+// folder now matches ("" clears it), leaving the manifest alone. A folder
+// with no cache.json yet gets one from synced, the state it now matches,
+// rather than an empty manifest that would show every file as added.
+func recordSyncedCommit(projectPath, commitID string, synced ProjectState) error {
+	if _, err := os.Stat(cacheFile(projectPath)); errors.Is(err, os.ErrNotExist) {
+		if err := WriteCacheFromState(projectPath, synced, synced.Algo); err != nil {
+			return err
+		}
+	}
+	lc, err := LoadLocalCache(projectPath)
+	if err != nil {
+		return err
+	}
+	lc.LastSyncedCommitID = commitID
+	lc.LastSyncedAt = time.Time{}
+	if commitID != "" {
+		lc.LastSyncedAt = time.Now().UTC()
 	}
 	return SaveLocalCache(projectPath, lc)
 }
@@ -184,7 +356,7 @@ func preserveCorruptCache(path string, data []byte) error {
 	return nil
 }
 
-func normalizeManifestKeys(in map[string]string) map[string]string {
+func normalizeManifestKeys(in map[string]CacheEntry) map[string]CacheEntry {
 	if len(in) == 0 {
 		return in
 	}
@@ -192,7 +364,7 @@ func normalizeManifestKeys(in map[string]string) map[string]string {
 		// On non-windows, callers already normalized to forward slashes
 		return in
 	}
-	out := make(map[string]string, len(in))
+	out := make(map[string]CacheEntry, len(in))
 	for k, v := range in {
 		out[normalizeKey(k)] = v
 	}
@@ -201,22 +373,5 @@ func normalizeManifestKeys(in map[string]string) map[string]string {
 
 func normalizeKey(p string) string {
 	// Ensure forward slashes, and lowercase on Windows to match scanner policy
-	np := filepath.ToSlash(p)
-	if runtime.GOOS == "windows" {
-		np = lowerASCII(np)
-	}
-	return np
-}
-
-func lowerASCII(s string) string {
-	// fast-path: ASCII lowercasing; avoids locale weirdness
-	b := make([]byte, len(s))
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'A' && c <= 'Z' {
-			c += 'a' - 'A'
-		}
-		b[i] = c
-	}
-	return string(b)
+	return scan.FoldCase(filepath.ToSlash(p))
 }
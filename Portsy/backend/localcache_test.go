@@ -0,0 +1,143 @@
+package backend
+
+import (
+	corehash "Portsy/backend/internal/core/hash"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLegacyHashmap writes a hashmap.json whose entry for each file in
+// match hashes its current bytes, plus one stale entry.
+func writeLegacyHashmap(t *testing.T, dir string, match ...string) {
+	t.Helper()
+	legacy := corehash.New(corehash.DefaultAlg)
+	old := map[string]string{"gone.wav": "stale"}
+	for _, rel := range match {
+		h, err := legacy.File(filepath.Join(dir, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		old[rel] = h
+	}
+	b, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, legacyHashmapFile(dir), string(b))
+}
+
+func TestLoadLocalCacheLeavesHashmapAlone(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "Set.als"), "set")
+	writeLegacyHashmap(t, dir, "Set.als")
+
+	lc, err := LoadLocalCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lc.Manifest) != 0 {
+		t.Errorf("manifest = %v, want empty", lc.Manifest)
+	}
+	if _, err := os.Stat(cacheFile(dir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("cache.json written by a read (stat err %v)", err)
+	}
+	if _, err := os.Stat(legacyHashmapFile(dir)); err != nil {
+		t.Errorf("hashmap.json gone after a read: %v", err)
+	}
+}
+
+func TestMigrateLegacyCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		hashmap   bool
+		cache     bool
+		wantMoved bool
+	}{
+		{name: "nothing to migrate"},
+		{name: "hashmap only", hashmap: true, wantMoved: true},
+		{name: "cache already there", hashmap: true, cache: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, filepath.Join(dir, "Set.als"), "set")
+			if tt.hashmap {
+				writeLegacyHashmap(t, dir, "Set.als")
+			}
+			if tt.cache {
+				writeTestFile(t, cacheFile(dir), `{"version":2,"algo":"sha256","manifest":{}}`)
+			}
+
+			moved, err := MigrateLegacyCache(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved != tt.wantMoved {
+				t.Fatalf("migrated = %v, want %v", moved, tt.wantMoved)
+			}
+			if !moved {
+				return
+			}
+			lc, err := LoadLocalCache(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _, _, _ := HashFileSHA256(filepath.Join(dir, "Set.als"))
+			if got := lc.Manifest["Set.als"].Hash; got != want {
+				t.Errorf("Set.als = %q, want sha256 %q", got, want)
+			}
+			if got := lc.Manifest["gone.wav"].Hash; got != "legacy:stale" {
+				t.Errorf("gone.wav = %q, want legacy:stale", got)
+			}
+			if _, err := os.Stat(legacyHashmapFile(dir)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("hashmap.json kept after migration (stat err %v)", err)
+			}
+		})
+	}
+}
+
+func TestRecordSyncedCommitSeedsMissingCache(t *testing.T) {
+	synced := ProjectState{Algo: "sha256", Files: []FileEntry{{Path: "Set.als", Hash: "aa", Size: 3}}}
+	tests := []struct {
+		name     string
+		existing string // cache.json body, "" for none
+		want     map[string]string
+	}{
+		{name: "no cache", want: map[string]string{"Set.als": "aa"}},
+		{
+			name:     "cache kept",
+			existing: `{"version":2,"algo":"sha256","manifest":{"Set.als":{"hash":"bb"}}}`,
+			want:     map[string]string{"Set.als": "bb"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.existing != "" {
+				writeTestFile(t, cacheFile(dir), tt.existing)
+			}
+			if err := recordSyncedCommit(dir, "c1", synced); err != nil {
+				t.Fatal(err)
+			}
+			lc, err := LoadLocalCache(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lc.LastSyncedCommitID != "c1" {
+				t.Errorf("LastSyncedCommitID = %q, want c1", lc.LastSyncedCommitID)
+			}
+			got := lc.Hashes()
+			if len(got) != len(tt.want) {
+				t.Fatalf("manifest = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package backend
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// Logger receives backend diagnostics. Printf is for the summaries we always
+// log; Debugf carries per-file decisions (upload/copy/carry-forward/skip) and
+// is dropped unless the logger was built with debug on (CLI -v).
+type Logger interface {
+	Printf(format string, args ...any)
+	Debugf(format string, args ...any)
+}
+
+// StdLogger writes through the standard log package.
+type StdLogger struct {
+	Debug bool
+}
+
+func (l StdLogger) Printf(format string, args ...any) { log.Printf(format, args...) }
+
+func (l StdLogger) Debugf(format string, args ...any) {
+	if l.Debug {
+		log.Printf("debug: "+format, args...)
+	}
+}
+
+var logger atomic.Value // holds loggerBox
+
+type loggerBox struct{ Logger }
+
+func init() { logger.Store(loggerBox{StdLogger{}}) }
+
+// SetLogger replaces the process-wide backend logger; nil restores the default.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = StdLogger{}
+	}
+	logger.Store(loggerBox{l})
+}
+
+// debugf logs a per-file decision at debug level.
+func debugf(format string, args ...any) {
+	logger.Load().(loggerBox).Debugf(format, args...)
+}
@@ -0,0 +1,5 @@
+//go:build !windows
+
+package backend
+
+func longPath(p string) string { return p }
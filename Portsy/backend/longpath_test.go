@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDeepPathsAreTracked builds a project nested past Windows' MAX_PATH, as
+// "Collect All and Save" can, and checks the hot paths handle it: the file is
+// in the manifest (not silently skipped), hashes, and copies.
+func TestDeepPathsAreTracked(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth int
+	}{
+		{name: "shallow", depth: 1},
+		{name: "past MAX_PATH", depth: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			rel := filepath.Join(strings.Repeat("Samples Collected"+string(filepath.Separator), tt.depth), "kick.wav")
+			abs := filepath.Join(dir, rel)
+			if tt.depth > 1 && len(abs) < 260 {
+				t.Fatalf("test path is only %d chars", len(abs))
+			}
+			// MkdirAll and WriteFile go through the \\?\ fixups in package os.
+			if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(abs, []byte("kick"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := BuildManifestDetailed(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Skipped) != 0 {
+				t.Fatalf("skipped %+v", res.Skipped)
+			}
+			if len(res.State.Files) != 1 || res.State.Files[0].Path != normalizeKey(rel) {
+				t.Fatalf("manifest = %+v, want just %s", res.State.Files, normalizeKey(rel))
+			}
+			want, _, _, err := HashFileSHA256(abs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.State.Files[0].Hash != want {
+				t.Errorf("manifest hash = %s, want %s", res.State.Files[0].Hash, want)
+			}
+
+			dst := abs + ".copy"
+			if err := copyFile(abs, dst); err != nil {
+				t.Fatalf("copyFile: %v", err)
+			}
+			if got, err := os.ReadFile(dst); err != nil || string(got) != "kick" {
+				t.Errorf("copy = %q, %v", got, err)
+			}
+		})
+	}
+}
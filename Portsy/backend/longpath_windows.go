@@ -0,0 +1,30 @@
+//go:build windows
+
+package backend
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPathLen is where Win32 file APIs start failing without the \\?\ prefix
+// (MAX_PATH minus room for an 8.3 name when creating directories).
+const maxPathLen = 248
+
+// longPath returns p in extended-length form (\\?\C:\... or \\?\UNC\...) once
+// it is long enough to hit MAX_PATH. "Collect All and Save" happily nests
+// samples past that. The prefix disables "."/".." handling, so the path is
+// made absolute and cleaned first.
+func longPath(p string) string {
+	if len(p) < maxPathLen || strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
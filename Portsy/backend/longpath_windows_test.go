@@ -0,0 +1,37 @@
+//go:build windows
+
+package backend
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLongPath(t *testing.T) {
+	deep := `C:\Music\Set Project\` + strings.Repeat(`Samples\Collected\`, 16) + "kick.wav"
+	cwd, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := strings.Repeat(`a\`, 130) + "kick.wav"
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "short", in: `C:\Music\Set.als`, want: `C:\Music\Set.als`},
+		{name: "deep drive path", in: deep, want: `\\?\` + deep},
+		{name: "already extended", in: `\\?\` + deep, want: `\\?\` + deep},
+		{name: "deep UNC path", in: `\\nas\share\` + deep[3:], want: `\\?\UNC\nas\share\` + deep[3:]},
+		{name: "dot segments cleaned", in: `C:\Music\x\..\` + deep[len(`C:\Music\`):], want: `\\?\` + deep},
+		{name: "relative made absolute", in: rel, want: `\\?\` + filepath.Join(cwd, rel)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := longPath(tt.in); got != tt.want {
+				t.Errorf("longPath(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
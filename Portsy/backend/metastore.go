@@ -18,14 +18,33 @@ type MetaStore interface {
 type MetaStoreConfig struct {
 	ProjectID       string
 	CredentialsPath string
-	EmulatorHost    string // optional
+	EmulatorHost    string // optional; no credentials are needed with it
 }
 
 // Keep call-site simple: just pass cfg (no context parameter needed here).
 func NewMetaStore(cfg MetaStoreConfig) (MetaStore, error) {
-	return remote.NewFirebaseStore(remote.Config{
-		ProjectID:       cfg.ProjectID,
-		CredentialsPath: cfg.CredentialsPath,
-		EmulatorHost:    cfg.EmulatorHost,
+	m, err := remote.NewMetaStore(context.Background(), remote.MetaStoreConfig{
+		GCPProjectID:      cfg.ProjectID,
+		ServiceAccountKey: cfg.CredentialsPath,
+		EmulatorHost:      cfg.EmulatorHost,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return firestoreMeta{m}, nil
+}
+
+// firestoreMeta narrows the Firestore store to MetaStore, whose reads leave
+// out the commit. The rest of remote.MetaStore (ListProjects...) is still
+// reachable through the embedded store.
+type firestoreMeta struct{ *remote.MetaStore }
+
+func (f firestoreMeta) GetLatestState(ctx context.Context, project string) (*ProjectState, error) {
+	st, _, err := f.MetaStore.GetLatestState(ctx, project)
+	return st, err
+}
+
+func (f firestoreMeta) GetStateByCommit(ctx context.Context, project, commitID string) (*ProjectState, error) {
+	st, _, err := f.MetaStore.GetStateByCommit(ctx, project, commitID)
+	return st, err
 }
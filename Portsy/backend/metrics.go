@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"Portsy/backend/remote"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Metrics receives operational counters (see remote.Metrics). Pass one via
+// R2Config.Metrics and remote.MetaStoreConfig.Metrics; leave it nil for none.
+type Metrics = remote.Metrics
+
+const (
+	MetricBytesUploaded   = remote.MetricBytesUploaded
+	MetricBytesDownloaded = remote.MetricBytesDownloaded
+	MetricObjectsHeaded   = remote.MetricObjectsHeaded
+	MetricR2Retries       = remote.MetricR2Retries
+	MetricFirestoreReads  = remote.MetricFirestoreReads
+	MetricFirestoreWrites = remote.MetricFirestoreWrites
+	MetricCacheHits       = remote.MetricCacheHits
+	MetricLastSyncUnix    = remote.MetricLastSyncUnix
+)
+
+// Counters is the default in-memory Metrics. The zero value is ready to use.
+// It serves its values in the Prometheus text format, so a sync daemon can
+// mount it at /metrics.
+type Counters struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]int64
+}
+
+func (c *Counters) Count(name string, delta int64) {
+	c.mu.Lock()
+	if c.counters == nil {
+		c.counters = map[string]int64{}
+	}
+	c.counters[name] += delta
+	c.mu.Unlock()
+}
+
+func (c *Counters) Gauge(name string, value int64) {
+	c.mu.Lock()
+	if c.gauges == nil {
+		c.gauges = map[string]int64{}
+	}
+	c.gauges[name] = value
+	c.mu.Unlock()
+}
+
+// Snapshot copies every counter and gauge.
+func (c *Counters) Snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int64, len(c.counters)+len(c.gauges))
+	for k, v := range c.counters {
+		out[k] = v
+	}
+	for k, v := range c.gauges {
+		out[k] = v
+	}
+	return out
+}
+
+// ServeHTTP writes the values in the Prometheus text exposition format.
+func (c *Counters) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	c.mu.Lock()
+	type metric struct {
+		name, kind string
+		value      int64
+	}
+	all := make([]metric, 0, len(c.counters)+len(c.gauges))
+	for k, v := range c.counters {
+		all = append(all, metric{k, "counter", v})
+	}
+	for k, v := range c.gauges {
+		all = append(all, metric{k, "gauge", v})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range all {
+		fmt.Fprintf(w, "# TYPE %s %s\n%s %d\n", m.name, m.kind, m.name, m.value)
+	}
+}
+
+// count / gauge are the R2Client's nil-safe hooks.
+func (r *R2Client) count(name string, delta int64) {
+	if r.cfg.Metrics != nil {
+		r.cfg.Metrics.Count(name, delta)
+	}
+}
+
+func (r *R2Client) gauge(name string, value int64) {
+	if r.cfg.Metrics != nil {
+		r.cfg.Metrics.Gauge(name, value)
+	}
+}
+
+// markSynced records a successful push or pull.
+func (r *R2Client) markSynced() { r.gauge(MetricLastSyncUnix, time.Now().Unix()) }
+
+// countingRetryer wraps the SDK retryer to count retried R2 requests;
+// RetryDelay is only consulted when an attempt is about to be retried.
+type countingRetryer struct {
+	aws.RetryerV2
+	metrics Metrics
+}
+
+func (c countingRetryer) RetryDelay(attempt int, err error) (time.Duration, error) {
+	c.metrics.Count(MetricR2Retries, 1)
+	return c.RetryerV2.RetryDelay(attempt, err)
+}
@@ -0,0 +1,223 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// MigrateReport summarizes a MigrateKeyScheme run.
+type MigrateReport struct {
+	Project        string    `json:"project"`
+	Target         KeyScheme `json:"target"`
+	States         int       `json:"states"`
+	StatesMigrated int       `json:"statesMigrated"`
+	StatesSkipped  int       `json:"statesSkipped"` // already on target
+	BlobsCopied    int       `json:"blobsCopied"`   // distinct keys ensured via server-side copy
+}
+
+// MigrateKeyScheme re-lays out every blob referenced by a project's states
+// under target, without re-uploading:
+//   - per state, CopyIfMissing(oldKey -> newKey) for each file, then rewrite
+//     R2Key/KeyScheme in the state doc
+//   - finally switch the project doc so new commits use target
+//
+// Old keys are left in place (PruneMigratedKeys reclaims them). Safe to re-run after a
+// failure: migrated states are skipped and existing copies are HEAD-checked.
+func MigrateKeyScheme(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project string, target KeyScheme) (*MigrateReport, error) {
+	if !target.Valid() {
+		return nil, fmt.Errorf("migrate: unknown key scheme %d", int(target))
+	}
+	target = target.Resolve()
+	rep := &MigrateReport{Project: project, Target: target}
+
+	ids, err := meta.ListStateIDs(ctx, project)
+	if err != nil {
+		return rep, fmt.Errorf("migrate: %w", err)
+	}
+	sort.Strings(ids) // stable order makes partial runs easier to reason about
+	rep.States = len(ids)
+
+	copied := map[string]bool{} // new key -> ensured this run
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			return rep, fmt.Errorf("migrate: %w", err)
+		}
+
+		changed := st.KeyScheme.Resolve() != target
+		for i := range st.Files {
+			fe := &st.Files[i]
+			from := r2.KeyFor(st, project, *fe)
+			to := r2.BuildKeyScheme(target, project, fe.Hash)
+			if from == to {
+				continue
+			}
+			if !copied[to] {
+				if err := r2.CopyIfMissing(ctx, from, to); err != nil {
+					return rep, fmt.Errorf("migrate: copy %s -> %s: %w", from, to, err)
+				}
+				copied[to] = true
+				rep.BlobsCopied++
+			}
+			fe.R2Key = to
+			changed = true
+		}
+		if !changed {
+			rep.StatesSkipped++
+			continue
+		}
+
+		// Only rewrite once every blob of this state is in place.
+		st.KeyScheme = target
+		if err := meta.ReplaceState(ctx, project, id, *st); err != nil {
+			return rep, fmt.Errorf("migrate: %w", err)
+		}
+		rep.StatesMigrated++
+	}
+
+	if err := meta.SetKeyScheme(ctx, project, target); err != nil {
+		return rep, fmt.Errorf("migrate: set key scheme: %w", err)
+	}
+	return rep, nil
+}
+
+// ManifestMigrateReport summarizes a MigrateStatesToR2 run.
+type ManifestMigrateReport struct {
+	Project        string `json:"project"`
+	States         int    `json:"states"`
+	StatesMigrated int    `json:"statesMigrated"`
+	StatesSkipped  int    `json:"statesSkipped"` // already in R2
+}
+
+// MigrateStatesToR2 moves every inline/gzip state of a project to
+// remote.StateStorageR2: the file list is uploaded as a manifest blob, then
+// the state doc is rewritten as pointer + summary. It also switches meta to
+// write R2 manifests from here on. Re-runnable; R2 states are skipped.
+// blobs is normally the R2Client.
+func MigrateStatesToR2(ctx context.Context, meta *remote.MetaStore, blobs remote.ManifestBlobs, project string) (*ManifestMigrateReport, error) {
+	meta.SetManifestBlobs(blobs)
+	meta.SetManifestsInR2(true)
+	rep := &ManifestMigrateReport{Project: project}
+
+	ids, err := meta.ListStateIDs(ctx, project)
+	if err != nil {
+		return rep, fmt.Errorf("migrate manifests: %w", err)
+	}
+	sort.Strings(ids)
+	rep.States = len(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			return rep, fmt.Errorf("migrate manifests: %w", err)
+		}
+		if st.FilesStorage == remote.StateStorageR2 {
+			rep.StatesSkipped++
+			continue
+		}
+		if err := meta.ReplaceState(ctx, project, id, *st); err != nil {
+			return rep, fmt.Errorf("migrate manifests: %w", err)
+		}
+		rep.StatesMigrated++
+	}
+	return rep, nil
+}
+
+// MigratePruneReport summarizes a PruneMigratedKeys run.
+type MigratePruneReport struct {
+	Project         string   `json:"project"`
+	DryRun          bool     `json:"dryRun,omitempty"`
+	StatesRewritten int      `json:"statesRewritten"` // stragglers still on old keys
+	OldKeys         int      `json:"oldKeys"`         // keys found under <project>/blobs/
+	Deleted         []string `json:"deleted"`         // would-be deletions when DryRun
+	StillReferenced int      `json:"stillReferenced"` // kept: some state points at them
+	Unconfirmed     []string `json:"unconfirmed"`     // kept: global copy missing
+}
+
+// PruneMigratedKeys closes out a move to KeySchemeGlobalBlobs. It first
+// re-runs MigrateKeyScheme so states written with the old layout mid-way
+// (e.g. a push that started before the switch) are copied and rewritten,
+// then deletes each <project>/blobs/<hash> key whose global copy HEADs fine
+// and that no state references any more. Old keys without a confirmed copy
+// are kept and listed. With dryRun nothing is deleted.
+//
+// States are re-read right before deleting, but a push still writing with
+// the old layout could race it; run it when the project is quiet.
+func PruneMigratedKeys(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project string, dryRun bool) (*MigratePruneReport, error) {
+	rep := &MigratePruneReport{Project: project, DryRun: dryRun}
+
+	scheme, err := meta.GetKeyScheme(ctx, project)
+	if err != nil {
+		return rep, fmt.Errorf("prune: %w", err)
+	}
+	if scheme.Resolve() != KeySchemeGlobalBlobs {
+		return rep, fmt.Errorf("prune: project %q is still on %s; migrate it first", project, scheme)
+	}
+	mrep, err := MigrateKeyScheme(ctx, meta, r2, project, KeySchemeGlobalBlobs)
+	if mrep != nil {
+		rep.StatesRewritten = mrep.StatesMigrated
+	}
+	if err != nil {
+		return rep, fmt.Errorf("prune: %w", err)
+	}
+
+	ids, err := meta.ListStateIDs(ctx, project)
+	if err != nil {
+		return rep, fmt.Errorf("prune: %w", err)
+	}
+	referenced := map[string]bool{}
+	for _, id := range ids {
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			// Can't tell what it references, so nothing may be deleted.
+			return rep, fmt.Errorf("prune: %w", err)
+		}
+		for _, fe := range st.Files {
+			referenced[r2.KeyFor(st, project, fe)] = true
+		}
+	}
+
+	old, err := r2.ListKeys(ctx, r2.ProjectBlobPrefix(project))
+	if err != nil {
+		return rep, fmt.Errorf("prune: %w", err)
+	}
+	sort.Strings(old)
+	rep.OldKeys = len(old)
+	for _, k := range old {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		if referenced[k] {
+			rep.StillReferenced++
+			continue
+		}
+		to := r2.BuildKeyScheme(KeySchemeGlobalBlobs, project, path.Base(k))
+		ok, err := r2.Exists(ctx, to)
+		if err != nil {
+			return rep, fmt.Errorf("prune: %w", err)
+		}
+		if !ok {
+			rep.Unconfirmed = append(rep.Unconfirmed, k)
+			continue
+		}
+		if !dryRun {
+			if err := r2.Delete(ctx, k); err != nil {
+				return rep, fmt.Errorf("prune: %w", err)
+			}
+		}
+		rep.Deleted = append(rep.Deleted, k)
+	}
+	if !dryRun && len(rep.Deleted) > 0 {
+		_ = meta.RecordAudit(ctx, project, remote.AuditPruneKeys, fmt.Sprintf("%d old key(s)", len(rep.Deleted)))
+	}
+	return rep, nil
+}
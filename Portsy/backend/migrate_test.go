@@ -0,0 +1,109 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// memManifests is a remote.ManifestBlobs over a map, counting uploads.
+type memManifests struct {
+	blobs map[string][]byte
+	puts  int
+}
+
+func (b *memManifests) PutManifest(_ context.Context, key string, data []byte) error {
+	if b.blobs == nil {
+		b.blobs = map[string][]byte{}
+	}
+	b.blobs[key] = append([]byte(nil), data...)
+	b.puts++
+	return nil
+}
+
+func (b *memManifests) GetManifest(_ context.Context, key string) ([]byte, error) {
+	data, ok := b.blobs[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return data, nil
+}
+
+// TestEmulatorMigrateStatesToR2 moves two inline states to R2 manifests in
+// a fake blob store, reads them back, re-runs the migration and pushes once
+// more. Needs the Firestore emulator, like the remote package's tests:
+//
+//	FIRESTORE_EMULATOR_HOST=localhost:8080 go test ./backend/ -run Emulator
+func TestEmulatorMigrateStatesToR2(t *testing.T) {
+	host := os.Getenv(remote.EmulatorHostEnv)
+	if host == "" {
+		t.Skipf("%s not set; skipping Firestore emulator test", remote.EmulatorHostEnv)
+	}
+	t.Setenv(remote.EmulatorHostEnv, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	meta, err := remote.NewMetaStoreForEmulator(ctx, "portsy-test", host)
+	if err != nil {
+		t.Fatalf("NewMetaStoreForEmulator: %v", err)
+	}
+	defer meta.Close()
+	project := fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+
+	states := map[string]ProjectState{
+		"c1": {ProjectName: project, Files: []FileEntry{{Path: "Set.als", Hash: "aa", Size: 10}}},
+		"c2": {ProjectName: project, Files: []FileEntry{{Path: "Set.als", Hash: "ab", Size: 11}, {Path: "kick.wav", Hash: "bb", Size: 20}}},
+	}
+	for i, id := range []string{"c1", "c2"} {
+		if err := meta.UpsertLatestState(ctx, project, states[id], CommitMeta{ID: id, Timestamp: int64(100 + i)}); err != nil {
+			t.Fatalf("UpsertLatestState(%s): %v", id, err)
+		}
+	}
+
+	blobs := &memManifests{}
+	rep, err := MigrateStatesToR2(ctx, meta, blobs, project)
+	if err != nil {
+		t.Fatalf("MigrateStatesToR2: %v", err)
+	}
+	if rep.States != 2 || rep.StatesMigrated != 2 || rep.StatesSkipped != 0 {
+		t.Errorf("report = %+v, want 2 states migrated", rep)
+	}
+	for id, want := range states {
+		key := remote.ManifestKey(project, id)
+		if len(blobs.blobs[key]) == 0 {
+			t.Errorf("no manifest uploaded at %s", key)
+		}
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			t.Fatalf("GetState(%s): %v", id, err)
+		}
+		if st.FilesStorage != remote.StateStorageR2 || st.ManifestKey != key {
+			t.Errorf("%s stored %q at %q, want %q at %q", id, st.FilesStorage, st.ManifestKey, remote.StateStorageR2, key)
+		}
+		if !reflect.DeepEqual(st.Files, want.Files) {
+			t.Errorf("%s Files = %+v, want %+v", id, st.Files, want.Files)
+		}
+	}
+
+	puts := blobs.puts
+	rep, err = MigrateStatesToR2(ctx, meta, blobs, project)
+	if err != nil {
+		t.Fatalf("second MigrateStatesToR2: %v", err)
+	}
+	if rep.StatesMigrated != 0 || rep.StatesSkipped != 2 || blobs.puts != puts {
+		t.Errorf("re-run = %+v with %d new uploads, want everything skipped", rep, blobs.puts-puts)
+	}
+
+	// The store now writes new commits to R2 too.
+	next := ProjectState{ProjectName: project, Files: []FileEntry{{Path: "Set.als", Hash: "ac", Size: 12}}}
+	if err := meta.UpsertLatestState(ctx, project, next, CommitMeta{ID: "c3", Timestamp: 300}); err != nil {
+		t.Fatalf("UpsertLatestState(c3): %v", err)
+	}
+	if _, ok := blobs.blobs[remote.ManifestKey(project, "c3")]; !ok {
+		t.Errorf("commit after the migration didn't write an R2 manifest")
+	}
+}
@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// renameFile is moveFile's first attempt; tests swap it to fake EXDEV.
+var renameFile = os.Rename
+
+// moveFile renames src to dst, falling back to copy + fsync + remove when
+// they are on different volumes (EXDEV, or ERROR_NOT_SAME_DEVICE on
+// Windows). The copy goes through dst+".part" and a same-directory rename,
+// so dst is never seen half-written.
+func moveFile(src, dst string) error {
+	err := renameFile(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	part := dst + ".part"
+	out, err := os.OpenFile(part, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("move %s: %w", filepath.Base(src), err)
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(part, dst)
+	}
+	if err != nil {
+		_ = os.Remove(part)
+		return fmt.Errorf("move %s: %w", filepath.Base(src), err)
+	}
+	_ = in.Close()
+	return os.Remove(src)
+}
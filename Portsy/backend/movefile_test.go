@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveFile(t *testing.T) {
+	errDenied := errors.New("access denied")
+	tests := []struct {
+		name      string
+		renameErr error // first rename fails with this; nil uses os.Rename
+		wantErr   error
+		wantMoved bool
+	}{
+		{name: "same volume", wantMoved: true},
+		{name: "cross device falls back to copy", renameErr: errCrossDevice, wantMoved: true},
+		{name: "other rename error", renameErr: errDenied, wantErr: errDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.renameErr != nil {
+				renameFile = func(oldpath, newpath string) error {
+					return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: tt.renameErr}
+				}
+				t.Cleanup(func() { renameFile = os.Rename })
+			}
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "tmp", "blob.part"), filepath.Join(dir, "out", "kick.wav")
+			writeTestFile(t, src, "kick")
+			writeTestFile(t, dst, "old kick") // replaced, like os.Rename
+
+			err := moveFile(src, dst)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("moveFile = %v, want %v", err, tt.wantErr)
+			}
+			got, _ := os.ReadFile(dst)
+			_, srcErr := os.Stat(src)
+			if tt.wantMoved {
+				if string(got) != "kick" {
+					t.Errorf("dst = %q, want kick", got)
+				}
+				if !errors.Is(srcErr, os.ErrNotExist) {
+					t.Errorf("src still there (stat err %v)", srcErr)
+				}
+			} else {
+				if string(got) != "old kick" {
+					t.Errorf("dst = %q, want it untouched", got)
+				}
+				if srcErr != nil {
+					t.Errorf("src gone after a failed move: %v", srcErr)
+				}
+			}
+			if _, err := os.Stat(dst + ".part"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s.part left behind (stat err %v)", dst, err)
+			}
+		})
+	}
+}
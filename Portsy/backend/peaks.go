@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"Portsy/backend/internal/audio"
+	remote "Portsy/backend/remote"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+)
+
+// DefaultPeaksSamplesPerPixel gives about ten peaks a second at 44.1 kHz:
+// a few KB for a typical sample, enough to draw a waveform overview.
+const DefaultPeaksSamplesPerPixel = 4096
+
+// PeakData is a file's stored waveform overview (see GeneratePeaks).
+type PeakData = audio.PeakData
+
+// GeneratePeaks decodes a WAV/AIFF file into min,max pairs, one per
+// samplesPerPixel frames (<= 0 = DefaultPeaksSamplesPerPixel), mixed across
+// channels and scaled to -1..1.
+func GeneratePeaks(localPath string, samplesPerPixel int) ([]float32, error) {
+	d, err := generatePeakData(localPath, samplesPerPixel)
+	if err != nil {
+		return nil, err
+	}
+	return d.Peaks, nil
+}
+
+func generatePeakData(localPath string, samplesPerPixel int) (*PeakData, error) {
+	if samplesPerPixel <= 0 {
+		samplesPerPixel = DefaultPeaksSamplesPerPixel
+	}
+	return audio.Peaks(longPath(localPath), samplesPerPixel)
+}
+
+// PeaksKey is where an audio blob's peaks are kept, relative to KeyPrefix
+// like ManifestKey. Keyed by content hash, so copies and renames share them.
+func PeaksKey(projectName, hash string) string {
+	return path.Join(projectName, "peaks", hash+".json")
+}
+
+// uploadPeaks stores peaks for the given freshly uploaded files that are
+// WAV/AIFF and don't have them yet. Best effort: a file that can't be
+// decoded or stored is logged and skipped, never failing the push.
+func uploadPeaks(ctx context.Context, r2 *R2Client, project AbletonProject, files []FileEntry) {
+	done := 0
+	for _, f := range files {
+		if ctx.Err() != nil {
+			return
+		}
+		if !audio.Supported(f.Path) {
+			continue
+		}
+		key := PeaksKey(project.Name, f.Hash)
+		if ok, err := r2.Exists(ctx, r2.prefixed(key)); err == nil && ok {
+			continue
+		}
+		d, err := generatePeakData(filepath.Join(project.Path, filepath.FromSlash(f.Path)), 0)
+		if err != nil {
+			debugf("push %s: %s: peaks: %v", project.Name, f.Path, err)
+			continue
+		}
+		b, err := json.Marshal(d)
+		if err != nil {
+			continue
+		}
+		if _, err := r2.uploadReaderSized(ctx, bytes.NewReader(b), int64(len(b)), r2.prefixed(key), WithContentType("application/json")); err != nil {
+			debugf("push %s: %s: peaks upload: %v", project.Name, f.Path, err)
+			continue
+		}
+		done++
+	}
+	if done > 0 {
+		debugf("push %s: stored peaks for %d audio file(s)", project.Name, done)
+	}
+}
+
+// FilePeaks reads the stored peaks of relPath in the project's commitID
+// ("" = HEAD), for drawing a waveform without downloading the audio.
+func FilePeaks(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project, commitID, relPath string) (*PeakData, error) {
+	st, err := stateAt(ctx, meta, project, commitID)
+	if err != nil {
+		return nil, fmt.Errorf("peaks: %w", err)
+	}
+	want := normalizeKey(relPath)
+	for _, fe := range st.Files {
+		if normalizeKey(fe.Path) != want {
+			continue
+		}
+		rc, err := r2.OpenReader(ctx, r2.prefixed(PeaksKey(project, fe.Hash)))
+		if err != nil {
+			return nil, fmt.Errorf("peaks: %s: %w", relPath, err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("peaks: %s: %w", relPath, err)
+		}
+		var d PeakData
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, fmt.Errorf("peaks: %s: %w", relPath, err)
+		}
+		return &d, nil
+	}
+	return nil, fmt.Errorf("peaks: %s is not in %s", relPath, project)
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// previewTypes covers the audio formats a project usually holds, since the
+// OS MIME tables mime.TypeByExtension falls back to often lack them.
+var previewTypes = map[string]string{
+	".wav":  "audio/wav",
+	".aif":  "audio/aiff",
+	".aiff": "audio/aiff",
+	".flac": "audio/flac",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".m4a":  "audio/mp4",
+}
+
+// PreviewContentType is the MIME type a file is served as for preview when
+// its entry has no recorded ContentType (commits pushed before it was
+// recorded): it comes from the file's extension.
+func PreviewContentType(relPath string) string {
+	if t := extContentType(relPath); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func extContentType(relPath string) string {
+	ext := strings.ToLower(path.Ext(relPath))
+	if t, ok := previewTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// DetectContentType is the type push records on a file's entry: by
+// extension where that's known, otherwise sniffed from the first 512 bytes
+// of the file at localPath.
+func DetectContentType(localPath, relPath string) string {
+	if t := extContentType(relPath); t != "" {
+		return t
+	}
+	f, err := os.Open(longPath(localPath))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	var head [512]byte
+	n, _ := io.ReadFull(f, head[:])
+	return http.DetectContentType(head[:n])
+}
+
+// previewType is the type fe is served as: the one recorded at push, else
+// the extension's.
+func previewType(fe FileEntry) string {
+	if fe.ContentType != "" {
+		return fe.ContentType
+	}
+	return PreviewContentType(fe.Path)
+}
+
+// PreviewURL returns a presigned URL streaming relPath as stored in the
+// project's commitID ("" = HEAD), for listening to a remote sample without
+// pulling the project.
+func PreviewURL(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project, commitID, relPath string) (string, error) {
+	st, err := stateAt(ctx, meta, project, commitID)
+	if err != nil {
+		return "", fmt.Errorf("preview: %w", err)
+	}
+	want := normalizeKey(relPath)
+	for _, fe := range st.Files {
+		if normalizeKey(fe.Path) == want {
+			return r2.PresignGetForPreview(ctx, r2.KeyFor(st, project, fe), previewType(fe))
+		}
+	}
+	return "", fmt.Errorf("preview: %s is not in %s", relPath, project)
+}
@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		rel  string
+		body string
+		want string
+	}{
+		{rel: "Samples/kick.wav", body: "RIFF....WAVE", want: "audio/wav"},
+		{rel: "Samples/KICK.AIF", body: "FORM....AIFF", want: "audio/aiff"},
+		{rel: "Bounces/mix.flac", body: "fLaC", want: "audio/flac"},
+		{rel: "Samples/kick", body: "RIFF\x24\x00\x00\x00WAVEfmt ", want: "audio/wave"}, // sniffed
+		{rel: "Set.als", body: "\x1f\x8b\x08\x00", want: "application/x-gzip"},
+		{rel: "notes", body: "take 3 is the keeper", want: "text/plain; charset=utf-8"},
+		{rel: "blob.bin2", body: "\x00\x01\x02", want: "application/octet-stream"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			p := filepath.Join(dir, filepath.FromSlash(tt.rel))
+			writeTestFile(t, p, tt.body)
+			if got := DetectContentType(p, tt.rel); got != tt.want {
+				t.Errorf("DetectContentType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := DetectContentType(filepath.Join(dir, "missing"), "missing"); got != "application/octet-stream" {
+		t.Errorf("DetectContentType(missing) = %q, want application/octet-stream", got)
+	}
+}
+
+func TestPreviewType(t *testing.T) {
+	tests := []struct {
+		name string
+		fe   FileEntry
+		want string
+	}{
+		{name: "recorded", fe: FileEntry{Path: "Samples/kick", ContentType: "audio/wave"}, want: "audio/wave"},
+		{name: "recorded wins over extension", fe: FileEntry{Path: "Samples/kick.wav", ContentType: "audio/x-wav"}, want: "audio/x-wav"},
+		{name: "older commit, known extension", fe: FileEntry{Path: "Samples/kick.wav"}, want: "audio/wav"},
+		{name: "older commit, unknown extension", fe: FileEntry{Path: "Samples/kick"}, want: "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previewType(tt.fe); got != tt.want {
+				t.Errorf("previewType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
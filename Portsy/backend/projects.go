@@ -109,11 +109,20 @@ func ScanProjectsCtx(ctx context.Context, rootPath string) ([]AbletonProject, er
 			return p
 		}
 
+		// Last synced commit, from the local cache's sync record (ID only).
+		var last *CommitMeta
+		if hasPortsy {
+			if lc, err := LoadLocalCache(projectPath); err == nil && lc.LastSyncedCommitID != "" {
+				last = &CommitMeta{ID: lc.LastSyncedCommitID, ShortID: ShortCommitID(lc.LastSyncedCommitID)}
+			}
+		}
+
 		projects = append(projects, AbletonProject{
-			Name:      projectName,
-			Path:      norm(projectPath),
-			AlsFile:   norm(alsPath),
-			HasPortsy: hasPortsy,
+			Name:       projectName,
+			Path:       norm(projectPath),
+			AlsFile:    norm(alsPath),
+			HasPortsy:  hasPortsy,
+			LastCommit: last,
 		})
 	}
 
@@ -0,0 +1,85 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectSettings are per-project overrides kept in .portsy/settings.json.
+type ProjectSettings struct {
+	// TempDir stages downloads and collected samples for this project
+	// instead of R2Config.TempDir / TempDirEnv (e.g. when the project's
+	// drive is nearly full). "" = no override.
+	TempDir string `json:"tempDir,omitempty"`
+
+	// RelocatedFrom is where the project lived before RelocateRoot moved its
+	// library; the next push records the new path remotely and clears it.
+	RelocatedFrom string `json:"relocatedFrom,omitempty"`
+
+	// TrackOnlyExtensions, if set, limits tracking to files with these
+	// extensions (".wav" or "wav", any case); top-level and nested .als
+	// files are always tracked. Empty tracks everything.
+	TrackOnlyExtensions []string `json:"trackOnlyExtensions,omitempty"`
+}
+
+// trackFilter returns whether a rel path is tracked under
+// TrackOnlyExtensions, or nil when every file is.
+func (s ProjectSettings) trackFilter() func(rel string) bool {
+	if len(s.TrackOnlyExtensions) == 0 {
+		return nil
+	}
+	exts := map[string]bool{".als": true}
+	for _, e := range s.TrackOnlyExtensions {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			exts["."+strings.TrimPrefix(e, ".")] = true
+		}
+	}
+	return func(rel string) bool { return exts[strings.ToLower(path.Ext(rel))] }
+}
+
+// TempDirEnv is the scratch folder for downloads and sample collection when
+// neither R2Config.TempDir nor the project's settings name one.
+const TempDirEnv = "PORTSY_TEMP_DIR"
+
+func projectSettingsFile(projectPath string) string {
+	return filepath.Join(projectPath, ".portsy", "settings.json")
+}
+
+// LoadProjectSettings reads the project's settings; a missing file is the
+// zero value.
+func LoadProjectSettings(projectPath string) (ProjectSettings, error) {
+	var s ProjectSettings
+	b, err := os.ReadFile(projectSettingsFile(projectPath))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	return s, err
+}
+
+// SaveProjectSettings writes the project's settings.
+func SaveProjectSettings(projectPath string, s ProjectSettings) error {
+	b, _ := json.MarshalIndent(s, "", "  ")
+	return writeFileAtomic(projectSettingsFile(projectPath), func(f *os.File) error {
+		_, err := f.Write(b)
+		return err
+	})
+}
+
+// projectTempDir picks the scratch folder for projectPath: its settings,
+// then fallback, then TempDirEnv; "" stages next to each destination.
+func projectTempDir(projectPath, fallback string) string {
+	if s, err := LoadProjectSettings(projectPath); err == nil && s.TempDir != "" {
+		return s.TempDir
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return os.Getenv(TempDirEnv)
+}
@@ -0,0 +1,90 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+)
+
+// PullAll outcomes (PullAllResult.Status / PullAllProgress.Status).
+const (
+	PullAllPulling  = "pulling"
+	PullAllPulled   = "pulled"
+	PullAllUpToDate = "up-to-date"
+	PullAllSkipped  = "skipped"
+	PullAllFailed   = "failed"
+)
+
+// PullAllProgress is one per-project event from PullAll.
+type PullAllProgress struct {
+	Project string `json:"project"`
+	Index   int    `json:"index"` // 1-based
+	Total   int    `json:"total"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PullAllResult is the outcome for one local project.
+type PullAllResult struct {
+	Project string     `json:"project"`
+	Path    string     `json:"path"`
+	Status  string     `json:"status"`
+	Reason  string     `json:"reason,omitempty"`
+	Stats   *PullStats `json:"stats,omitempty"`
+}
+
+// PullAll brings every local project under root up to its remote HEAD.
+// The decision per project comes from RootStatus/ComputePullStatus:
+//   - remote not ahead            -> up-to-date
+//   - remote ahead, local changes -> skipped (never clobber unpushed work)
+//   - remote ahead, local clean   -> pulled, cache rewritten
+//
+// Projects that only exist remotely are not touched (see PullInto).
+// A failing project doesn't stop the rest; the error is for scan failures
+// or cancellation.
+func PullAll(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, root string, opts PullOptions, onProgress func(PullAllProgress)) ([]PullAllResult, error) {
+	emit := func(p PullAllProgress) {
+		if onProgress != nil {
+			onProgress(p)
+		}
+	}
+
+	rows, err := RootStatus(ctx, root, RemoteHead(meta))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]PullAllResult, 0, len(rows))
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		res := PullAllResult{Project: row.Name, Path: row.Path}
+		switch {
+		case row.Error != "":
+			res.Status, res.Reason = PullAllFailed, row.Error
+		case !row.RemoteNewer:
+			res.Status = PullAllUpToDate
+		case row.LocalNewer:
+			res.Status, res.Reason = PullAllSkipped, "local changes"
+		default:
+			emit(PullAllProgress{Project: row.Name, Index: i + 1, Total: len(rows), Status: PullAllPulling})
+			stats, err := PullProjectWithOptions(ctx, meta, r2, row.Remote, row.Path, "", opts)
+			res.Stats = stats
+			if err != nil {
+				res.Status, res.Reason = PullAllFailed, err.Error()
+				break
+			}
+			res.Status = PullAllPulled
+			if ps, err := BuildManifest(row.Path); err == nil {
+				algo := ps.Algo
+				if algo == "" {
+					algo = "sha256"
+				}
+				_ = WriteCacheFromState(row.Path, ps, algo)
+			}
+		}
+		out = append(out, res)
+		emit(PullAllProgress{Project: row.Name, Index: i + 1, Total: len(rows), Status: res.Status, Reason: res.Reason})
+	}
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// pullCheckpoint is the pull journal, .portsy/pull-journal.json: the files an
+// interrupted pull already downloaded and verified (rel path -> hash, plus the
+// size/mtime that let a resume skip rehashing them). Target pins it to one
+// manifest so a pull of a different commit ignores it. Older builds wrote the
+// same JSON to .portsy/pull-progress.json; it is still read, and dropped once
+// the journal replaces it.
+type pullCheckpoint struct {
+	Target string                     `json:"target"` // manifest hash of the state being pulled
+	Files  map[string]checkpointEntry `json:"files"`
+}
+
+type checkpointEntry struct {
+	Hash  string `json:"hash"`
+	Size  int64  `json:"size"`
+	Mtime int64  `json:"mtime"` // unix nano, as left on disk after the pull
+}
+
+func pullCheckpointFile(projectPath string) string {
+	return filepath.Join(projectPath, ".portsy", "pull-journal.json")
+}
+
+func legacyPullCheckpointFile(projectPath string) string {
+	return filepath.Join(projectPath, ".portsy", "pull-progress.json")
+}
+
+// loadPullJournal is loadPullCheckpoint on the project's own journal, falling
+// back to a pull-progress.json left by an older build.
+func loadPullJournal(projectPath, target string) map[string]checkpointEntry {
+	file := pullCheckpointFile(projectPath)
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		file = legacyPullCheckpointFile(projectPath)
+	}
+	return loadPullCheckpoint(file, target)
+}
+
+// loadPullCheckpoint returns the completed files recorded for target in
+// file (usually pullCheckpointFile), or an empty set when there is no
+// checkpoint or it belongs to another pull.
+func loadPullCheckpoint(file, target string) map[string]checkpointEntry {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return map[string]checkpointEntry{}
+	}
+	var cp pullCheckpoint
+	if json.Unmarshal(b, &cp) != nil || cp.Target != target || cp.Files == nil {
+		return map[string]checkpointEntry{}
+	}
+	return cp.Files
+}
+
+func savePullCheckpoint(projectPath, target string, files map[string]checkpointEntry) error {
+	b, err := json.Marshal(pullCheckpoint{Target: target, Files: files})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(pullCheckpointFile(projectPath), func(f *os.File) error {
+		_, err := f.Write(b)
+		return err
+	}); err != nil {
+		return err
+	}
+	_ = os.Remove(legacyPullCheckpointFile(projectPath))
+	return nil
+}
+
+func clearPullCheckpoint(projectPath string) {
+	_ = os.Remove(pullCheckpointFile(projectPath))
+	_ = os.Remove(legacyPullCheckpointFile(projectPath))
+}
+
+// stillComplete: the file a checkpoint entry describes is untouched on disk.
+func (e checkpointEntry) stillComplete(rf FileEntry, fi os.FileInfo) bool {
+	return e.Hash == rf.Hash && fi.Size() == e.Size && fi.ModTime().UnixNano() == e.Mtime
+}
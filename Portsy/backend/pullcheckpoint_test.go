@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPullJournal(t *testing.T) {
+	entry := `{"target":"m1","files":{"Set.als":{"hash":"aa","size":3,"mtime":7}}}`
+	tests := []struct {
+		name    string
+		journal string // .portsy/pull-journal.json, "" for none
+		legacy  string // .portsy/pull-progress.json, "" for none
+		target  string
+		want    int
+	}{
+		{name: "none", target: "m1"},
+		{name: "journal", journal: entry, target: "m1", want: 1},
+		{name: "legacy progress file", legacy: entry, target: "m1", want: 1},
+		{name: "journal wins over legacy", journal: `{"target":"m1","files":{}}`, legacy: entry, target: "m1"},
+		{name: "other target", journal: entry, target: "m2"},
+		{name: "corrupt", journal: "{", target: "m1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.journal != "" {
+				writeTestFile(t, pullCheckpointFile(dir), tt.journal)
+			}
+			if tt.legacy != "" {
+				writeTestFile(t, legacyPullCheckpointFile(dir), tt.legacy)
+			}
+			got := loadPullJournal(dir, tt.target)
+			if len(got) != tt.want {
+				t.Fatalf("loaded %v, want %d entries", got, tt.want)
+			}
+			if tt.want > 0 && got["Set.als"].Hash != "aa" {
+				t.Errorf("Set.als = %+v, want hash aa", got["Set.als"])
+			}
+		})
+	}
+}
+
+func TestSavePullCheckpointReplacesLegacy(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, legacyPullCheckpointFile(dir), `{"target":"m1","files":{}}`)
+
+	files := map[string]checkpointEntry{"Set.als": {Hash: "aa", Size: 3, Mtime: 7}}
+	if err := savePullCheckpoint(dir, "m1", files); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacyPullCheckpointFile(dir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("pull-progress.json kept after save (stat err %v)", err)
+	}
+	if got := loadPullJournal(dir, "m1"); got["Set.als"] != files["Set.als"] {
+		t.Errorf("journal = %v, want %v", got, files)
+	}
+	if filepath.Base(pullCheckpointFile(dir)) != "pull-journal.json" {
+		t.Errorf("journal written to %s", pullCheckpointFile(dir))
+	}
+
+	clearPullCheckpoint(dir)
+	if got := loadPullJournal(dir, "m1"); len(got) != 0 {
+		t.Errorf("journal after clear = %v, want empty", got)
+	}
+}
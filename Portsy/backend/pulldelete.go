@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultDeleteWorkers is PullOptions.DeleteWorkers' default.
+const defaultDeleteWorkers = 8
+
+// DeleteError is a local file a pull's delete pass couldn't remove
+// (typically locked by Live or another program).
+type DeleteError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// deleteStale is the pull's delete pass: it collects every file under
+// destPath (outside .portsy) that isn't in the target, removes them in path
+// order with opts.DeleteWorkers at a time, then removes the directories that
+// became empty. Top-level .als files go to the .als backups first; with
+// BackupChanged, files are moved aside by backup rather than removed.
+// Failures land in stats.DeleteErrors instead of stopping the pull. Files
+// outside the project's TrackOnlyExtensions aren't Portsy's and are kept.
+func deleteStale(projectName, destPath string, targetByPath map[string]FileEntry, opts PullOptions, backup func(rel, localPath string) error, stats *PullStats) {
+	var tracked func(string) bool
+	if s, err := LoadProjectSettings(destPath); err == nil {
+		tracked = s.trackFilter()
+	}
+	var stale []string
+	_ = filepath.Walk(destPath, func(p string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil || info.IsDir() {
+			if info != nil && info.IsDir() && info.Name() == ".portsy" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(destPath, p)
+		rel = filepath.ToSlash(rel)
+		if _, ok := targetByPath[rel]; !ok && (tracked == nil || tracked(rel)) {
+			stale = append(stale, rel)
+		}
+		return nil
+	})
+	if len(stale) == 0 {
+		return
+	}
+	sort.Strings(stale)
+
+	workers := opts.DeleteWorkers
+	if workers <= 0 {
+		workers = defaultDeleteWorkers
+	}
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		removed []string
+	)
+	fail := func(rel string, err error) {
+		mu.Lock()
+		stats.DeleteErrors = append(stats.DeleteErrors, DeleteError{Path: rel, Error: err.Error()})
+		mu.Unlock()
+	}
+	jobs := make(chan string)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for rel := range jobs {
+				p := filepath.Join(destPath, filepath.FromSlash(rel))
+				debugf("pull %s: %s: delete (not in target)", projectName, rel)
+				if isTopLevelALS(rel) {
+					if err := backupALS(destPath, rel, opts.ALSBackupKeep); err != nil {
+						fail(rel, err) // keep the set rather than lose it
+						continue
+					}
+				}
+				var err error
+				if opts.BackupChanged {
+					err = backup(rel, p)
+				} else {
+					err = os.Remove(longPath(p))
+				}
+				if err != nil {
+					fail(rel, err)
+					continue
+				}
+				mu.Lock()
+				stats.Deleted++
+				if opts.BackupChanged {
+					stats.BackedUp++
+				}
+				removed = append(removed, rel)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, rel := range stale {
+		jobs <- rel
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(stats.DeleteErrors, func(i, j int) bool { return stats.DeleteErrors[i].Path < stats.DeleteErrors[j].Path })
+	for _, de := range stats.DeleteErrors {
+		log.Printf("pull %s: could not delete %s: %s", projectName, de.Path, de.Error)
+	}
+	removeEmptyParents(destPath, removed)
+}
+
+// removeEmptyParents removes the directories holding rels (and their
+// parents, up to but not including root) that are now empty, deepest first.
+func removeEmptyParents(root string, rels []string) {
+	dirs := map[string]bool{}
+	for _, rel := range rels {
+		for d := filepath.Dir(filepath.FromSlash(rel)); d != "." && d != string(filepath.Separator); d = filepath.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	ordered := make([]string, 0, len(dirs))
+	for d := range dirs {
+		ordered = append(ordered, d)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		di, dj := strings.Count(ordered[i], string(filepath.Separator)), strings.Count(ordered[j], string(filepath.Separator))
+		if di != dj {
+			return di > dj
+		}
+		return ordered[i] < ordered[j]
+	})
+	for _, d := range ordered {
+		_ = os.Remove(longPath(filepath.Join(root, d))) // fails, harmlessly, unless empty
+	}
+}
+
+// localDirs lists the directories under root, relative, outside .portsy;
+// with onlyEmpty, just those without entries.
+func localDirs(root string, onlyEmpty bool) []string {
+	var out []string
+	_ = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || p == root {
+			return nil
+		}
+		if d.Name() == ".portsy" {
+			return filepath.SkipDir
+		}
+		if onlyEmpty {
+			if ents, err := os.ReadDir(p); err != nil || len(ents) > 0 {
+				return nil
+			}
+		}
+		rel, _ := filepath.Rel(root, p)
+		out = append(out, rel)
+		return nil
+	})
+	return out
+}
+
+// pruneEmptyDirs removes the directories under root that are empty now but
+// weren't before the pull (keepEmpty), deepest first so parents that empty
+// out go too. Directories holding anything, ignored files included, stay.
+func pruneEmptyDirs(root string, keepEmpty []string) int {
+	keep := make(map[string]bool, len(keepEmpty))
+	for _, d := range keepEmpty {
+		keep[d] = true
+	}
+	dirs := localDirs(root, false)
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := strings.Count(dirs[i], string(filepath.Separator)), strings.Count(dirs[j], string(filepath.Separator))
+		if di != dj {
+			return di > dj
+		}
+		return dirs[i] < dirs[j]
+	})
+	n := 0
+	for _, d := range dirs {
+		if keep[d] {
+			continue
+		}
+		if os.Remove(longPath(filepath.Join(root, d))) == nil {
+			debugf("pull: removed empty folder %s", filepath.ToSlash(d))
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,239 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// remoteMarker records which remote project a local folder was pulled from,
+// so a later pull into the same root can tell "ours" from a name collision.
+// It's stored as .portsy/id, the remote project's name on one line.
+const remoteMarkerName = "id"
+
+// legacyRemoteMarkerName is the JSON marker ({"project": ...}) bindings
+// used before .portsy/id. It's still read, and removed on the next write.
+const legacyRemoteMarkerName = "remote.json"
+
+type remoteMarker struct {
+	Project string `json:"project"`
+}
+
+// PullInto pulls project under rootDir, into the folder ProjectDirFor picks,
+// and refreshes the local cache. Returns the folder it used.
+func PullInto(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project, rootDir, commitID string, opts PullOptions) (string, *PullStats, error) {
+	dest, err := ProjectDirFor(rootDir, project)
+	if err != nil {
+		return "", nil, err
+	}
+	stats, err := PullIntoDir(ctx, meta, r2, project, dest, commitID, opts)
+	return dest, stats, err
+}
+
+// PullIntoDir pulls project into dest whatever the folder is called, and
+// binds the folder to project (the remote marker RemoteProjectFor reads), so
+// pushes from it go back to project rather than to a remote named after the
+// folder. A folder already bound to a different project is refused; use
+// RebindProject to move it. With opts.ReadOnly it's a plain pull: no binding
+// or cache is written.
+func PullIntoDir(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project, dest, commitID string, opts PullOptions) (*PullStats, error) {
+	if m, err := readRemoteMarker(dest); err == nil && m.Project != "" && m.Project != project {
+		return nil, fmt.Errorf("%s is bound to remote project %q, not %q (rebind it first)", dest, m.Project, project)
+	}
+	stats, err := PullProjectWithOptions(ctx, meta, r2, project, dest, commitID, opts)
+	if err != nil || opts.ReadOnly {
+		return stats, err
+	}
+	if err := writeRemoteMarker(dest, project); err != nil {
+		return stats, err
+	}
+	if stats.Partial {
+		return stats, nil // the cache describes whole commits only
+	}
+	if ps, err := BuildManifest(dest); err == nil {
+		algo := ps.Algo
+		if algo == "" {
+			algo = "sha256"
+		}
+		_ = WriteCacheFromState(dest, ps, algo)
+	}
+	return stats, nil
+}
+
+// ProjectDirFor resolves the local folder for a remote project under rootDir:
+// rootDir/<project>, with characters Windows can't store in a folder name
+// replaced. If that folder already holds something else (another project, or
+// unrelated files), "<name> (2)", "<name> (3)", ... are tried instead.
+func ProjectDirFor(rootDir, project string) (string, error) {
+	if strings.TrimSpace(project) == "" {
+		return "", fmt.Errorf("empty project name")
+	}
+	if rootDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		rootDir = cwd
+	}
+	base := SanitizeFolderName(project)
+	for n := 1; n < 100; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s (%d)", base, n)
+		}
+		dir := filepath.Join(rootDir, name)
+		if dirAvailableFor(dir, name, project) {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no free folder for %q under %s", project, rootDir)
+}
+
+// SanitizeFolderName maps a project name to a portable folder name.
+func SanitizeFolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '<', '>', ':', '"', '/', '\\', '|', '?', '*':
+			return '_'
+		}
+		if r < 0x20 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	// Windows silently drops trailing dots/spaces.
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		name = "_"
+	}
+	return name
+}
+
+// dirAvailableFor: missing, empty, or already a pull of the same project.
+func dirAvailableFor(dir, folderName, project string) bool {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	if len(entries) == 0 {
+		return true
+	}
+	if m, err := readRemoteMarker(dir); err == nil {
+		return m.Project == project
+	}
+	// Pulled before markers existed: trust an exact folder-name match on a
+	// Portsy-tracked folder.
+	if fi, err := os.Stat(filepath.Join(dir, ".portsy")); err == nil && fi.IsDir() {
+		return folderName == project
+	}
+	return false
+}
+
+// RemoteProjectFor returns the remote project a local folder is bound to
+// (by a pull or RebindProject), or folderName when it has no binding.
+func RemoteProjectFor(projectPath, folderName string) string {
+	if m, err := readRemoteMarker(projectPath); err == nil && m.Project != "" {
+		return m.Project
+	}
+	return folderName
+}
+
+// RebindProject points a local folder at another remote project, e.g. after
+// a renamed folder created a duplicate, or to fork. The remote must exist.
+// The binding is stored in the folder's .portsy/id, and the local cache
+// is reset to that remote's HEAD so diffs and the next push are against its
+// history (everything shows as added if it has no commits yet).
+func RebindProject(ctx context.Context, meta *remote.MetaStore, projectPath, remoteProject string) error {
+	remoteProject = strings.TrimSpace(remoteProject)
+	if remoteProject == "" {
+		return fmt.Errorf("rebind: empty remote project")
+	}
+	if fi, err := os.Stat(projectPath); err != nil || !fi.IsDir() {
+		return fmt.Errorf("rebind: %s is not a folder", projectPath)
+	}
+	ok, err := meta.ProjectExists(ctx, remoteProject)
+	if err != nil {
+		return fmt.Errorf("rebind: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("rebind: remote project %q not found", remoteProject)
+	}
+	head, headCommit, err := meta.GetLatestState(ctx, remoteProject)
+	if err != nil {
+		return fmt.Errorf("rebind: read %q head: %w", remoteProject, err)
+	}
+
+	if err := writeRemoteMarker(projectPath, remoteProject); err != nil {
+		return fmt.Errorf("rebind: %w", err)
+	}
+	st := ProjectState{}
+	if head != nil {
+		st = hashesOnly(*head)
+	}
+	if err := WriteCacheFromState(projectPath, st, st.Algo); err != nil {
+		return fmt.Errorf("rebind: refresh cache: %w", err)
+	}
+	headID := ""
+	if headCommit != nil {
+		headID = headCommit.ID
+	}
+	if err := recordSyncedCommit(projectPath, headID, st); err != nil {
+		return fmt.Errorf("rebind: refresh cache: %w", err)
+	}
+	return nil
+}
+
+// hashesOnly drops the mtimes a remote state recorded on whichever machine
+// pushed it, so a cache written from it carries hashes without a stat and
+// BuildManifest reads every local file once instead of trusting a size and
+// mtime that merely happen to match.
+func hashesOnly(st ProjectState) ProjectState {
+	files := make([]FileEntry, len(st.Files))
+	for i, f := range st.Files {
+		f.Modified = 0
+		files[i] = f
+	}
+	st.Files = files
+	return st
+}
+
+func readRemoteMarker(projectPath string) (*remoteMarker, error) {
+	b, err := os.ReadFile(filepath.Join(projectPath, ".portsy", remoteMarkerName))
+	if err == nil {
+		return &remoteMarker{Project: strings.TrimSpace(string(b))}, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	b, err = os.ReadFile(filepath.Join(projectPath, ".portsy", legacyRemoteMarkerName))
+	if err != nil {
+		return nil, err
+	}
+	var m remoteMarker
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func writeRemoteMarker(projectPath, project string) error {
+	dir := filepath.Join(projectPath, ".portsy")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, remoteMarkerName), []byte(project+"\n"), 0o644); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, legacyRemoteMarkerName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
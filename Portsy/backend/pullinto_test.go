@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoteProjectFor(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string // under .portsy
+		want  string
+	}{
+		{name: "unbound", want: "Folder"},
+		{name: "id file", files: map[string]string{"id": "Remote A\n"}, want: "Remote A"},
+		{name: "legacy marker", files: map[string]string{"remote.json": `{"project":"Remote B"}`}, want: "Remote B"},
+		{
+			name:  "id file wins over legacy",
+			files: map[string]string{"id": "Remote A", "remote.json": `{"project":"Remote B"}`},
+			want:  "Remote A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, body := range tt.files {
+				writeTestFile(t, filepath.Join(dir, ".portsy", name), body)
+			}
+			if got := RemoteProjectFor(dir, "Folder"); got != tt.want {
+				t.Errorf("RemoteProjectFor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRemoteMarkerReplacesLegacy(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ".portsy", legacyRemoteMarkerName), `{"project":"Old"}`)
+
+	if err := writeRemoteMarker(dir, "New"); err != nil {
+		t.Fatal(err)
+	}
+	if got := RemoteProjectFor(dir, "Folder"); got != "New" {
+		t.Errorf("RemoteProjectFor = %q, want New", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".portsy", legacyRemoteMarkerName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("legacy marker still present (stat err %v)", err)
+	}
+}
+
+func TestRebindCacheForcesRehash(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "kick.wav")
+	writeTestFile(t, p, "local bytes")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+	want, _, _, err := HashFileSHA256(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another machine's state: same size and mtime, different content.
+	remoteState := ProjectState{Algo: "sha256", Files: []FileEntry{
+		{Path: "kick.wav", Hash: "remote-hash", Size: int64(len("local bytes")), Modified: old.Unix()},
+	}}
+
+	tests := []struct {
+		name  string
+		state ProjectState
+		want  string
+	}{
+		{name: "stat trusted", state: remoteState, want: "remote-hash"},
+		{name: "hashes only", state: hashesOnly(remoteState), want: want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := WriteCacheFromState(dir, tt.state, "sha256"); err != nil {
+				t.Fatal(err)
+			}
+			ps, err := BuildManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ps.Files) != 1 || ps.Files[0].Hash != tt.want {
+				t.Errorf("manifest = %+v, want hash %s", ps.Files, tt.want)
+			}
+		})
+	}
+}
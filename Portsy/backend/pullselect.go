@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// selectPullFiles keeps the files PullOptions.Paths asks for: each entry is
+// a rel path, or a folder meaning every file under it. An entry matching
+// nothing is an error, so a typo doesn't silently pull nothing.
+func selectPullFiles(files []FileEntry, paths []string) ([]FileEntry, error) {
+	want := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.Trim(normalizeKey(strings.TrimSpace(p)), "/")
+		if p != "" && p != "." {
+			want = append(want, path.Clean(p))
+		}
+	}
+	if len(want) == 0 {
+		return nil, fmt.Errorf("no paths selected")
+	}
+	matched := make([]bool, len(want))
+	var out []FileEntry
+	for _, f := range files {
+		key := normalizeKey(f.Path)
+		hit := false
+		for i, w := range want {
+			if key == w || strings.HasPrefix(key, w+"/") {
+				matched[i], hit = true, true
+			}
+		}
+		if hit {
+			out = append(out, f)
+		}
+	}
+	for i, w := range want {
+		if !matched[i] {
+			return nil, fmt.Errorf("%q is not in the commit", w)
+		}
+	}
+	return out, nil
+}
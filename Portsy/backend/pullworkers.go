@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+)
+
+// pullResult is one file's outcome from a pull worker.
+type pullResult struct {
+	rf         FileEntry
+	err        error
+	downloaded bool
+	backedUp   bool
+	fi         os.FileInfo // final on-disk state, for the checkpoint
+}
+
+// runPullWorkers hands files to workers running ensure and passes each result
+// to record on the calling goroutine, in completion order. Results failing
+// with context.Canceled are still recorded; any other error is fatal.
+//
+// On cancellation or the first fatal error it stops handing out files, calls
+// flush straight away, then waits for the files already in flight and records
+// those too, so no worker is left blocked and the checkpoint covers
+// everything that finished. It returns the first fatal error, else ctx.Err().
+func runPullWorkers(ctx context.Context, files []FileEntry, workers int, ensure func(context.Context, FileEntry) pullResult, record func(pullResult), flush func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan FileEntry)
+	dones := make(chan pullResult)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for rf := range jobs {
+				dones <- ensure(ctx, rf)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, rf := range files {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- rf:
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(dones)
+	}()
+
+	var fatal error
+	take := func(d pullResult) {
+		if d.err != nil && !errors.Is(d.err, context.Canceled) {
+			if fatal == nil {
+				fatal = d.err
+				cancel()
+			}
+			return
+		}
+		record(d)
+	}
+collect:
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			break collect
+		case d, ok := <-dones:
+			if !ok {
+				break collect
+			}
+			take(d)
+		}
+	}
+	for d := range dones {
+		take(d)
+	}
+	if fatal != nil {
+		return fatal
+	}
+	return ctx.Err()
+}
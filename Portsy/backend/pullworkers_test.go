@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRunPullWorkersCheckpointsOnStop interrupts a pull partway through and
+// checks every worker exits and the checkpoint lists exactly the files that
+// finished before the stop.
+func TestRunPullWorkersCheckpointsOnStop(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name    string
+		stopAt  int   // index of the file that stops the pull; -1 runs to the end
+		fatal   error // returned by that file; nil cancels the pull's context
+		wantErr error
+	}{
+		{name: "completes", stopAt: -1},
+		{name: "cancelled", stopAt: 7, wantErr: context.Canceled},
+		{name: "fatal error", stopAt: 7, fatal: errBoom, wantErr: errBoom},
+		{name: "cancelled on first file", stopAt: 0, wantErr: context.Canceled},
+	}
+	const n = 20
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := make([]FileEntry, n)
+			for i := range files {
+				files[i] = FileEntry{Path: fmt.Sprintf("Samples/%02d.wav", i), Hash: fmt.Sprintf("h%02d", i)}
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ensure := func(ctx context.Context, rf FileEntry) pullResult {
+				var i int
+				fmt.Sscanf(rf.Hash, "h%d", &i)
+				switch {
+				case tt.stopAt < 0 || i < tt.stopAt:
+					p := filepath.Join(dir, filepath.FromSlash(rf.Path))
+					writeTestFile(t, p, rf.Hash)
+					fi, err := os.Lstat(p)
+					return pullResult{rf: rf, downloaded: true, fi: fi, err: err}
+				case i == tt.stopAt && tt.fatal != nil:
+					return pullResult{rf: rf, err: tt.fatal}
+				case i == tt.stopAt:
+					cancel()
+				}
+				<-ctx.Done()
+				return pullResult{rf: rf, err: ctx.Err()}
+			}
+			completed := map[string]checkpointEntry{}
+			record := func(d pullResult) {
+				if d.err == nil && d.fi != nil {
+					completed[d.rf.Path] = checkpointEntry{Hash: d.rf.Hash, Size: d.fi.Size(), Mtime: d.fi.ModTime().UnixNano()}
+				}
+			}
+			flush := func() {
+				if err := savePullCheckpoint(dir, "target", completed); err != nil {
+					t.Error(err)
+				}
+			}
+
+			errc := make(chan error, 1)
+			go func() { errc <- runPullWorkers(ctx, files, 3, ensure, record, flush) }()
+			var err error
+			select {
+			case err = <-errc:
+			case <-time.After(10 * time.Second):
+				t.Fatal("runPullWorkers did not return; a worker is stuck")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				flush() // as PullProjectWithOptions does on error
+			}
+
+			want := n
+			if tt.stopAt >= 0 {
+				want = tt.stopAt
+			}
+			if len(completed) != want {
+				t.Errorf("recorded %d files, want %d", len(completed), want)
+			}
+			if tt.stopAt < 0 {
+				return
+			}
+			cp := loadPullCheckpoint(pullCheckpointFile(dir), "target")
+			if len(cp) != want {
+				t.Fatalf("checkpoint has %d files, want %d", len(cp), want)
+			}
+			for i := 0; i < want; i++ {
+				if _, ok := cp[files[i].Path]; !ok {
+					t.Errorf("checkpoint missing %s", files[i].Path)
+				}
+			}
+		})
+	}
+}
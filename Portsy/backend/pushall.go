@@ -0,0 +1,116 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PushAll progress states.
+const (
+	PushAllPushing = "pushing"
+	PushAllDone    = "done"
+	PushAllFailed  = "failed"
+)
+
+// PushAllProgress is one per-project event from PushAll.
+type PushAllProgress struct {
+	Project  string `json:"project"`
+	Index    int    `json:"index"` // 1-based
+	Total    int    `json:"total"`
+	Status   string `json:"status"`
+	CommitID string `json:"commitId,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PushAllResult is the outcome for one changed project.
+type PushAllResult struct {
+	ProjectChange
+	CommitID string `json:"commitId,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	// Verify is the post-push check, with PushOptions.VerifyAfter set.
+	Verify *VerifyReport `json:"verify,omitempty"`
+}
+
+// PushAll pushes every project under root that changed since its local cache,
+// one at a time (projects never share blobs, and sequential pushes keep R2
+// and Firestore load predictable). A failing project doesn't stop the rest;
+// the returned error is only for scan failures or cancellation.
+// msg may be empty ("push-all: <time>").
+func PushAll(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, root, msg string, opts PushOptions, onProgress func(PushAllProgress)) ([]PushAllResult, error) {
+	return PushAllSelected(ctx, meta, r2, root, msg, opts, nil, onProgress)
+}
+
+// PushAllSelected is PushAll with a per-project filter: include is asked once
+// per changed project, before anything is pushed, and projects it rejects are
+// left out of the run (and of Index/Total). A nil include pushes them all.
+func PushAllSelected(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, root, msg string, opts PushOptions, include func(ProjectChange) bool, onProgress func(PushAllProgress)) ([]PushAllResult, error) {
+	emit := func(p PushAllProgress) {
+		if onProgress != nil {
+			onProgress(p)
+		}
+	}
+	if msg == "" {
+		msg = "push-all: " + time.Now().Format(time.RFC3339)
+	}
+
+	changed, err := ChangedProjectsSinceCache(root)
+	if err != nil {
+		return nil, err
+	}
+	if include != nil {
+		picked := changed[:0]
+		for _, pc := range changed {
+			if include(pc) {
+				picked = append(picked, pc)
+			}
+		}
+		changed = picked
+	}
+
+	out := make([]PushAllResult, 0, len(changed))
+	for i, pc := range changed {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		ev := PushAllProgress{Project: pc.Name, Index: i + 1, Total: len(changed), Status: PushAllPushing}
+		emit(ev)
+
+		res := PushAllResult{ProjectChange: pc}
+		cm := CommitMeta{
+			ID:        uuid.NewString(),
+			Message:   msg,
+			Timestamp: time.Now().Unix(),
+		}
+		proj := AbletonProject{Name: RemoteProjectFor(pc.Path, pc.Name), Path: pc.Path}
+		popts := opts
+		if opts.VerifyAfter {
+			popts.OnVerified = func(rep *VerifyReport) {
+				res.Verify = rep
+				if opts.OnVerified != nil {
+					opts.OnVerified(rep)
+				}
+			}
+		}
+		if err := PushProjectWithOptions(ctx, meta, r2, proj, cm, popts); err != nil {
+			res.Error = err.Error()
+			ev.Status, ev.Error = PushAllFailed, res.Error
+		} else {
+			res.CommitID = cm.ID
+			ev.Status, ev.CommitID = PushAllDone, cm.ID
+			if ps, err := BuildManifest(pc.Path); err == nil {
+				algo := ps.Algo
+				if algo == "" {
+					algo = "sha256"
+				}
+				_ = WriteCacheFromState(pc.Path, ps, algo)
+			}
+		}
+		out = append(out, res)
+		emit(ev)
+	}
+	return out, nil
+}
@@ -0,0 +1,111 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReconcileReport compares what a project's states reference with what's
+// actually stored under its R2 blob prefix.
+type ReconcileReport struct {
+	Project  string `json:"project"`
+	CommitID string `json:"commitId"` // HEAD at the time of the check
+	Stored   int    `json:"stored"`   // keys under the project's blob prefix
+
+	// Orphans are stored keys no state of the project references (left over
+	// from interrupted pushes): candidates for garbage collection.
+	Orphans []string `json:"orphans"`
+
+	// Dangling are HEAD's files whose blob doesn't exist: they need a
+	// re-upload (PushOptions.ForceKeys via BadKeys).
+	Dangling []BlobProblem `json:"dangling"`
+
+	// Set when states use KeySchemeGlobalBlobs: those blobs are shared with
+	// other projects, so they're never reported as orphans here.
+	GlobalBlobs bool `json:"globalBlobs,omitempty"`
+}
+
+// OK reports whether storage and states agree.
+func (r *ReconcileReport) OK() bool { return len(r.Orphans) == 0 && len(r.Dangling) == 0 }
+
+// BadKeys returns the dangling keys, for a repair push.
+func (r *ReconcileReport) BadKeys() []string { return badKeys(r.Dangling) }
+
+// ReconcileProject lists the project's blob prefix and cross-checks it
+// against its states. Orphans are judged against every state (older commits
+// still need their blobs), dangling references against HEAD. Blobs outside
+// the project prefix (global layout) are checked with a HEAD each.
+func ReconcileProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project string) (*ReconcileReport, error) {
+	head, cm, err := meta.GetLatestState(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: read state: %w", err)
+	}
+	if head == nil {
+		return nil, fmt.Errorf("reconcile: no remote state for %q", project)
+	}
+	rep := &ReconcileReport{Project: project, CommitID: cm.ID}
+
+	prefix := r2.ProjectBlobPrefix(project)
+	stored, err := r2.ListKeys(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: %w", err)
+	}
+	rep.Stored = len(stored)
+	inR2 := make(map[string]bool, len(stored))
+	for _, k := range stored {
+		inR2[k] = true
+	}
+
+	// Every key any state (including pending ones) references.
+	ids, err := meta.ListStateIDs(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: %w", err)
+	}
+	referenced := map[string]bool{}
+	for _, id := range ids {
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			// Can't tell what it references, so nothing may be called orphaned.
+			return nil, fmt.Errorf("reconcile: %w", err)
+		}
+		if st.KeyScheme.Resolve() == KeySchemeGlobalBlobs {
+			rep.GlobalBlobs = true
+		}
+		for _, fe := range st.Files {
+			referenced[r2.KeyFor(st, project, fe)] = true
+		}
+	}
+	for _, k := range stored {
+		if !referenced[k] {
+			rep.Orphans = append(rep.Orphans, k)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, fe := range head.Files {
+		k := r2.KeyFor(head, project, fe)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		if strings.HasPrefix(k, prefix) {
+			if !inR2[k] {
+				rep.Dangling = append(rep.Dangling, BlobProblem{Path: fe.Path, Key: k, Kind: BlobMissing})
+			}
+			continue
+		}
+		info, err := r2.Head(ctx, k)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("reconcile: %w", err)
+		case info == nil:
+			rep.Dangling = append(rep.Dangling, BlobProblem{Path: fe.Path, Key: k, Kind: BlobMissing})
+		}
+	}
+	sort.Strings(rep.Orphans)
+	sort.Slice(rep.Dangling, func(i, j int) bool { return rep.Dangling[i].Path < rep.Dangling[j].Path })
+	return rep, nil
+}
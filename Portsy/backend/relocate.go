@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RelocateRoot fixes up the projects under newRoot after the whole library
+// was moved there from oldRoot (e.g. to a bigger drive). Caches only hold
+// relative paths and survive the move; what doesn't is stored absolute
+// paths: a project's TempDir setting under oldRoot is rewritten to the same
+// place under newRoot. Each project is marked so its next push records the
+// new location as the remote ProjectPath, and its cache is checked against
+// the files that actually arrived. Every project is handled before the
+// problems found are returned together.
+func RelocateRoot(oldRoot, newRoot string) error {
+	oldRoot, newRoot = filepath.Clean(oldRoot), filepath.Clean(newRoot)
+	if oldRoot == newRoot {
+		return fmt.Errorf("relocate: old and new root are both %s", newRoot)
+	}
+	projects, err := ScanProjects(newRoot)
+	if err != nil {
+		return fmt.Errorf("relocate: %w", err)
+	}
+	var problems []error
+	for _, p := range projects {
+		if !p.HasPortsy {
+			continue
+		}
+		if err := relocateProject(oldRoot, newRoot, p); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", p.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("relocate: %w", errors.Join(problems...))
+	}
+	return nil
+}
+
+func relocateProject(oldRoot, newRoot string, p AbletonProject) error {
+	path := filepath.FromSlash(p.Path)
+	rel, err := filepath.Rel(newRoot, path)
+	if err != nil {
+		return err
+	}
+	s, err := LoadProjectSettings(path)
+	if err != nil {
+		return fmt.Errorf("read settings: %w", err)
+	}
+	if moved, ok := rebase(s.TempDir, oldRoot, newRoot); ok {
+		log.Printf("relocate %s: temp dir %s -> %s", p.Name, s.TempDir, moved)
+		s.TempDir = moved
+	}
+	s.RelocatedFrom = filepath.Join(oldRoot, rel)
+	if err := SaveProjectSettings(path, s); err != nil {
+		return fmt.Errorf("write settings: %w", err)
+	}
+
+	// The cache's files should all have come along.
+	lc, err := LoadLocalCache(path)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for key := range lc.Manifest {
+		if _, err := os.Lstat(filepath.Join(path, filepath.FromSlash(key))); err != nil {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%d cached file(s) missing after the move (e.g. %s)", len(missing), missing[0])
+	}
+	return nil
+}
+
+// rebase maps p from under oldRoot to the same place under newRoot.
+func rebase(p, oldRoot, newRoot string) (string, bool) {
+	if p == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(oldRoot, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.Join(newRoot, rel), true
+}
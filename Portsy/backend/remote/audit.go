@@ -0,0 +1,117 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Audit actions. Commit-level ones mirror the commit history; the rest
+// (repairs, relayouts, rollbacks, key GC) leave no other trace.
+const (
+	AuditCreateProject  = "create-project"
+	AuditBeginCommit    = "begin-commit"
+	AuditFinalizeCommit = "finalize-commit"
+	AuditUpsertState    = "upsert-state" // legacy single-step commit
+	AuditReplaceState   = "replace-state"
+	AuditRepairRecent   = "repair-recent"
+	AuditNormalizeDoc   = "normalize-doc"
+	AuditSetKeyScheme   = "set-key-scheme"
+	AuditRollback       = "rollback"
+	AuditPruneKeys      = "prune-keys"
+	AuditGC             = "gc"
+	AuditClone          = "clone" // on the new project; target is "<source>@<commit>"
+)
+
+// AuditEntry is one row of projects/{project}/audit: who did what to which
+// commit/state/setting, and when.
+type AuditEntry struct {
+	ID     string    `firestore:"-"      json:"id"`
+	Actor  string    `firestore:"actor"  json:"actor"`
+	Action string    `firestore:"action" json:"action"`
+	Target string    `firestore:"target" json:"target,omitempty"`
+	At     time.Time `firestore:"at"     json:"at"`
+}
+
+// defaultAuditLimit bounds GetAuditLog when no limit is given.
+const defaultAuditLimit = 100
+
+// defaultActor is "user@host" for the running process, or whichever half
+// is known.
+func defaultActor() string {
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	switch {
+	case name != "" && host != "":
+		return name + "@" + host
+	case name != "":
+		return name
+	case host != "":
+		return "@" + host
+	}
+	return "unknown"
+}
+
+// RecordAudit appends an entry to the project's audit log, for mutations
+// made outside the MetaStore (rollbacks, blob GC). Best-effort like the
+// MetaStore's own entries: the error is only returned for callers that care.
+func (m *MetaStore) RecordAudit(ctx context.Context, projectName, action, target string) error {
+	m.countWrites(1)
+	_, _, err := m.client.Collection("projects").Doc(projectName).Collection("audit").Add(ctx, AuditEntry{
+		Actor:  m.actor,
+		Action: action,
+		Target: target,
+		At:     time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("audit %s %s: %w", action, target, err)
+	}
+	return nil
+}
+
+// audit records a mutation that already succeeded; failures are dropped so
+// the audit log can never fail the operation it describes.
+func (m *MetaStore) audit(ctx context.Context, projectName, action, target string) {
+	_ = m.RecordAudit(ctx, projectName, action, target)
+}
+
+// GetAuditLog returns the project's audit entries, newest first
+// (limit <= 0 = 100).
+func (m *MetaStore) GetAuditLog(ctx context.Context, projectName string, limit int) ([]AuditEntry, error) {
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	iter := m.client.Collection("projects").Doc(projectName).
+		Collection("audit").OrderBy("at", firestore.Desc).Limit(limit).Documents(ctx)
+	defer iter.Stop()
+
+	var out []AuditEntry
+	for {
+		d, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("audit log %q: %w", projectName, err)
+		}
+		m.countReads(1)
+		var e AuditEntry
+		if err := d.DataTo(&e); err != nil {
+			continue
+		}
+		e.ID = d.Ref.ID
+		out = append(out, e)
+	}
+	if len(out) == 0 {
+		m.countReads(1) // an empty query still bills one read
+	}
+	return out, nil
+}
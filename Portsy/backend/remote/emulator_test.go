@@ -0,0 +1,249 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEmulatorStore connects to the Firestore emulator named by
+// FIRESTORE_EMULATOR_HOST, skipping the test when it isn't set:
+//
+//	gcloud emulators firestore start --host-port=localhost:8080
+//	FIRESTORE_EMULATOR_HOST=localhost:8080 go test ./backend/remote/
+//
+// The variable is cleared for the test itself, so the store has to reach the
+// emulator through MetaStoreConfig.EmulatorHost alone.
+func newEmulatorStore(t *testing.T) *MetaStore {
+	t.Helper()
+	host := os.Getenv(EmulatorHostEnv)
+	if host == "" {
+		t.Skipf("%s not set; skipping Firestore emulator test", EmulatorHostEnv)
+	}
+	t.Setenv(EmulatorHostEnv, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	m, err := NewMetaStoreForEmulator(ctx, "portsy-test", host)
+	if err != nil {
+		t.Fatalf("NewMetaStoreForEmulator: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// emulatorProject is a project name no other test run collides with.
+func emulatorProject(t *testing.T) string {
+	return fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestEmulatorUpsertAndReadBack(t *testing.T) {
+	m := newEmulatorStore(t)
+	ctx := context.Background()
+	project := emulatorProject(t)
+
+	if got, _, err := m.GetLatestState(ctx, project); err != nil || got != nil {
+		t.Fatalf("GetLatestState before any push = %v, %v; want nil, nil", got, err)
+	}
+
+	state := ProjectState{
+		ProjectName: project,
+		Algo:        "sha256",
+		Files: []FileEntry{
+			{Path: "Set.als", Hash: "aa", Size: 10},
+			{Path: "Samples/kick.wav", Hash: "bb", Size: 20},
+		},
+	}
+	commit := CommitMeta{ID: "c1", Message: "first", Timestamp: 100}
+	if err := m.UpsertLatestState(ctx, project, state, commit); err != nil {
+		t.Fatalf("UpsertLatestState: %v", err)
+	}
+
+	got, cm, err := m.GetLatestState(ctx, project)
+	if err != nil {
+		t.Fatalf("GetLatestState: %v", err)
+	}
+	if cm == nil || cm.ID != "c1" {
+		t.Fatalf("head commit = %+v, want c1", cm)
+	}
+	if got == nil || len(got.Files) != len(state.Files) {
+		t.Fatalf("state files = %+v, want %+v", got, state.Files)
+	}
+	for i, f := range state.Files {
+		if got.Files[i].Path != f.Path || got.Files[i].Hash != f.Hash || got.Files[i].Size != f.Size {
+			t.Errorf("file %d = %+v, want %+v", i, got.Files[i], f)
+		}
+	}
+}
+
+// TestEmulatorNormalizeLegacyDoc covers both write paths: a header written
+// with the old capitalized keys is readable, and NormalizeProjectDoc moves it
+// onto the tagged names and drops the legacy keys.
+func TestEmulatorNormalizeLegacyDoc(t *testing.T) {
+	m := newEmulatorStore(t)
+	ctx := context.Background()
+	project := emulatorProject(t)
+
+	state := ProjectState{ProjectName: project, Files: []FileEntry{{Path: "Set.als", Hash: "aa", Size: 1}}}
+	if err := m.UpsertLatestState(ctx, project, state, CommitMeta{ID: "c1", Timestamp: 100}); err != nil {
+		t.Fatalf("UpsertLatestState: %v", err)
+	}
+	// Rewrite the header the way old clients did.
+	p := m.client.Collection("projects").Doc(project)
+	if _, err := p.Set(ctx, map[string]any{
+		"Name": project, "LastCommitID": "c1", "LastCommitAt": int64(100),
+	}); err != nil {
+		t.Fatalf("write legacy header: %v", err)
+	}
+	if _, cm, err := m.GetLatestState(ctx, project); err != nil || cm == nil || cm.ID != "c1" {
+		t.Fatalf("GetLatestState on legacy header = %+v, %v; want c1", cm, err)
+	}
+
+	if err := m.NormalizeProjectDoc(ctx, project); err != nil {
+		t.Fatalf("NormalizeProjectDoc: %v", err)
+	}
+	snap, err := p.Get(ctx)
+	if err != nil {
+		t.Fatalf("get header: %v", err)
+	}
+	raw := snap.Data()
+	for _, legacy := range legacyHeaderKeys {
+		if _, ok := raw[legacy]; ok {
+			t.Errorf("legacy key %s still present: %v", legacy, raw)
+		}
+	}
+	if raw["lastCommitId"] != "c1" || raw["name"] != project {
+		t.Errorf("normalized header = %v", raw)
+	}
+	if _, cm, err := m.GetLatestState(ctx, project); err != nil || cm == nil || cm.ID != "c1" {
+		t.Fatalf("GetLatestState after normalize = %+v, %v; want c1", cm, err)
+	}
+}
+
+// TestEmulatorUpsertIsAtomic fails a push on its last write (a state doc over
+// Firestore's 1 MiB limit, after a valid header and commit) and checks none
+// of it landed: HEAD and history still show only the earlier commit.
+func TestEmulatorUpsertIsAtomic(t *testing.T) {
+	m := newEmulatorStore(t)
+	ctx := context.Background()
+	project := emulatorProject(t)
+
+	tests := []struct {
+		id      string
+		path    string
+		wantErr bool
+	}{
+		{id: "c1", path: "Set.als"},
+		{id: "c2", path: strings.Repeat("x", 1<<20+1), wantErr: true},
+	}
+	for i, tt := range tests {
+		state := ProjectState{ProjectName: project, Files: []FileEntry{{Path: tt.path, Hash: "aa", Size: 1}}}
+		err := m.UpsertLatestState(ctx, project, state, CommitMeta{ID: tt.id, Timestamp: int64(100 + i)})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("UpsertLatestState(%s) err = %v, wantErr %v", tt.id, err, tt.wantErr)
+		}
+	}
+
+	_, cm, err := m.GetLatestState(ctx, project)
+	if err != nil || cm == nil || cm.ID != "c1" {
+		t.Fatalf("GetLatestState = %+v, %v; want c1", cm, err)
+	}
+	snap, err := m.client.Collection("projects").Doc(project).Collection("commits").Doc("c2").Get(ctx)
+	if err == nil || snap.Exists() {
+		t.Errorf("commit c2 was written by the failed push")
+	}
+}
+
+// TestEmulatorCommitHistoryOrder writes three commits out of timestamp order
+// and reads them back through the ordered query and through the unordered
+// read GetCommitHistory falls back to without an index.
+func TestEmulatorCommitHistoryOrder(t *testing.T) {
+	m := newEmulatorStore(t)
+	ctx := context.Background()
+	project := emulatorProject(t)
+
+	for _, c := range []CommitMeta{{ID: "c2", Timestamp: 200}, {ID: "c1", Timestamp: 100}, {ID: "c3", Timestamp: 300}} {
+		state := ProjectState{ProjectName: project, Files: []FileEntry{{Path: "Set.als", Hash: c.ID, Size: 1}}}
+		if err := m.UpsertLatestState(ctx, project, state, c); err != nil {
+			t.Fatalf("UpsertLatestState(%s): %v", c.ID, err)
+		}
+	}
+	col := m.client.Collection("projects").Doc(project).Collection("commits")
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{name: "all", limit: 0, want: []string{"c3", "c2", "c1"}},
+		{name: "negative limit", limit: -1, want: []string{"c3", "c2", "c1"}},
+		{name: "limit", limit: 2, want: []string{"c3", "c2"}},
+		{name: "limit above count", limit: 10, want: []string{"c3", "c2", "c1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := func(commits []CommitMeta) []string {
+				out := []string{}
+				for _, cm := range commits {
+					out = append(out, cm.ID)
+				}
+				return out
+			}
+			got, err := m.GetCommitHistory(ctx, project, tt.limit)
+			if err != nil {
+				t.Fatalf("GetCommitHistory: %v", err)
+			}
+			if !slices.Equal(ids(got), tt.want) {
+				t.Errorf("GetCommitHistory = %v, want %v", ids(got), tt.want)
+			}
+
+			all, err := m.readCommits(col.Documents(ctx))
+			if err != nil {
+				t.Fatalf("readCommits: %v", err)
+			}
+			if got := ids(newestFirst(all, tt.limit)); !slices.Equal(got, tt.want) {
+				t.Errorf("fallback = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEmulatorCompressedStates writes one state inline and the next gzipped,
+// then reads both back through Firestore with compression on: the legacy
+// native-array doc still decodes.
+func TestEmulatorCompressedStates(t *testing.T) {
+	m := newEmulatorStore(t)
+	ctx := context.Background()
+	project := emulatorProject(t)
+
+	want := testState()
+	want.ProjectName = project
+	for _, c := range []struct {
+		id       string
+		compress bool
+	}{{"c1", false}, {"c2", true}} {
+		m.compressStates = c.compress
+		if err := m.UpsertLatestState(ctx, project, want, CommitMeta{ID: c.id, Timestamp: 100}); err != nil {
+			t.Fatalf("UpsertLatestState(%s): %v", c.id, err)
+		}
+	}
+
+	m.compressStates = true
+	for id, storage := range map[string]string{"c1": StateStorageInline, "c2": StateStorageGzip} {
+		st, err := m.GetState(ctx, project, id)
+		if err != nil {
+			t.Fatalf("GetState(%s): %v", id, err)
+		}
+		if st.FilesStorage != storage {
+			t.Errorf("%s FilesStorage = %q, want %q", id, st.FilesStorage, storage)
+		}
+		if !reflect.DeepEqual(st.Files, want.Files) {
+			t.Errorf("%s Files = %+v, want %+v", id, st.Files, want.Files)
+		}
+	}
+}
@@ -3,27 +3,87 @@ package remote
 import (
 	"Portsy/backend/internal/core/model"
 	"context"
+	"crypto/ed25519"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 )
 
 type MetaStore struct {
-	client *firestore.Client
-	projID string
+	client         *firestore.Client
+	emulatorConn   *grpc.ClientConn // set by MetaStoreConfig.EmulatorHost
+	projID         string
+	compressStates bool
+	manifestsInR2  bool               // see SetManifestsInR2
+	manifestBlobs  ManifestBlobs      // see SetManifestBlobs
+	metrics        Metrics            // optional, see MetaStoreConfig.Metrics
+	actor          string             // audit log actor, see MetaStoreConfig.Actor
+	signingKey     ed25519.PrivateKey // optional, see MetaStoreConfig.SigningKey
 }
 
 type MetaStoreConfig struct {
 	GCPProjectID      string // e.g. "portsy-prod"
 	ServiceAccountKey string // path to service account json (or leave "" to use ADC)
+
+	// ServiceAccountJSON is the key itself (e.g. from the OS keychain); wins
+	// over ServiceAccountKey.
+	ServiceAccountJSON []byte
+
+	// Optional server-side auth (Cloud Run / workload identity). When
+	// ImpersonateServiceAccount is set, the base credentials (key file or ADC)
+	// are exchanged for short-lived tokens of that account; no key file needs
+	// to ship with the sync engine.
+	ImpersonateServiceAccount string   // e.g. "portsy-sync@portsy-prod.iam.gserviceaccount.com"
+	Delegates                 []string // optional delegation chain for impersonation
+	Scopes                    []string // defaults to the Firestore (datastore) scope
+
+	// TokenSource, if set, wins over every other auth option.
+	TokenSource oauth2.TokenSource
+
+	// CompressStates writes new state docs with the file list gzipped into
+	// one bytes field (StateStorageGzip) instead of a native array, cutting
+	// document size for large manifests. Reads handle both either way.
+	CompressStates bool
+
+	// ManifestsInR2 writes each state's file list to R2 (StateStorageR2) and
+	// keeps only a pointer + summary in Firestore; wins over CompressStates.
+	// Requires SetManifestBlobs before the first write.
+	ManifestsInR2 bool
+
+	// Metrics, if set, counts Firestore document reads and writes.
+	Metrics Metrics
+
+	// Actor names whoever this store's mutations are attributed to in the
+	// audit log (e.g. a studio login); defaults to the OS "user@host".
+	Actor string
+
+	// SigningKey, if set, signs every final commit this store writes (see
+	// CommitSigningPayload); nil writes unsigned commits.
+	SigningKey ed25519.PrivateKey
+
+	// EmulatorHost ("localhost:8080") talks to a Firestore emulator instead
+	// of GCP over a plaintext connection of this store's own; no credentials
+	// are loaded and the process environment is left alone. Empty leaves the
+	// normal auth path alone.
+	EmulatorHost string
 }
 
+// EmulatorHostEnv is the variable the Firestore client reads to use an emulator.
+const EmulatorHostEnv = "FIRESTORE_EMULATOR_HOST"
+
+const firestoreScope = "https://www.googleapis.com/auth/datastore"
+
 // --- local, remote-only copies to avoid import cycles ---
 type FileEntry struct {
 	Path     string `firestore:"path" json:"path"`
@@ -31,6 +91,31 @@ type FileEntry struct {
 	Size     int64  `firestore:"size" json:"size"`
 	Modified int64  `firestore:"modified" json:"modified"`
 	R2Key    string `firestore:"r2Key" json:"r2Key"`
+
+	// ETag R2 returned when the blob was uploaded (unquoted). For single-part
+	// uploads this is the MD5 of the bytes; see VerifyCommit.
+	ETag string `firestore:"etag,omitempty" json:"etag,omitempty"`
+
+	// AudioHash fingerprints the decoded samples of WAV/AIFF files (metadata
+	// chunks ignored); only recorded when pushing with audio fingerprinting.
+	AudioHash string `firestore:"audioHash,omitempty" json:"audioHash,omitempty"`
+
+	// Audio is the header info of WAV/AIFF/FLAC files, read at push time;
+	// nil for other files, unreadable headers and older commits.
+	Audio *AudioInfo `firestore:"audio,omitempty" json:"audio,omitempty"`
+
+	// ContentType is the MIME type recorded at push, served by previews;
+	// empty for older commits.
+	ContentType string `firestore:"contentType,omitempty" json:"contentType,omitempty"`
+}
+
+// AudioInfo describes an audio file without downloading it.
+type AudioInfo struct {
+	Format     string  `firestore:"format"     json:"format"`   // "wav" | "aiff" | "flac"
+	Duration   float64 `firestore:"duration"   json:"duration"` // seconds
+	SampleRate int     `firestore:"sampleRate" json:"sampleRate"`
+	Channels   int     `firestore:"channels"   json:"channels"`
+	BitDepth   int     `firestore:"bitDepth"   json:"bitDepth"`
 }
 
 type ProjectState struct {
@@ -39,6 +124,26 @@ type ProjectState struct {
 	Files       []FileEntry `firestore:"files"       json:"files"`
 	CreatedAt   int64       `firestore:"createdAt"   json:"createdAt"`
 	Algo        string      `firestore:"algo"        json:"algo,omitempty"`
+
+	// sha256 over sorted (path, hash) pairs; see ComputeManifestHash.
+	ManifestHash string `firestore:"manifestHash,omitempty" json:"manifestHash,omitempty"`
+
+	// How Files is stored in the state doc (StateStorage*); Files is always
+	// filled in again on read. FilesGz only travels to/from Firestore.
+	FilesStorage string `firestore:"filesStorage,omitempty" json:"filesStorage,omitempty"`
+	FilesGz      []byte `firestore:"filesGz,omitempty" json:"-"`
+
+	// StateStorageR2 only: where the file list lives, plus a summary that
+	// doesn't need the blob.
+	ManifestKey string `firestore:"manifestKey,omitempty" json:"manifestKey,omitempty"`
+	FileCount   int    `firestore:"fileCount,omitempty" json:"fileCount,omitempty"`
+	TotalBytes  int64  `firestore:"totalBytes,omitempty" json:"totalBytes,omitempty"`
+
+	// Blob key layout this state was written with (0 = pre-versioning, v1).
+	KeyScheme KeyScheme `firestore:"keyScheme,omitempty" json:"keyScheme,omitempty"`
+
+	// Ableton Live version that saved the project's main .als ("" if unknown).
+	LiveVersion string `firestore:"liveVersion,omitempty" json:"liveVersion,omitempty"`
 }
 
 type CommitMeta struct {
@@ -48,14 +153,31 @@ type CommitMeta struct {
 	UserID    string `firestore:"userId"    json:"userId,omitempty"`
 	ParentID  string `firestore:"parentId"  json:"parentId,omitempty"`
 	Status    string `firestore:"status"    json:"status,omitempty"`
+
+	// Copy of ProjectState.LiveVersion so history can show it without the state.
+	LiveVersion string `firestore:"liveVersion,omitempty" json:"liveVersion,omitempty"`
+
+	// Change counts vs the parent, set at commit time (or backfilled by
+	// GetCommitHistory for older commits).
+	Summary *CommitSummary `firestore:"summary,omitempty" json:"summary,omitempty"`
+
+	// Optional ed25519 signature (base64) over CommitSigningPayload, and the
+	// SigningKeyID of the key that made it. Empty = unsigned.
+	SignerKeyID string `firestore:"signerKeyId,omitempty" json:"signerKeyId,omitempty"`
+	Signature   string `firestore:"signature,omitempty"   json:"signature,omitempty"`
+
+	// ShortID is ID's first ShortCommitIDLen chars, filled in on read.
+	ShortID string `firestore:"-" json:"shortId,omitempty"`
 }
 
 type ProjectDoc struct {
-	ProjectID    string   `firestore:"-"            json:"projectId"`
-	Name         string   `firestore:"name"         json:"name"`
-	LastCommitID string   `firestore:"lastCommitId" json:"lastCommitId,omitempty"`
-	LastCommitAt int64    `firestore:"lastCommitAt" json:"lastCommitAt,omitempty"`
-	Last5        []string `firestore:"last5"        json:"last5,omitempty"`
+	ProjectID    string    `firestore:"-"            json:"projectId"`
+	Name         string    `firestore:"name"         json:"name"`
+	LastCommitID string    `firestore:"lastCommitId" json:"lastCommitId,omitempty"`
+	LastCommitAt int64     `firestore:"lastCommitAt" json:"lastCommitAt,omitempty"`
+	Last5        []string  `firestore:"last5"        json:"last5,omitempty"`
+	KeyScheme    KeyScheme `firestore:"keyScheme,omitempty" json:"keyScheme,omitempty"`
+	LiveVersion  string    `firestore:"liveVersion,omitempty" json:"liveVersion,omitempty"`
 }
 
 func NewMetaStore(ctx context.Context, cfg MetaStoreConfig) (*MetaStore, error) {
@@ -64,56 +186,147 @@ func NewMetaStore(ctx context.Context, cfg MetaStoreConfig) (*MetaStore, error)
 		err    error
 	)
 
-	if cfg.ServiceAccountKey != "" {
-		client, err = firestore.NewClient(ctx, cfg.GCPProjectID, option.WithCredentialsFile(cfg.ServiceAccountKey))
-	} else {
-		client, err = firestore.NewClient(ctx, cfg.GCPProjectID)
+	var (
+		opts []option.ClientOption
+		conn *grpc.ClientConn
+	)
+	if cfg.EmulatorHost != "" {
+		if conn, err = dialEmulator(cfg.EmulatorHost); err != nil {
+			return nil, err
+		}
+		opts = []option.ClientOption{option.WithGRPCConn(conn), option.WithoutAuthentication()}
+	} else if opts, err = clientAuthOptions(ctx, cfg); err != nil {
+		return nil, err
 	}
+	client, err = firestore.NewClient(ctx, cfg.GCPProjectID, opts...)
 	if err != nil {
+		if conn != nil {
+			_ = conn.Close()
+		}
 		return nil, fmt.Errorf("firestore.NewClient: %w", err)
 	}
-	return &MetaStore{client: client, projID: cfg.GCPProjectID}, nil
+	actor := cfg.Actor
+	if actor == "" {
+		actor = defaultActor()
+	}
+	return &MetaStore{client: client, emulatorConn: conn, projID: cfg.GCPProjectID, compressStates: cfg.CompressStates, manifestsInR2: cfg.ManifestsInR2, metrics: cfg.Metrics, actor: actor, signingKey: cfg.SigningKey}, nil
+}
+
+// dialEmulator opens a plaintext connection to a Firestore emulator, the
+// way the client does for FIRESTORE_EMULATOR_HOST, but for this store only
+// rather than by changing the process environment.
+func dialEmulator(host string) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(host,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(emulatorCreds{}))
+	if err != nil {
+		return nil, fmt.Errorf("dial firestore emulator %s: %w", host, err)
+	}
+	return conn, nil
+}
+
+// emulatorCreds is the fixed token the emulator accepts as the admin user.
+type emulatorCreds struct{}
+
+func (emulatorCreds) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer owner"}, nil
+}
+
+func (emulatorCreds) RequireTransportSecurity() bool { return false }
+
+// NewMetaStoreForEmulator connects to the Firestore emulator at host under
+// projectID (any ID works there), for integration tests and local runs.
+func NewMetaStoreForEmulator(ctx context.Context, projectID, host string) (*MetaStore, error) {
+	return NewMetaStore(ctx, MetaStoreConfig{GCPProjectID: projectID, EmulatorHost: host})
+}
+
+// clientAuthOptions picks the auth path, in order of precedence:
+// explicit TokenSource > impersonation > service-account JSON/file > bare ADC.
+func clientAuthOptions(ctx context.Context, cfg MetaStoreConfig) ([]option.ClientOption, error) {
+	if cfg.TokenSource != nil {
+		return []option.ClientOption{option.WithTokenSource(cfg.TokenSource)}, nil
+	}
+
+	var base []option.ClientOption
+	switch {
+	case len(cfg.ServiceAccountJSON) > 0:
+		base = append(base, option.WithCredentialsJSON(cfg.ServiceAccountJSON))
+	case cfg.ServiceAccountKey != "":
+		base = append(base, option.WithCredentialsFile(cfg.ServiceAccountKey))
+	}
+	if cfg.ImpersonateServiceAccount == "" {
+		return base, nil
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{firestoreScope}
+	}
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: cfg.ImpersonateServiceAccount,
+		Scopes:          scopes,
+		Delegates:       cfg.Delegates,
+	}, base...)
+	if err != nil {
+		return nil, fmt.Errorf("impersonate %s: %w", cfg.ImpersonateServiceAccount, err)
+	}
+	return []option.ClientOption{option.WithTokenSource(ts)}, nil
 }
 
 func (m *MetaStore) Close() error {
+	var err error
 	if m.client != nil {
-		return m.client.Close()
+		err = m.client.Close()
 	}
-	return nil
+	if m.emulatorConn != nil {
+		if cerr := m.emulatorConn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
 // Collections layout:
 // projects/{projectName}
-//   - fields: Name, LastCommitID, LastCommitAt
+//   - fields: name, NameLower, lastCommitId, lastCommitAt, last5 (see ProjectDoc tags)
 //   - commits/{commitID} (doc)
 //   - states/{commitID}  (doc)  // manifest snapshot for that commit
+//   - audit/{autoID}     (doc)  // append-only AuditEntry per mutation
 func (m *MetaStore) UpsertLatestState(ctx context.Context, projectName string, state ProjectState, commit CommitMeta) error {
 	p := m.client.Collection("projects").Doc(projectName)
+	stored, err := m.encodeState(ctx, projectName, commit.ID, state)
+	if err != nil {
+		return err
+	}
+	m.signCommit(&commit, stored.ManifestHash)
+
+	// One batch so header, commit and state land together (or not at all);
+	// a torn write would leave HEAD pointing at a commit with no state doc.
+	b := m.client.Batch()
 
 	// MergeAll REQUIRES a map, not a struct.
-	if _, err := p.Set(ctx, map[string]interface{}{
-		"Name":         projectName,
-		"NameLower":    strings.ToLower(projectName),
-		"LastCommitID": commit.ID,
-		"LastCommitAt": commit.Timestamp,
-	}, firestore.MergeAll); err != nil {
-		return fmt.Errorf("upsert project header: %w", err)
-	}
+	b.Set(p, headerFields(projectName, withLiveVersion(state, map[string]any{
+		"lastCommitId": commit.ID,
+		"lastCommitAt": commit.Timestamp,
+	})), firestore.MergeAll)
 
 	// New commit doc — no merge needed.
-	if _, err := p.Collection("commits").Doc(commit.ID).Set(ctx, commit); err != nil {
-		return fmt.Errorf("set commit %s: %w", commit.ID, err)
-	}
+	b.Set(p.Collection("commits").Doc(commit.ID), commit)
 
 	// Snapshot for that commit.
-	if _, err := p.Collection("states").Doc(commit.ID).Set(ctx, state); err != nil {
-		return fmt.Errorf("set state %s: %w", commit.ID, err)
+	b.Set(p.Collection("states").Doc(commit.ID), stored)
+
+	m.countWrites(3)
+	if _, err := b.Commit(ctx); err != nil {
+		return fmt.Errorf("upsert latest state %s: %w", commit.ID, err)
 	}
+	m.audit(ctx, projectName, AuditUpsertState, commit.ID)
 	return nil
 }
 
 func (m *MetaStore) GetLatestState(ctx context.Context, projectName string) (*ProjectState, *CommitMeta, error) {
 	p := m.client.Collection("projects").Doc(projectName)
+	m.countReads(1)
 	doc, err := p.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
@@ -122,14 +335,15 @@ func (m *MetaStore) GetLatestState(ctx context.Context, projectName string) (*Pr
 		return nil, nil, fmt.Errorf("get project %q: %w", projectName, err)
 	}
 
-	var pd ProjectDoc
-	if err := doc.DataTo(&pd); err != nil {
+	pd, err := decodeProjectDoc(doc)
+	if err != nil {
 		return nil, nil, fmt.Errorf("decode project doc: %w", err)
 	}
 	if pd.LastCommitID == "" {
 		return nil, nil, nil
 	}
 
+	m.countReads(2)
 	cdoc, err := p.Collection("commits").Doc(pd.LastCommitID).Get(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get commit %s: %w", pd.LastCommitID, err)
@@ -139,21 +353,23 @@ func (m *MetaStore) GetLatestState(ctx context.Context, projectName string) (*Pr
 	if err := cdoc.DataTo(&cm); err != nil {
 		return nil, nil, fmt.Errorf("decode commit %s: %w", pd.LastCommitID, err)
 	}
+	cm.ShortID = ShortCommitID(cm.ID)
 
 	sdoc, err := p.Collection("states").Doc(pd.LastCommitID).Get(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get state %s: %w", pd.LastCommitID, err)
 	}
 
-	var st ProjectState
-	if err := sdoc.DataTo(&st); err != nil {
-		return nil, nil, fmt.Errorf("decode state %s: %w", pd.LastCommitID, err)
+	st, err := m.decodeState(ctx, pd.LastCommitID, sdoc)
+	if err != nil {
+		return nil, nil, err
 	}
 	return &st, &cm, nil
 }
 
 func (m *MetaStore) ListProjects(ctx context.Context) ([]model.ProjectDoc, error) {
 	docs, err := m.client.Collection("projects").Documents(ctx).GetAll()
+	m.countReads(max(1, len(docs))) // an empty query still bills one read
 	if err != nil {
 		return nil, err
 	}
@@ -163,12 +379,62 @@ func (m *MetaStore) ListProjects(ctx context.Context) ([]model.ProjectDoc, error
 		if err := d.DataTo(&p); err != nil {
 			continue
 		}
+		p.Name, p.LastCommitID, p.LastCommitAt = headFromData(d.Data())
 		p.ProjectID = d.Ref.ID
+		p.Empty = p.LastCommitID == ""
 		out = append(out, p)
 	}
 	return out, nil
 }
 
+// ProjectExists reports whether a project doc named projectName exists.
+func (m *MetaStore) ProjectExists(ctx context.Context, projectName string) (bool, error) {
+	m.countReads(1)
+	_, err := m.client.Collection("projects").Doc(projectName).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get project %q: %w", projectName, err)
+	}
+	return true, nil
+}
+
+// CreateProject registers an empty remote project (no HEAD) ahead of its first commit.
+// Fails if a project with the same name (case-insensitive) already exists.
+func (m *MetaStore) CreateProject(ctx context.Context, projectName string) (string, error) {
+	projectName = strings.TrimSpace(projectName)
+	if projectName == "" {
+		return "", fmt.Errorf("create project: empty name")
+	}
+	lower := strings.ToLower(projectName)
+
+	m.countReads(1)
+	dupes, err := m.client.Collection("projects").Where("NameLower", "==", lower).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return "", fmt.Errorf("lookup project %q: %w", projectName, err)
+	}
+	if len(dupes) > 0 {
+		return "", fmt.Errorf("project %q already exists (%s)", projectName, dupes[0].Ref.ID)
+	}
+
+	p := m.client.Collection("projects").Doc(projectName)
+	// Create (not Set) so a concurrent creator with the same doc ID loses cleanly.
+	m.countWrites(1)
+	if _, err := p.Create(ctx, map[string]any{
+		"name":      projectName,
+		"NameLower": lower,
+		"createdAt": time.Now().Unix(),
+	}); err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			return "", fmt.Errorf("project %q already exists", projectName)
+		}
+		return "", fmt.Errorf("create project %q: %w", projectName, err)
+	}
+	m.audit(ctx, projectName, AuditCreateProject, projectName)
+	return p.ID, nil
+}
+
 // BeginCommit writes a pending commit + its draft state.
 // Only writes; no reads, so a batch is fine.
 func (m *MetaStore) BeginCommit(ctx context.Context, projectName string, commit CommitMeta, state ProjectState) error {
@@ -177,27 +443,32 @@ func (m *MetaStore) BeginCommit(ctx context.Context, projectName string, commit
 		commit.Timestamp = time.Now().Unix()
 	}
 
+	stored, err := m.encodeState(ctx, projectName, commit.ID, state)
+	if err != nil {
+		return err
+	}
+
 	p := m.client.Collection("projects").Doc(projectName)
 	b := m.client.Batch()
 
 	// Ensure the project doc exists (merge so we don't clobber fields)
-	b.Set(p, map[string]any{
-		"Name":      projectName,
-		"NameLower": strings.ToLower(projectName),
-	}, firestore.MergeAll)
+	b.Set(p, headerFields(projectName, nil), firestore.MergeAll)
 
 	// Stash commit + state under subcollections
 	b.Set(p.Collection("commits").Doc(commit.ID), commit)
-	b.Set(p.Collection("states").Doc(commit.ID), state)
+	b.Set(p.Collection("states").Doc(commit.ID), stored)
 
-	_, err := b.Commit(ctx)
-	if err != nil {
+	m.countWrites(3)
+	if _, err := b.Commit(ctx); err != nil {
 		return fmt.Errorf("begin commit %s: %w", commit.ID, err)
 	}
+	m.audit(ctx, projectName, AuditBeginCommit, commit.ID)
 	return nil
 }
 
-// FinalizeCommit verifies blobs exist (outside tx), then atomically:
+// FinalizeCommit verifies blobs exist (outside tx; verify runs right after
+// the uploads, so it should ride out read-after-write lag, as the backend's
+// R2Client.BlobVerifier does), then atomically:
 // - writes the final commit + state (idempotent if already present)
 // - advances project HEAD
 // - updates Last5 as a list of commit IDs (max 5, oldest->newest)
@@ -215,14 +486,20 @@ func (m *MetaStore) FinalizeCommit(
 		}
 	}
 
+	stored, err := m.encodeState(ctx, projectName, commit.ID, state)
+	if err != nil {
+		return err
+	}
+
 	p := m.client.Collection("projects").Doc(projectName)
 	commits := p.Collection("commits")
 	states := p.Collection("states")
 
 	// 2) Firestore transaction: all reads first, then writes (no read after write).
-	return m.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+	err = m.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
 		// READ the current project doc (ok before any writes)
 		var proj ProjectDoc
+		m.countReads(1)
 		snap, err := tx.Get(p)
 		if err != nil {
 			if status.Code(err) == codes.NotFound {
@@ -230,21 +507,45 @@ func (m *MetaStore) FinalizeCommit(
 			} else {
 				return fmt.Errorf("tx get project: %w", err)
 			}
-		} else if err := snap.DataTo(&proj); err != nil {
+		} else if proj, err = decodeProjectDoc(snap); err != nil {
 			return fmt.Errorf("tx decode project: %w", err)
 		}
 
-		// Prepare the final commit
+		// Summarize against the HEAD we're replacing (still in the read phase).
+		if commit.ParentID == "" {
+			commit.ParentID = proj.LastCommitID
+		}
+		if commit.Summary == nil {
+			var parent *ProjectState
+			if commit.ParentID != "" {
+				m.countReads(1)
+				psnap, err := tx.Get(states.Doc(commit.ParentID))
+				if err != nil {
+					return fmt.Errorf("tx get parent state: %w", err)
+				}
+				ps, err := m.decodeState(ctx, commit.ParentID, psnap)
+				if err != nil {
+					return fmt.Errorf("tx decode parent state: %w", err)
+				}
+				parent = &ps
+			}
+			sum := SummarizeStates(parent, state)
+			commit.Summary = &sum
+		}
+
+		// Prepare the final commit (signed now that ParentID is settled)
 		commit.Status = "final"
+		m.signCommit(&commit, stored.ManifestHash)
 		if commit.Timestamp == 0 {
 			commit.Timestamp = time.Now().Unix()
 		}
 
 		// WRITE (no reads after this point)
+		m.countWrites(3) // commit, state, project header
 		if err := tx.Set(commits.Doc(commit.ID), commit); err != nil {
 			return fmt.Errorf("tx set commit: %w", err)
 		}
-		if err := tx.Set(states.Doc(commit.ID), state); err != nil {
+		if err := tx.Set(states.Doc(commit.ID), stored); err != nil {
 			return fmt.Errorf("tx set state: %w", err)
 		}
 
@@ -260,41 +561,226 @@ func (m *MetaStore) FinalizeCommit(
 		}
 		proj.Last5 = newLast
 
-		// Upsert the project doc
-		if err := tx.Set(p, proj); err != nil {
+		// Upsert the project doc (merge keeps NameLower & friends intact)
+		if err := tx.Set(p, headerFields(projectName, withLiveVersion(state, map[string]any{
+			"lastCommitId": proj.LastCommitID,
+			"lastCommitAt": proj.LastCommitAt,
+			"last5":        proj.Last5,
+		})), firestore.MergeAll); err != nil {
 			return fmt.Errorf("tx set project: %w", err)
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	m.audit(ctx, projectName, AuditFinalizeCommit, commit.ID)
+	return nil
 }
 
-func (m *MetaStore) GetCommitHistory(ctx context.Context, projectName string, limit int) ([]CommitMeta, error) {
-	iter := m.client.Collection("projects").Doc(projectName).
-		Collection("commits").OrderBy("Timestamp", firestore.Desc).Limit(limit).Documents(ctx)
+// RepairRecent rebuilds the project's pointer fields from the commits subcollection.
+// - Only final commits count (legacy UpsertLatestState commits have no status)
+// - Last5 is rewritten from the real commits (oldest->newest)
+// - HEAD is moved to the newest final commit if it points at a missing/pending one
+func (m *MetaStore) RepairRecent(ctx context.Context, projectName string) error {
+	p := m.client.Collection("projects").Doc(projectName)
+
+	iter := p.Collection("commits").OrderBy("timestamp", firestore.Asc).Documents(ctx)
 	defer iter.Stop()
 
-	var commits []CommitMeta
+	var final []CommitMeta
 	for {
 		d, err := iter.Next()
 		if err != nil {
 			if err == iterator.Done {
 				break
 			}
+			return fmt.Errorf("iterate commits: %w", err)
+		}
+		m.countReads(1)
+		var cm CommitMeta
+		if err := d.DataTo(&cm); err != nil {
+			return fmt.Errorf("decode commit %s: %w", d.Ref.ID, err)
+		}
+		if cm.ID == "" {
+			cm.ID = d.Ref.ID
+		}
+		if cm.Status == "" || cm.Status == "final" {
+			final = append(final, cm)
+		}
+	}
+
+	err := m.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		m.countReads(1)
+		snap, err := tx.Get(p)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return fmt.Errorf("project %q not found", projectName)
+			}
+			return fmt.Errorf("tx get project: %w", err)
+		}
+		proj, err := decodeProjectDoc(snap)
+		if err != nil {
+			return fmt.Errorf("tx decode project: %w", err)
+		}
+
+		recent := make([]string, 0, 5)
+		start := max(0, len(final)-5)
+		for _, cm := range final[start:] {
+			recent = append(recent, cm.ID)
+		}
+
+		// HEAD must reference a real, final commit; otherwise fall back to the newest one.
+		head, headAt := proj.LastCommitID, proj.LastCommitAt
+		valid := false
+		for _, cm := range final {
+			if cm.ID == head {
+				valid, headAt = true, cm.Timestamp
+				break
+			}
+		}
+		if !valid {
+			head, headAt = "", 0
+			if n := len(final); n > 0 {
+				head, headAt = final[n-1].ID, final[n-1].Timestamp
+			}
+		}
+
+		m.countWrites(1)
+		if err := tx.Set(p, headerFields(projectName, map[string]any{
+			"lastCommitId": head,
+			"lastCommitAt": headAt,
+			"last5":        recent,
+		}), firestore.MergeAll); err != nil {
+			return fmt.Errorf("tx repair project: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.audit(ctx, projectName, AuditRepairRecent, projectName)
+	return nil
+}
+
+// readCommits decodes every commit doc iter yields.
+func (m *MetaStore) readCommits(iter *firestore.DocumentIterator) ([]CommitMeta, error) {
+	defer iter.Stop()
+	var commits []CommitMeta
+	for {
+		d, err := iter.Next()
+		if err != nil {
+			if err == iterator.Done {
+				return commits, nil
+			}
 			return nil, fmt.Errorf("iterate commits: %w", err)
 		}
+		m.countReads(1)
 		var cm CommitMeta
 		if err := d.DataTo(&cm); err != nil {
-			return nil, fmt.Errorf("decode commite: %w", err)
+			return nil, fmt.Errorf("decode commit: %w", err)
 		}
+		cm.ShortID = ShortCommitID(cm.ID)
 		commits = append(commits, cm)
 	}
+}
+
+// newestFirst sorts commits by timestamp, newest first (ties keep their
+// order), and keeps at most limit of them (<= 0 = all).
+func newestFirst(commits []CommitMeta, limit int) []CommitMeta {
+	sort.SliceStable(commits, func(i, j int) bool { return commits[i].Timestamp > commits[j].Timestamp })
+	if limit > 0 && len(commits) > limit {
+		commits = commits[:limit]
+	}
+	return commits
+}
+
+// GetCommitHistory returns the project's commits newest first, at most
+// limit of them (<= 0 = all).
+func (m *MetaStore) GetCommitHistory(ctx context.Context, projectName string, limit int) ([]CommitMeta, error) {
+	col := m.client.Collection("projects").Doc(projectName).Collection("commits")
+	q := col.OrderBy("timestamp", firestore.Desc)
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	commits, err := m.readCommits(q.Documents(ctx))
+	if status.Code(err) == codes.FailedPrecondition {
+		// No usable index for the ordered query: read them all and sort here.
+		if commits, err = m.readCommits(col.Documents(ctx)); err == nil {
+			commits = newestFirst(commits, limit)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Lazily backfill summaries of pre-summary commits (best-effort). Without
+	// a ParentID the parent is the next-older commit; the oldest one on a
+	// short page is the project's first commit.
+	for i := range commits {
+		cm := &commits[i]
+		if cm.Summary != nil {
+			continue
+		}
+		parent := cm.ParentID
+		if parent == "" {
+			switch {
+			case i+1 < len(commits):
+				parent = commits[i+1].ID
+			case len(commits) == limit:
+				continue // parent is beyond this page
+			}
+		}
+		_ = m.backfillSummary(ctx, projectName, cm, parent)
+	}
 	return commits, nil
 }
 
+// ShortCommitIDLen is how much of a commit ID is shown by default; any
+// unique prefix of at least MinCommitPrefixLen chars resolves.
+const (
+	ShortCommitIDLen   = 8
+	MinCommitPrefixLen = 4
+)
+
+// ShortCommitID returns the display prefix of a commit ID.
+func ShortCommitID(id string) string {
+	if len(id) > ShortCommitIDLen {
+		return id[:ShortCommitIDLen]
+	}
+	return id
+}
+
+// ResolveCommitPrefix expands a commit ID prefix to the one full ID that
+// starts with it. A full ID is returned as long as the commit exists.
+func (m *MetaStore) ResolveCommitPrefix(ctx context.Context, projectName, prefix string) (string, error) {
+	prefix = strings.TrimSpace(prefix)
+	if len(prefix) < MinCommitPrefixLen {
+		return "", fmt.Errorf("commit %q: need at least %d characters", prefix, MinCommitPrefixLen)
+	}
+	commits := m.client.Collection("projects").Doc(projectName).Collection("commits")
+	docs, err := commits.
+		Where(firestore.DocumentID, ">=", commits.Doc(prefix)).
+		Where(firestore.DocumentID, "<", commits.Doc(prefix+"\uf8ff")).
+		Select().Documents(ctx).GetAll()
+	m.countReads(max(1, len(docs)))
+	if err != nil {
+		return "", fmt.Errorf("resolve commit %q: %w", prefix, err)
+	}
+	switch len(docs) {
+	case 0:
+		return "", fmt.Errorf("commit %q not found in %q", prefix, projectName)
+	case 1:
+		return docs[0].Ref.ID, nil
+	}
+	return "", fmt.Errorf("commit %q is ambiguous, %d matches", prefix, len(docs))
+}
+
 // Fetch manifest + commit metadata for a specific commit ID.
 func (m *MetaStore) GetStateByCommit(ctx context.Context, projectName, commitID string) (*ProjectState, *CommitMeta, error) {
 	p := m.client.Collection("projects").Doc(projectName)
 
+	m.countReads(2)
 	cdoc, err := p.Collection("commits").Doc(commitID).Get(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get commit %s: %w", commitID, err)
@@ -303,14 +789,183 @@ func (m *MetaStore) GetStateByCommit(ctx context.Context, projectName, commitID
 	if err := cdoc.DataTo(&cm); err != nil {
 		return nil, nil, fmt.Errorf("decode commit %s: %w", commitID, err)
 	}
+	cm.ShortID = ShortCommitID(cm.ID)
 
 	sdoc, err := p.Collection("states").Doc(commitID).Get(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get state %s: %w", commitID, err)
 	}
-	var st ProjectState
-	if err := sdoc.DataTo(&st); err != nil {
-		return nil, nil, fmt.Errorf("decode state %s: %w", commitID, err)
+	st, err := m.decodeState(ctx, commitID, sdoc)
+	if err != nil {
+		return nil, nil, err
 	}
 	return &st, &cm, nil
 }
+
+// ListStateIDs returns the commit IDs that have a state snapshot, in no particular order.
+func (m *MetaStore) ListStateIDs(ctx context.Context, projectName string) ([]string, error) {
+	refs, err := m.client.Collection("projects").Doc(projectName).
+		Collection("states").DocumentRefs(ctx).GetAll()
+	m.countReads(max(1, len(refs)))
+	if err != nil {
+		return nil, fmt.Errorf("list states: %w", err)
+	}
+	ids := make([]string, 0, len(refs))
+	for _, r := range refs {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// GetState reads a state snapshot without its commit doc.
+func (m *MetaStore) GetState(ctx context.Context, projectName, commitID string) (*ProjectState, error) {
+	m.countReads(1)
+	sdoc, err := m.client.Collection("projects").Doc(projectName).
+		Collection("states").Doc(commitID).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get state %s: %w", commitID, err)
+	}
+	st, err := m.decodeState(ctx, commitID, sdoc)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// ReplaceState overwrites an existing snapshot in place (storage-only rewrites
+// such as R2Key relayouts; the manifest hash is recomputed, not trusted).
+func (m *MetaStore) ReplaceState(ctx context.Context, projectName, commitID string, state ProjectState) error {
+	state.ManifestHash = ""
+	stored, err := m.encodeState(ctx, projectName, commitID, state)
+	if err != nil {
+		return err
+	}
+	m.countWrites(1)
+	_, err = m.client.Collection("projects").Doc(projectName).
+		Collection("states").Doc(commitID).Set(ctx, stored)
+	if err != nil {
+		return fmt.Errorf("replace state %s: %w", commitID, err)
+	}
+	m.audit(ctx, projectName, AuditReplaceState, commitID)
+	return nil
+}
+
+// ---- project header field casing ----
+//
+// Older writers stored the header as Name/LastCommitID/LastCommitAt while
+// ProjectDoc is tagged name/lastCommitId/lastCommitAt. DataTo matches keys
+// case-insensitively, so a doc holding both spellings decodes to whichever
+// key happens to come last. All writes now go through headerFields (tagged
+// names + deletes of the legacy keys) and all reads through headFromData.
+
+// tagged name -> legacy capitalized name
+var legacyHeaderKeys = map[string]string{
+	"name":         "Name",
+	"lastCommitId": "LastCommitID",
+	"lastCommitAt": "LastCommitAt",
+}
+
+// headerFields builds a MergeAll payload for the project doc using the
+// ProjectDoc tag names. Any legacy key whose tagged twin is being written
+// is deleted in the same write.
+func headerFields(projectName string, extra map[string]any) map[string]any {
+	out := map[string]any{
+		"name":      projectName,
+		"NameLower": strings.ToLower(projectName),
+	}
+	for k, v := range extra {
+		out[k] = v
+	}
+	for tagged, legacy := range legacyHeaderKeys {
+		if _, ok := out[tagged]; ok {
+			out[legacy] = firestore.Delete
+		}
+	}
+	return out
+}
+
+// withLiveVersion adds the HEAD state's Live version to header fields, so the
+// project list can show it; unknown versions leave the stored one alone.
+func withLiveVersion(state ProjectState, fields map[string]any) map[string]any {
+	if state.LiveVersion != "" {
+		fields["liveVersion"] = state.LiveVersion
+	}
+	return fields
+}
+
+// decodeProjectDoc decodes a project header, resolving legacy field names.
+func decodeProjectDoc(snap *firestore.DocumentSnapshot) (ProjectDoc, error) {
+	var pd ProjectDoc
+	if err := snap.DataTo(&pd); err != nil {
+		return pd, err
+	}
+	pd.Name, pd.LastCommitID, pd.LastCommitAt = headFromData(snap.Data())
+	return pd, nil
+}
+
+// headFromData reads name + HEAD from raw doc data, accepting both spellings.
+// If both HEAD spellings are present, the one with the newer timestamp wins.
+func headFromData(raw map[string]any) (name, lastID string, lastAt int64) {
+	name, _ = raw["name"].(string)
+	if name == "" {
+		name, _ = raw["Name"].(string)
+	}
+
+	id, _ := raw["lastCommitId"].(string)
+	at, _ := raw["lastCommitAt"].(int64)
+	legacyID, _ := raw["LastCommitID"].(string)
+	legacyAt, _ := raw["LastCommitAt"].(int64)
+
+	if id == "" || (legacyID != "" && legacyAt > at) {
+		return name, legacyID, legacyAt
+	}
+	return name, id, at
+}
+
+// normalizedHeader is the MergeAll payload that moves a project header's raw
+// data onto the tagged field names: HEAD as resolved by headFromData, and a
+// delete for every legacy key the doc still has.
+func normalizedHeader(projectName string, raw map[string]any) map[string]any {
+	name, id, at := headFromData(raw)
+	if name == "" {
+		name = projectName
+	}
+	extra := map[string]any{}
+	if id != "" {
+		extra["lastCommitId"] = id
+		extra["lastCommitAt"] = at
+	}
+	fields := headerFields(name, extra)
+	for _, legacy := range legacyHeaderKeys {
+		if _, ok := raw[legacy]; ok {
+			fields[legacy] = firestore.Delete
+		}
+	}
+	return fields
+}
+
+// NormalizeProjectDoc rewrites a project header onto the tagged field names
+// and removes the legacy capitalized keys. Safe to run repeatedly.
+func (m *MetaStore) NormalizeProjectDoc(ctx context.Context, projectName string) error {
+	p := m.client.Collection("projects").Doc(projectName)
+	err := m.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		m.countReads(1)
+		snap, err := tx.Get(p)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return fmt.Errorf("project %q not found", projectName)
+			}
+			return fmt.Errorf("tx get project: %w", err)
+		}
+		m.countWrites(1)
+		if err := tx.Set(p, normalizedHeader(projectName, snap.Data()), firestore.MergeAll); err != nil {
+			return fmt.Errorf("tx normalize project: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.audit(ctx, projectName, AuditNormalizeDoc, projectName)
+	return nil
+}
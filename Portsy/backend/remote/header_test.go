@@ -0,0 +1,75 @@
+package remote
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestNormalizedHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    map[string]any
+		wantID string
+		wantAt int64
+		want   string // name
+	}{
+		{
+			name:   "legacy only",
+			raw:    map[string]any{"Name": "Song", "LastCommitID": "c1", "LastCommitAt": int64(10)},
+			wantID: "c1", wantAt: 10, want: "Song",
+		},
+		{
+			name:   "tagged only",
+			raw:    map[string]any{"name": "Song", "lastCommitId": "c2", "lastCommitAt": int64(20)},
+			wantID: "c2", wantAt: 20, want: "Song",
+		},
+		{
+			name: "both, legacy newer",
+			raw: map[string]any{
+				"name": "Song", "lastCommitId": "c2", "lastCommitAt": int64(20),
+				"LastCommitID": "c3", "LastCommitAt": int64(30),
+			},
+			wantID: "c3", wantAt: 30, want: "Song",
+		},
+		{
+			name:   "no name, no head",
+			raw:    map[string]any{"LastCommitID": ""},
+			wantID: "", want: "fallback",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizedHeader("fallback", tt.raw)
+			if got["name"] != tt.want {
+				t.Errorf("name = %v, want %q", got["name"], tt.want)
+			}
+			if tt.wantID == "" {
+				if _, ok := got["lastCommitId"]; ok {
+					t.Errorf("lastCommitId = %v, want unset", got["lastCommitId"])
+				}
+			} else if got["lastCommitId"] != tt.wantID || got["lastCommitAt"] != tt.wantAt {
+				t.Errorf("head = %v@%v, want %s@%d", got["lastCommitId"], got["lastCommitAt"], tt.wantID, tt.wantAt)
+			}
+			// Every legacy key the doc had must be deleted.
+			for _, legacy := range legacyHeaderKeys {
+				_, had := tt.raw[legacy]
+				if had && got[legacy] != firestore.Delete {
+					t.Errorf("%s = %v, want firestore.Delete", legacy, got[legacy])
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderFieldsDeletesLegacyTwins(t *testing.T) {
+	got := headerFields("Song", map[string]any{"lastCommitId": "c1", "lastCommitAt": int64(1)})
+	for tagged, legacy := range legacyHeaderKeys {
+		if _, ok := got[tagged]; !ok {
+			t.Errorf("%s missing", tagged)
+		}
+		if got[legacy] != firestore.Delete {
+			t.Errorf("%s = %v, want firestore.Delete", legacy, got[legacy])
+		}
+	}
+}
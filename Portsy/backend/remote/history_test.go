@@ -0,0 +1,43 @@
+package remote
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewestFirst(t *testing.T) {
+	commits := func(ids ...string) []CommitMeta {
+		ts := map[string]int64{"a": 100, "b": 200, "c": 300, "b2": 200}
+		out := make([]CommitMeta, len(ids))
+		for i, id := range ids {
+			out[i] = CommitMeta{ID: id, Timestamp: ts[id]}
+		}
+		return out
+	}
+	tests := []struct {
+		name  string
+		in    []CommitMeta
+		limit int
+		want  []string
+	}{
+		{name: "empty", want: []string{}},
+		{name: "out of order", in: commits("b", "a", "c"), want: []string{"c", "b", "a"}},
+		{name: "already newest first", in: commits("c", "b", "a"), want: []string{"c", "b", "a"}},
+		{name: "limit", in: commits("a", "c", "b"), limit: 2, want: []string{"c", "b"}},
+		{name: "limit above count", in: commits("a", "c"), limit: 5, want: []string{"c", "a"}},
+		{name: "zero limit is all", in: commits("a", "b", "c"), limit: 0, want: []string{"c", "b", "a"}},
+		{name: "negative limit is all", in: commits("a", "b", "c"), limit: -1, want: []string{"c", "b", "a"}},
+		{name: "ties keep read order", in: commits("b2", "a", "b"), want: []string{"b2", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, cm := range newestFirst(tt.in, tt.limit) {
+				got = append(got, cm.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("newestFirst = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
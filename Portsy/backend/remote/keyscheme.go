@@ -0,0 +1,92 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// KeyScheme versions the R2 blob key layout. The project doc records the
+// scheme new commits use; each state records the scheme it was written with,
+// so old commits keep resolving after a project moves to a newer layout.
+// The zero value means "unversioned" and resolves to KeySchemeProjectBlobs.
+type KeyScheme int
+
+const (
+	KeySchemeProjectBlobs KeyScheme = 1 // <project>/blobs/<hash>
+	KeySchemeGlobalBlobs  KeyScheme = 2 // blobs/<hash[:2]>/<hash> (dedup across projects)
+
+	DefaultKeyScheme = KeySchemeProjectBlobs
+)
+
+// Resolve maps the unversioned zero value to the layout it was written with.
+func (s KeyScheme) Resolve() KeyScheme {
+	if s == 0 {
+		return KeySchemeProjectBlobs
+	}
+	return s
+}
+
+func (s KeyScheme) Valid() bool {
+	switch s.Resolve() {
+	case KeySchemeProjectBlobs, KeySchemeGlobalBlobs:
+		return true
+	}
+	return false
+}
+
+func (s KeyScheme) String() string {
+	switch s.Resolve() {
+	case KeySchemeProjectBlobs:
+		return "v1 (project blobs)"
+	case KeySchemeGlobalBlobs:
+		return "v2 (global blobs)"
+	}
+	return fmt.Sprintf("v%d (unknown)", int(s))
+}
+
+// GetKeyScheme returns the scheme new commits for projectName should use.
+// Projects that predate versioning (or don't exist yet) get DefaultKeyScheme.
+func (m *MetaStore) GetKeyScheme(ctx context.Context, projectName string) (KeyScheme, error) {
+	m.countReads(1)
+	snap, err := m.client.Collection("projects").Doc(projectName).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return DefaultKeyScheme, nil
+	}
+	if err != nil {
+		return DefaultKeyScheme, err
+	}
+	v, err := snap.DataAt("keyScheme")
+	if err != nil {
+		return DefaultKeyScheme, nil // field absent
+	}
+	n, ok := v.(int64)
+	if !ok || n == 0 {
+		return DefaultKeyScheme, nil
+	}
+	s := KeyScheme(n)
+	if !s.Valid() {
+		return DefaultKeyScheme, fmt.Errorf("project %q: unknown key scheme %d", projectName, n)
+	}
+	return s, nil
+}
+
+// SetKeyScheme switches the layout used by future commits of projectName.
+// Existing states keep their own KeyScheme (and explicit R2Keys).
+func (m *MetaStore) SetKeyScheme(ctx context.Context, projectName string, s KeyScheme) error {
+	if !s.Valid() {
+		return fmt.Errorf("unknown key scheme %d", int(s))
+	}
+	m.countWrites(1)
+	_, err := m.client.Collection("projects").Doc(projectName).Set(ctx, map[string]any{
+		"keyScheme": int(s.Resolve()),
+	}, firestore.MergeAll)
+	if err != nil {
+		return err
+	}
+	m.audit(ctx, projectName, AuditSetKeyScheme, s.String())
+	return nil
+}
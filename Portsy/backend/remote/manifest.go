@@ -0,0 +1,53 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrManifestMismatch means a stored state's file list doesn't match its
+// recorded ManifestHash (truncated, partially written or tampered).
+var ErrManifestMismatch = errors.New("manifest hash mismatch")
+
+// ComputeManifestHash hashes the sorted (path, hash) pairs of a state.
+// Only content identity is covered; R2 keys/mtimes may change without
+// invalidating it (e.g. layout migrations).
+func ComputeManifestHash(ps ProjectState) string {
+	pairs := make([][2]string, 0, len(ps.Files))
+	for _, f := range ps.Files {
+		pairs = append(pairs, [2]string{f.Path, f.Hash})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+	h := sha256.New()
+	for _, p := range pairs {
+		h.Write([]byte(p[0]))
+		h.Write([]byte{0})
+		h.Write([]byte(p[1]))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// withManifestHash stamps the state before it is written.
+func withManifestHash(ps ProjectState) ProjectState {
+	if ps.ManifestHash == "" {
+		ps.ManifestHash = ComputeManifestHash(ps)
+	}
+	return ps
+}
+
+// verifyManifestHash checks a decoded state. States written before
+// ManifestHash existed have none and are accepted as-is.
+func verifyManifestHash(commitID string, ps *ProjectState) error {
+	if ps.ManifestHash == "" {
+		return nil
+	}
+	if got := ComputeManifestHash(*ps); got != ps.ManifestHash {
+		return fmt.Errorf("state %s: %w (want %s, got %s)", commitID, ErrManifestMismatch, ps.ManifestHash, got)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package remote
+
+// Metrics receives operational counters and gauges (transfer volume,
+// Firestore traffic, retries). Implementations must be safe for concurrent
+// use. It's optional everywhere: with no Metrics configured the hooks are a
+// nil check and nothing else.
+type Metrics interface {
+	Count(name string, delta int64) // monotonically increasing counter
+	Gauge(name string, value int64) // last value wins
+}
+
+// Metric names, in Prometheus style. The R2 and cache ones are reported by
+// the backend package.
+const (
+	MetricBytesUploaded   = "portsy_r2_bytes_uploaded_total"
+	MetricBytesDownloaded = "portsy_r2_bytes_downloaded_total"
+	MetricObjectsHeaded   = "portsy_r2_objects_headed_total"
+	MetricR2Retries       = "portsy_r2_retries_total"
+	MetricFirestoreReads  = "portsy_firestore_reads_total"
+	MetricFirestoreWrites = "portsy_firestore_writes_total"
+	MetricCacheHits       = "portsy_cache_hits_total"       // blobs carried forward / files already up to date
+	MetricLastSyncUnix    = "portsy_last_sync_unix_seconds" // gauge: last successful push or pull
+)
+
+// countReads / countWrites record Firestore document operations.
+func (m *MetaStore) countReads(n int) {
+	if m.metrics != nil && n > 0 {
+		m.metrics.Count(MetricFirestoreReads, int64(n))
+	}
+}
+
+func (m *MetaStore) countWrites(n int) {
+	if m.metrics != nil && n > 0 {
+		m.metrics.Count(MetricFirestoreWrites, int64(n))
+	}
+}
@@ -0,0 +1,64 @@
+package remote
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Commit signature errors, from VerifyCommitSignature.
+var (
+	ErrCommitUnsigned  = errors.New("commit is not signed")
+	ErrUntrustedSigner = errors.New("commit signed by an untrusted key")
+	ErrBadSignature    = errors.New("commit signature does not match")
+)
+
+// CommitSigningPayload is what a commit signature covers: the commit ID, its
+// state's manifest hash (so the file list) and its parent (so history can't
+// be re-ordered under it).
+func CommitSigningPayload(commitID, manifestHash, parentID string) []byte {
+	return []byte("portsy-commit-v1\n" + commitID + "\n" + manifestHash + "\n" + parentID + "\n")
+}
+
+// SigningKeyID is the short fingerprint stored next to a signature.
+func SigningKeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// signCommit stamps commit with a signature over its payload when the store
+// has a signing key (MetaStoreConfig.SigningKey); ParentID must be final.
+func (m *MetaStore) signCommit(commit *CommitMeta, manifestHash string) {
+	if m.signingKey == nil {
+		return
+	}
+	sig := ed25519.Sign(m.signingKey, CommitSigningPayload(commit.ID, manifestHash, commit.ParentID))
+	commit.SignerKeyID = SigningKeyID(m.signingKey.Public().(ed25519.PublicKey))
+	commit.Signature = base64.StdEncoding.EncodeToString(sig)
+}
+
+// VerifyCommitSignature checks commit's signature over its state's manifest
+// hash against the trusted public keys.
+func VerifyCommitSignature(commit CommitMeta, manifestHash string, trusted []ed25519.PublicKey) error {
+	if commit.Signature == "" {
+		return ErrCommitUnsigned
+	}
+	sig, err := base64.StdEncoding.DecodeString(commit.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	payload := CommitSigningPayload(commit.ID, manifestHash, commit.ParentID)
+	for _, pub := range trusted {
+		if SigningKeyID(pub) != commit.SignerKeyID {
+			continue
+		}
+		if !ed25519.Verify(pub, payload, sig) {
+			return fmt.Errorf("%w (key %s)", ErrBadSignature, commit.SignerKeyID)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w (key %s)", ErrUntrustedSigner, commit.SignerKeyID)
+}
@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"cloud.google.com/go/firestore"
+)
+
+// State storage modes, recorded per state doc in ProjectState.FilesStorage.
+// Reads handle every mode regardless of what the store is configured to write,
+// so old native-array states keep working after compression is turned on.
+const (
+	StateStorageInline = ""     // Files as a native Firestore array (default, legacy)
+	StateStorageGzip   = "gzip" // FilesGz = gzip(JSON(Files)), Files left empty
+	StateStorageR2     = "r2"   // gzip(JSON(Files)) in R2 at ManifestKey; doc keeps a summary
+)
+
+// ManifestBlobs is the bit of R2 the MetaStore needs for StateStorageR2.
+// The backend's R2Client implements it.
+type ManifestBlobs interface {
+	PutManifest(ctx context.Context, key string, data []byte) error
+	GetManifest(ctx context.Context, key string) ([]byte, error)
+}
+
+// ErrNoManifestBlobs means a state needs R2 (to read or write its manifest)
+// but SetManifestBlobs was never called.
+var ErrNoManifestBlobs = errors.New("manifest blob store not configured")
+
+// ManifestKey is where StateStorageR2 keeps a commit's file list.
+func ManifestKey(projectName, commitID string) string {
+	return path.Join(projectName, "manifests", commitID+".json.gz")
+}
+
+// SetManifestBlobs wires the R2 side of StateStorageR2. Needed to read such
+// states even when this store writes another mode.
+func (m *MetaStore) SetManifestBlobs(b ManifestBlobs) { m.manifestBlobs = b }
+
+// SetManifestsInR2 switches new state writes to StateStorageR2 (or back).
+func (m *MetaStore) SetManifestsInR2(on bool) { m.manifestsInR2 = on }
+
+// encodeState stamps the manifest hash (always over the plain file list) and
+// packs Files according to the store's configured mode. In R2 mode the
+// manifest blob is uploaded here, before the doc that points at it.
+func (m *MetaStore) encodeState(ctx context.Context, projectName, commitID string, ps ProjectState) (ProjectState, error) {
+	ps = withManifestHash(ps)
+	ps.FilesStorage, ps.FilesGz, ps.ManifestKey = StateStorageInline, nil, ""
+	ps.FileCount, ps.TotalBytes = 0, 0
+	if !m.manifestsInR2 && !m.compressStates {
+		return ps, nil
+	}
+
+	gz, err := gzipFiles(ps.Files)
+	if err != nil {
+		return ps, fmt.Errorf("compress state %s files: %w", commitID, err)
+	}
+	if !m.manifestsInR2 {
+		ps.Files = nil
+		ps.FilesStorage, ps.FilesGz = StateStorageGzip, gz
+		return ps, nil
+	}
+
+	if m.manifestBlobs == nil {
+		return ps, fmt.Errorf("state %s: %w", commitID, ErrNoManifestBlobs)
+	}
+	key := ManifestKey(projectName, commitID)
+	if err := m.manifestBlobs.PutManifest(ctx, key, gz); err != nil {
+		return ps, fmt.Errorf("upload manifest %s: %w", key, err)
+	}
+	ps.FileCount = len(ps.Files)
+	for _, f := range ps.Files {
+		ps.TotalBytes += f.Size
+	}
+	ps.Files = nil
+	ps.FilesStorage, ps.ManifestKey = StateStorageR2, key
+	return ps, nil
+}
+
+// decodeState reads a state doc, unpacks Files for any storage mode, and
+// checks the manifest hash.
+func (m *MetaStore) decodeState(ctx context.Context, commitID string, snap *firestore.DocumentSnapshot) (ProjectState, error) {
+	var st ProjectState
+	if err := snap.DataTo(&st); err != nil {
+		return st, fmt.Errorf("decode state %s: %w", commitID, err)
+	}
+	return m.unpackState(ctx, commitID, st)
+}
+
+// unpackState is decodeState after the doc has been read: the inverse of
+// encodeState, whatever mode the store itself writes.
+func (m *MetaStore) unpackState(ctx context.Context, commitID string, st ProjectState) (ProjectState, error) {
+	switch st.FilesStorage {
+	case StateStorageInline:
+	case StateStorageGzip:
+		files, err := gunzipFiles(st.FilesGz)
+		if err != nil {
+			return st, fmt.Errorf("decode state %s files: %w", commitID, err)
+		}
+		st.Files, st.FilesGz = files, nil
+	case StateStorageR2:
+		if m.manifestBlobs == nil {
+			return st, fmt.Errorf("state %s: %w", commitID, ErrNoManifestBlobs)
+		}
+		gz, err := m.manifestBlobs.GetManifest(ctx, st.ManifestKey)
+		if err != nil {
+			return st, fmt.Errorf("get manifest %s: %w", st.ManifestKey, err)
+		}
+		files, err := gunzipFiles(gz)
+		if err != nil {
+			return st, fmt.Errorf("decode manifest %s: %w", st.ManifestKey, err)
+		}
+		st.Files = files
+	default:
+		return st, fmt.Errorf("decode state %s: unknown files storage %q", commitID, st.FilesStorage)
+	}
+	if err := verifyManifestHash(commitID, &st); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+func gzipFiles(files []FileEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(files); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipFiles(gz []byte) ([]FileEntry, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var files []FileEntry
+	if err := json.Unmarshal(raw, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
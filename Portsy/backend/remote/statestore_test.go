@@ -0,0 +1,217 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testState() ProjectState {
+	return ProjectState{
+		ProjectName: "Song",
+		Algo:        "sha256",
+		Files: []FileEntry{
+			{Path: "Set.als", Hash: "aa", Size: 10, Modified: 1_700_000_000},
+			{Path: "Samples/kick.wav", Hash: "bb", Size: 20, R2Key: "Song/blobs/bb", ETag: "e1", AudioHash: "cc"},
+		},
+	}
+}
+
+func TestStateStorageGzip(t *testing.T) {
+	ctx := context.Background()
+	inline := &MetaStore{}
+	compressed := &MetaStore{compressStates: true}
+	tests := []struct {
+		name        string
+		write, read *MetaStore
+		wantStorage string
+	}{
+		{name: "gzip round trip", write: compressed, read: compressed, wantStorage: StateStorageGzip},
+		{name: "gzip read without compression", write: compressed, read: inline, wantStorage: StateStorageGzip},
+		{name: "legacy inline read with compression", write: inline, read: compressed, wantStorage: StateStorageInline},
+		{name: "inline round trip", write: inline, read: inline, wantStorage: StateStorageInline},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := testState()
+			doc, err := tt.write.encodeState(ctx, "Song", "c1", want)
+			if err != nil {
+				t.Fatalf("encodeState: %v", err)
+			}
+			if doc.FilesStorage != tt.wantStorage {
+				t.Fatalf("FilesStorage = %q, want %q", doc.FilesStorage, tt.wantStorage)
+			}
+			if doc.ManifestHash == "" {
+				t.Error("encodeState left ManifestHash empty")
+			}
+			switch tt.wantStorage {
+			case StateStorageGzip:
+				if doc.Files != nil || len(doc.FilesGz) == 0 {
+					t.Errorf("gzip doc = %d files inline, %d bytes gzipped; want 0 and >0", len(doc.Files), len(doc.FilesGz))
+				}
+			case StateStorageInline:
+				if doc.FilesGz != nil || !reflect.DeepEqual(doc.Files, want.Files) {
+					t.Errorf("inline doc = %+v (%d gzipped bytes), want the files as-is", doc.Files, len(doc.FilesGz))
+				}
+			}
+
+			got, err := tt.read.unpackState(ctx, "c1", doc)
+			if err != nil {
+				t.Fatalf("unpackState: %v", err)
+			}
+			if !reflect.DeepEqual(got.Files, want.Files) {
+				t.Errorf("Files = %+v, want %+v", got.Files, want.Files)
+			}
+			if got.FilesGz != nil {
+				t.Errorf("FilesGz left on the decoded state (%d bytes)", len(got.FilesGz))
+			}
+		})
+	}
+}
+
+func TestUnpackStateGzipErrors(t *testing.T) {
+	ctx := context.Background()
+	m := &MetaStore{compressStates: true}
+	good, err := m.encodeState(ctx, "Song", "c1", testState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		edit    func(*ProjectState)
+		wantErr error  // errors.Is target
+		wantMsg string // otherwise a substring
+	}{
+		{name: "not gzip", edit: func(st *ProjectState) { st.FilesGz = []byte("[]") }, wantMsg: "decode state c1 files"},
+		{name: "truncated", edit: func(st *ProjectState) { st.FilesGz = st.FilesGz[:len(st.FilesGz)/2] }, wantMsg: "decode state c1 files"},
+		{name: "unknown mode", edit: func(st *ProjectState) { st.FilesStorage = "zstd" }, wantMsg: `unknown files storage "zstd"`},
+		{name: "hash mismatch", edit: func(st *ProjectState) { st.ManifestHash = "00" }, wantErr: ErrManifestMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := good
+			st.FilesGz = append([]byte(nil), good.FilesGz...)
+			tt.edit(&st)
+			_, err := m.unpackState(ctx, "c1", st)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("unpackState err = %v, want %v", err, tt.wantErr)
+				}
+			case err == nil || !strings.Contains(err.Error(), tt.wantMsg):
+				t.Fatalf("unpackState err = %v, want it to mention %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// memManifests is a ManifestBlobs over a map, counting uploads.
+type memManifests struct {
+	blobs map[string][]byte
+	puts  int
+}
+
+func (b *memManifests) PutManifest(_ context.Context, key string, data []byte) error {
+	if b.blobs == nil {
+		b.blobs = map[string][]byte{}
+	}
+	b.blobs[key] = append([]byte(nil), data...)
+	b.puts++
+	return nil
+}
+
+func (b *memManifests) GetManifest(_ context.Context, key string) ([]byte, error) {
+	data, ok := b.blobs[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return data, nil
+}
+
+func TestStateStorageR2(t *testing.T) {
+	ctx := context.Background()
+	blobs := &memManifests{}
+	m := &MetaStore{manifestsInR2: true, compressStates: true} // R2 wins
+	m.SetManifestBlobs(blobs)
+
+	want := testState()
+	doc, err := m.encodeState(ctx, "Song", "c1", want)
+	if err != nil {
+		t.Fatalf("encodeState: %v", err)
+	}
+	key := ManifestKey("Song", "c1")
+	if doc.FilesStorage != StateStorageR2 || doc.ManifestKey != key {
+		t.Fatalf("doc storage = %q at %q, want %q at %q", doc.FilesStorage, doc.ManifestKey, StateStorageR2, key)
+	}
+	if doc.Files != nil || doc.FilesGz != nil {
+		t.Errorf("doc keeps %d files, %d gzipped bytes; want only the pointer", len(doc.Files), len(doc.FilesGz))
+	}
+	if doc.FileCount != 2 || doc.TotalBytes != 30 {
+		t.Errorf("summary = %d files, %d bytes; want 2, 30", doc.FileCount, doc.TotalBytes)
+	}
+	if blobs.puts != 1 || len(blobs.blobs[key]) == 0 {
+		t.Fatalf("manifest uploads = %d, blob at %s = %d bytes; want one upload", blobs.puts, key, len(blobs.blobs[key]))
+	}
+
+	tests := []struct {
+		name    string
+		read    *MetaStore
+		doc     func() ProjectState
+		wantErr error  // errors.Is target
+		wantMsg string // otherwise a substring
+	}{
+		{name: "read back", read: m, doc: func() ProjectState { return doc }},
+		{name: "store writing inline", read: &MetaStore{manifestBlobs: blobs}, doc: func() ProjectState { return doc }},
+		{name: "no blob store", read: &MetaStore{}, doc: func() ProjectState { return doc }, wantErr: ErrNoManifestBlobs},
+		{
+			name: "manifest missing", read: m,
+			doc:     func() ProjectState { d := doc; d.ManifestKey = ManifestKey("Song", "gone"); return d },
+			wantMsg: "get manifest Song/manifests/gone.json.gz",
+		},
+		{
+			name: "manifest for another state", read: m,
+			doc: func() ProjectState {
+				other := want
+				other.Files = want.Files[:1]
+				d, err := m.encodeState(ctx, "Song", "c0", other)
+				if err != nil {
+					t.Fatal(err)
+				}
+				d.ManifestHash = doc.ManifestHash
+				return d
+			},
+			wantErr: ErrManifestMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.read.unpackState(ctx, "c1", tt.doc())
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("unpackState err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("unpackState err = %v, want it to mention %q", err, tt.wantMsg)
+				}
+				return
+			case err != nil:
+				t.Fatalf("unpackState: %v", err)
+			}
+			if !reflect.DeepEqual(got.Files, want.Files) {
+				t.Errorf("Files = %+v, want %+v", got.Files, want.Files)
+			}
+		})
+	}
+
+	t.Run("write without blob store", func(t *testing.T) {
+		_, err := (&MetaStore{manifestsInR2: true}).encodeState(ctx, "Song", "c1", want)
+		if !errors.Is(err, ErrNoManifestBlobs) {
+			t.Fatalf("encodeState err = %v, want %v", err, ErrNoManifestBlobs)
+		}
+	})
+}
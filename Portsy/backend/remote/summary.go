@@ -0,0 +1,81 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// CommitSummary is a commit's change counts against its parent, stored on the
+// commit doc so history can render them without loading any state.
+type CommitSummary struct {
+	Added      int  `firestore:"added"      json:"added"`
+	Modified   int  `firestore:"modified"   json:"modified"`
+	Deleted    int  `firestore:"deleted"    json:"deleted"`
+	ALSChanged bool `firestore:"alsChanged" json:"alsChanged"`
+}
+
+// SummarizeStates diffs cur against parent (nil = first commit).
+// ALSChanged ignores Live's Backup/ copies, which change on every save.
+func SummarizeStates(parent *ProjectState, cur ProjectState) CommitSummary {
+	var s CommitSummary
+	prev := map[string]string{}
+	if parent != nil {
+		for _, f := range parent.Files {
+			prev[f.Path] = f.Hash
+		}
+	}
+	isSet := func(p string) bool {
+		return strings.EqualFold(path.Ext(p), ".als") && !strings.HasPrefix(strings.ToLower(p), "backup/")
+	}
+	for _, f := range cur.Files {
+		ph, ok := prev[f.Path]
+		switch {
+		case !ok:
+			s.Added++
+		case ph != f.Hash:
+			s.Modified++
+		default:
+			delete(prev, f.Path)
+			continue
+		}
+		delete(prev, f.Path)
+		if isSet(f.Path) {
+			s.ALSChanged = true
+		}
+	}
+	for p := range prev {
+		s.Deleted++
+		if isSet(p) {
+			s.ALSChanged = true
+		}
+	}
+	return s
+}
+
+// backfillSummary computes and stores the summary of a commit written before
+// summaries existed. parentID "" means it's the project's first commit.
+func (m *MetaStore) backfillSummary(ctx context.Context, projectName string, cm *CommitMeta, parentID string) error {
+	cur, err := m.GetState(ctx, projectName, cm.ID)
+	if err != nil {
+		return err
+	}
+	var parent *ProjectState
+	if parentID != "" {
+		if parent, err = m.GetState(ctx, projectName, parentID); err != nil {
+			return err
+		}
+	}
+	sum := SummarizeStates(parent, *cur)
+	m.countWrites(1)
+	_, err = m.client.Collection("projects").Doc(projectName).
+		Collection("commits").Doc(cm.ID).Update(ctx, []firestore.Update{{Path: "summary", Value: sum}})
+	if err != nil {
+		return fmt.Errorf("backfill summary %s: %w", cm.ID, err)
+	}
+	cm.Summary = &sum
+	return nil
+}
@@ -0,0 +1,39 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+)
+
+// stateAt reads the project's state at commitID ("" = HEAD; short IDs are
+// expanded). A project with no commits is an error.
+func stateAt(ctx context.Context, meta *remote.MetaStore, project, commitID string) (*ProjectState, error) {
+	commitID, err := resolveCommitID(ctx, meta, project, commitID)
+	if err != nil {
+		return nil, err
+	}
+	var st *ProjectState
+	if commitID == "" {
+		st, _, err = meta.GetLatestState(ctx, project)
+	} else {
+		st, _, err = meta.GetStateByCommit(ctx, project, commitID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	if st == nil {
+		return nil, fmt.Errorf("%q has no commits", project)
+	}
+	return st, nil
+}
+
+// RemoteFiles lists the files of the project's commitID ("" = HEAD) as
+// stored, including audio header info where the push recorded it.
+func RemoteFiles(ctx context.Context, meta *remote.MetaStore, project, commitID string) ([]FileEntry, error) {
+	st, err := stateAt(ctx, meta, project, commitID)
+	if err != nil {
+		return nil, fmt.Errorf("files: %w", err)
+	}
+	return st.Files, nil
+}
@@ -0,0 +1,120 @@
+package backend
+
+import (
+	corehash "Portsy/backend/internal/core/hash"
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// ScrubReport is the result of one ScrubProject pass.
+type ScrubReport struct {
+	Project  string        `json:"project"`
+	Total    int           `json:"total"`   // distinct blobs across every state
+	Sampled  int           `json:"sampled"` // blobs downloaded and re-hashed
+	Problems []BlobProblem `json:"problems"`
+
+	// States that couldn't be read; their blobs weren't candidates.
+	UnreadableStates []string `json:"unreadableStates,omitempty"`
+}
+
+// OK reports whether every sampled blob passed.
+func (r *ScrubReport) OK() bool { return len(r.Problems) == 0 }
+
+// BadKeys returns the corrupt keys, for a targeted repair push
+// (PushOptions.ForceKeys) from a machine that still has the files.
+func (r *ScrubReport) BadKeys() []string { return badKeys(r.Problems) }
+
+// ScrubProject downloads and re-hashes a random sampleRate fraction (0 < rate
+// <= 1, at least one blob) of the blobs referenced by any of the project's
+// states, catching bit rot in old versions nobody pulls anymore. Keeping the
+// rate low lets it run continuously at a trickle (see RunScrubber).
+func ScrubProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project string, sampleRate float64) (*ScrubReport, error) {
+	if sampleRate <= 0 || sampleRate > 1 {
+		return nil, fmt.Errorf("scrub: sample rate %v out of range (0, 1]", sampleRate)
+	}
+	ids, err := meta.ListStateIDs(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("scrub: %w", err)
+	}
+	rep := &ScrubReport{Project: project}
+
+	type blob struct {
+		fe   FileEntry
+		algo string
+	}
+	byKey := map[string]blob{}
+	for _, id := range ids {
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			rep.UnreadableStates = append(rep.UnreadableStates, id)
+			continue
+		}
+		for _, fe := range st.Files {
+			k := r2.KeyFor(st, project, fe)
+			if _, ok := byKey[k]; !ok {
+				byKey[k] = blob{fe: fe, algo: st.Algo}
+			}
+		}
+	}
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rep.Total = len(keys)
+	if len(keys) == 0 {
+		return rep, nil
+	}
+
+	n := int(math.Ceil(float64(len(keys)) * sampleRate))
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	keys = keys[:n]
+	sort.Strings(keys)
+	rep.Sampled = n
+
+	rep.Problems, err = checkBlobs(ctx, keys, func(key string) *BlobProblem {
+		b := byKey[key]
+		return checkBlob(ctx, r2, corehash.New(corehash.Algorithm(b.algo)), key, b.fe, true)
+	})
+	return rep, err
+}
+
+// RunScrubber scrubs projects (every remote project when empty) once per
+// interval until ctx is done, handing each report to onReport. For the sync
+// daemon; a failed pass doesn't stop later ones.
+func RunScrubber(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, projects []string, interval time.Duration, sampleRate float64, onReport func(*ScrubReport, error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		names := projects
+		if len(names) == 0 {
+			docs, err := meta.ListProjects(ctx)
+			if err != nil {
+				onReport(nil, fmt.Errorf("scrub: list projects: %w", err))
+				continue
+			}
+			names = nil
+			for _, d := range docs {
+				if !d.Empty {
+					names = append(names, d.ProjectID)
+				}
+			}
+		}
+		for _, name := range names {
+			if ctx.Err() != nil {
+				return
+			}
+			onReport(ScrubProject(ctx, meta, r2, name, sampleRate))
+		}
+	}
+}
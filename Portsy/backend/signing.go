@@ -0,0 +1,87 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Commit signing env vars. The signing key is a base64 ed25519 private key
+// (GenerateSigningKey); like the other secrets it can live in the OS
+// keychain. Trusted keys are base64 public keys, comma-separated.
+const (
+	SigningKeyEnv    = "PORTSY_SIGNING_KEY"
+	TrustedKeysEnv   = "PORTSY_TRUSTED_KEYS"
+	RequireSignedEnv = "PORTSY_REQUIRE_SIGNED"
+)
+
+// SignaturePolicy decides which commits PullProjectWithOptions and
+// VerifyCommit accept. With no Trusted keys nothing is checked. Otherwise a
+// signed commit must verify against one of them, and an unsigned one is
+// only rejected when Require is set.
+type SignaturePolicy struct {
+	Trusted []ed25519.PublicKey
+	Require bool
+}
+
+// Check applies the policy to a commit and its state.
+func (p SignaturePolicy) Check(cm *CommitMeta, st *ProjectState) error {
+	if len(p.Trusted) == 0 || cm == nil {
+		return nil
+	}
+	err := remote.VerifyCommitSignature(*cm, st.ManifestHash, p.Trusted)
+	if errors.Is(err, remote.ErrCommitUnsigned) && !p.Require {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("commit %s: %w", cm.ID, err)
+	}
+	return nil
+}
+
+// GenerateSigningKey returns a new key pair, base64-encoded: the private key
+// for SigningKeyEnv, the public one for collaborators' TrustedKeysEnv.
+func GenerateSigningKey() (pub, priv string, err error) {
+	pk, sk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(pk), base64.StdEncoding.EncodeToString(sk), nil
+}
+
+// SigningKeyFromEnv decodes SigningKeyEnv; nil if unset.
+func SigningKeyFromEnv() (ed25519.PrivateKey, error) {
+	v := strings.TrimSpace(os.Getenv(SigningKeyEnv))
+	if v == "" {
+		return nil, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(b) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s: not a base64 ed25519 private key", SigningKeyEnv)
+	}
+	return ed25519.PrivateKey(b), nil
+}
+
+// SignaturePolicyFromEnv builds the policy from TrustedKeysEnv and
+// RequireSignedEnv.
+func SignaturePolicyFromEnv() (SignaturePolicy, error) {
+	var p SignaturePolicy
+	for _, s := range strings.Split(os.Getenv(TrustedKeysEnv), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return p, fmt.Errorf("%s: %q is not a base64 ed25519 public key", TrustedKeysEnv, s)
+		}
+		p.Trusted = append(p.Trusted, ed25519.PublicKey(b))
+	}
+	p.Require, _ = strconv.ParseBool(os.Getenv(RequireSignedEnv))
+	return p, nil
+}
@@ -0,0 +1,106 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"path/filepath"
+	"sort"
+)
+
+// HeadLookup fetches the remote HEAD state for a project (nil if never pushed).
+// MetaStore.GetLatestState satisfies this.
+type HeadLookup func(ctx context.Context, projectName string) (*ProjectState, error)
+
+// RemoteHead adapts the Firestore store (which also returns the commit) to HeadLookup.
+func RemoteHead(meta *remote.MetaStore) HeadLookup {
+	return func(ctx context.Context, projectName string) (*ProjectState, error) {
+		st, _, err := meta.GetLatestState(ctx, projectName)
+		return st, err
+	}
+}
+
+// ProjectStatus is one row of the root dashboard.
+type ProjectStatus struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Remote      string `json:"remote"` // bound remote project (RemoteProjectFor)
+	Added       int    `json:"added"`
+	Modified    int    `json:"modified"`
+	Deleted     int    `json:"deleted"`
+	Total       int    `json:"total"`
+	BytesToPush int64  `json:"bytesToPush"`
+	PullStatus
+	Error string `json:"error,omitempty"`
+}
+
+// ComputePullStatus compares local disk, the last-synced cache and the remote HEAD.
+// - LocalNewer: disk differs from the cache (unpushed work)
+// - RemoteNewer: remote HEAD differs from the cache (someone pushed since we synced)
+func ComputePullStatus(local, cached map[string]string, remote *ProjectState) PullStatus {
+	st := PullStatus{
+		LocalNewer: len(DiffManifests(local, cached)) > 0,
+	}
+	if remote != nil {
+		st.RemoteNewer = len(DiffManifests(ManifestFromState(*remote), cached)) > 0
+	}
+	return st
+}
+
+// RootStatus returns a status row per project under root in one pass:
+// one manifest build per project, reused for change counts, bytes-to-push
+// and the ahead/behind check. head may be nil (offline): remote fields stay empty.
+func RootStatus(ctx context.Context, root string, head HeadLookup) ([]ProjectStatus, error) {
+	projs, err := ScanProjectsCtx(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ProjectStatus, 0, len(projs))
+	for _, p := range projs {
+		if ctx.Err() != nil {
+			return out, ctx.Err()
+		}
+		pp := filepath.Join(root, p.Name)
+		row := ProjectStatus{Name: p.Name, Path: pp, Remote: RemoteProjectFor(pp, p.Name)}
+
+		ps, err := BuildManifest(pp)
+		if err != nil {
+			row.Error = err.Error()
+			out = append(out, row)
+			continue
+		}
+		cur := ManifestFromState(ps)
+		sizes := make(map[string]int64, len(ps.Files))
+		for _, f := range ps.Files {
+			sizes[normalizeKey(f.Path)] = f.Size
+		}
+
+		lc, err := LoadLocalCache(pp)
+		if err != nil {
+			row.Error = err.Error()
+			out = append(out, row)
+			continue
+		}
+
+		changes := DiffManifests(cur, lc.Hashes())
+		pc := summarizeChanges(p.Name, pp, changes)
+		row.Added, row.Modified, row.Deleted, row.Total = pc.Added, pc.Modified, pc.Deleted, pc.Total
+		for _, c := range changes {
+			if c.Type == "added" || c.Type == "modified" {
+				row.BytesToPush += sizes[c.Path]
+			}
+		}
+
+		var remote *ProjectState
+		if head != nil {
+			if remote, err = head(ctx, row.Remote); err != nil {
+				row.Error = err.Error()
+			}
+		}
+		row.PullStatus = ComputePullStatus(cur, lc.Hashes(), remote)
+		out = append(out, row)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
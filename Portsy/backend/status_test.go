@@ -0,0 +1,37 @@
+package backend
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestRootStatusUsesBoundRemote(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "Bound", "Bound.als"), "set")
+	writeTestFile(t, filepath.Join(root, "Bound", ".portsy", remoteMarkerName), "Remote Song")
+	writeTestFile(t, filepath.Join(root, "Plain", "Plain.als"), "set")
+
+	var asked []string
+	head := func(ctx context.Context, name string) (*ProjectState, error) {
+		asked = append(asked, name)
+		return nil, nil
+	}
+	rows, err := RootStatus(context.Background(), root, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"Bound": "Remote Song", "Plain": "Plain"}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for _, r := range rows {
+		if r.Remote != want[r.Name] {
+			t.Errorf("%s: Remote = %q, want %q", r.Name, r.Remote, want[r.Name])
+		}
+	}
+	if len(asked) != 2 || asked[0] != "Remote Song" || asked[1] != "Plain" {
+		t.Errorf("head looked up %v, want [Remote Song Plain]", asked)
+	}
+}
@@ -0,0 +1,236 @@
+//go:build integration
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+)
+
+// MinIO integration tests run the R2 client against a real S3 server:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	MINIO_ENDPOINT=http://localhost:9000 go test -tags integration ./backend/ -run MinIO
+//
+// MINIO_ACCESS_KEY / MINIO_SECRET_KEY default to minioadmin, MINIO_BUCKET to
+// portsy-test (created if missing). Everything is written under a fresh
+// prefix and deleted afterwards.
+const minioEndpointEnv = "MINIO_ENDPOINT"
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// newMinIOClient connects to MINIO_ENDPOINT with a 1 KiB download part size,
+// so anything bigger is fetched as several ranged GETs, and the minimum
+// 5 MiB upload part size. Skips without it.
+func newMinIOClient(t *testing.T) *R2Client {
+	t.Helper()
+	endpoint := os.Getenv(minioEndpointEnv)
+	if endpoint == "" {
+		t.Skipf("%s not set; skipping MinIO integration test", minioEndpointEnv)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	r2, err := NewR2(ctx, R2Config{
+		Endpoint:         endpoint,
+		UsePathStyle:     true,
+		AccessKey:        envOr("MINIO_ACCESS_KEY", "minioadmin"),
+		SecretKey:        envOr("MINIO_SECRET_KEY", "minioadmin"),
+		Bucket:           envOr("MINIO_BUCKET", "portsy-test"),
+		DownloadPartSize: 1 << 10,
+		UploadPartSize:   5 << 20,
+	})
+	if err != nil {
+		t.Fatalf("NewR2: %v", err)
+	}
+	if err := r2.Ping(ctx); err != nil {
+		if _, cerr := r2.client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(r2.BucketName())}); cerr != nil {
+			t.Fatalf("ping: %v; create bucket: %v", err, cerr)
+		}
+	}
+	return r2
+}
+
+func TestMinIOBlobLifecycle(t *testing.T) {
+	r2 := newMinIOClient(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Big enough for several 1 KiB download parts, not a multiple of one.
+	payload := bytes.Repeat([]byte("portsy minio "), 400)
+	local := filepath.Join(dir, "blob")
+	writeTestFile(t, local, string(payload))
+
+	prefix := path.Join("itest", uuid.NewString())
+	key, copyKey := path.Join(prefix, "blob"), path.Join(prefix, "copy")
+	t.Cleanup(func() {
+		for _, k := range []string{key, copyKey} {
+			_ = r2.Delete(context.Background(), k)
+		}
+	})
+
+	exists := func(t *testing.T, k string, want bool) {
+		t.Helper()
+		ok, err := r2.Exists(ctx, k)
+		if err != nil {
+			t.Fatalf("Exists(%s): %v", k, err)
+		}
+		if ok != want {
+			t.Fatalf("Exists(%s) = %v, want %v", k, ok, want)
+		}
+	}
+	download := func(t *testing.T, k string) []byte {
+		t.Helper()
+		dst := filepath.Join(dir, "dl")
+		if err := r2.DownloadTo(ctx, k, dst); err != nil {
+			t.Fatalf("DownloadTo(%s): %v", k, err)
+		}
+		got, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	steps := []struct {
+		name string
+		run  func(t *testing.T)
+	}{
+		{"upload", func(t *testing.T) {
+			exists(t, key, false)
+			if err := r2.UploadIfMissing(ctx, local, key); err != nil {
+				t.Fatal(err)
+			}
+			exists(t, key, true)
+		}},
+		{"upload again is a no-op", func(t *testing.T) {
+			if err := r2.UploadIfMissing(ctx, local, key); err != nil {
+				t.Fatal(err)
+			}
+			out, err := r2.UploadFileIfNoneMatch(ctx, local, key, "*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != nil {
+				t.Fatal("PUT with If-None-Match: * on an existing key succeeded; want precondition failure")
+			}
+		}},
+		{"head", func(t *testing.T) {
+			info, err := r2.Head(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info == nil || info.Size != int64(len(payload)) || info.ETag == "" {
+				t.Fatalf("Head = %+v, want size %d and an ETag", info, len(payload))
+			}
+			if info, err := r2.Head(ctx, path.Join(prefix, "never-written")); err != nil || info != nil {
+				t.Fatalf("Head(missing) = %+v, %v; want nil, nil", info, err)
+			}
+		}},
+		{"ranged download", func(t *testing.T) {
+			if got := download(t, key); !bytes.Equal(got, payload) {
+				t.Fatalf("downloaded %d bytes, want the %d uploaded", len(got), len(payload))
+			}
+		}},
+		{"range get", func(t *testing.T) {
+			tests := []struct{ from, to int }{{0, 0}, {10, 19}, {1000, 1100}, {len(payload) - 5, len(payload) - 1}}
+			for _, rg := range tests {
+				out, err := r2.client.GetObject(ctx, &s3.GetObjectInput{
+					Bucket: aws.String(r2.BucketName()),
+					Key:    aws.String(key),
+					Range:  aws.String("bytes=" + strconv.Itoa(rg.from) + "-" + strconv.Itoa(rg.to)),
+				})
+				if err != nil {
+					t.Fatalf("GetObject range %d-%d: %v", rg.from, rg.to, err)
+				}
+				got, err := io.ReadAll(out.Body)
+				out.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := payload[rg.from : rg.to+1]; !bytes.Equal(got, want) {
+					t.Errorf("range %d-%d = %q, want %q", rg.from, rg.to, got, want)
+				}
+			}
+		}},
+		{"copy", func(t *testing.T) {
+			if err := r2.CopyObject(ctx, key, copyKey); err != nil {
+				t.Fatal(err)
+			}
+			if got := download(t, copyKey); !bytes.Equal(got, payload) {
+				t.Fatal("copy has different bytes")
+			}
+			if err := r2.CopyIfMissing(ctx, key, copyKey); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"force upload goes multipart", func(t *testing.T) {
+			big := bytes.Repeat([]byte("portsy force "), 11<<20/13) // three 5 MiB parts
+			bigLocal := filepath.Join(dir, "big")
+			writeTestFile(t, bigLocal, string(big))
+			etag, err := r2.forceUpload(ctx, bigLocal, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := r2.Head(ctx, key)
+			if err != nil || info == nil {
+				t.Fatalf("Head = %+v, %v", info, err)
+			}
+			if etag != info.ETag || !strings.HasSuffix(etag, "-3") {
+				t.Errorf("forceUpload ETag = %q, Head %q; want the same 3-part multipart ETag", etag, info.ETag)
+			}
+			if got := download(t, key); !bytes.Equal(got, big) {
+				t.Fatalf("downloaded %d bytes, want the %d force-uploaded", len(got), len(big))
+			}
+		}},
+		{"delete", func(t *testing.T) {
+			for _, k := range []string{key, copyKey} {
+				if err := r2.Delete(ctx, k); err != nil {
+					t.Fatal(err)
+				}
+				exists(t, k, false)
+			}
+			if err := r2.DownloadTo(ctx, key, filepath.Join(dir, "gone")); err == nil {
+				t.Fatal("DownloadTo after delete succeeded")
+			}
+		}},
+	}
+	for _, s := range steps {
+		if !t.Run(s.name, s.run) {
+			return // later steps depend on this one
+		}
+	}
+}
+
+// TestMinIOCheckStorage runs the CLI's storage-check against the server.
+func TestMinIOCheckStorage(t *testing.T) {
+	r2 := newMinIOClient(t)
+	checks, err := CheckStorage(context.Background(), r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range checks {
+		if c.Err != "" {
+			t.Errorf("%s: %s", c.Name, c.Err)
+		}
+	}
+	if len(checks) == 0 {
+		t.Fatal("no checks ran")
+	}
+}
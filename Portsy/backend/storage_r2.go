@@ -1,18 +1,22 @@
 package backend
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
@@ -30,30 +34,96 @@ type R2Config struct {
 	Region    string // R2 uses "auto"
 	KeyPrefix string // optional prefix with bucket
 
+	// Endpoint overrides the Cloudflare endpoint derived from AccountID, for
+	// S3-compatible stores (AWS S3, Backblaze B2, MinIO), e.g.
+	// "http://localhost:9000". AccountID is then optional and Region
+	// defaults to "us-east-1".
+	Endpoint string
+	// UsePathStyle addresses buckets as <endpoint>/<bucket>/<key>. Ignored
+	// (always on) for R2; with a custom Endpoint it is off unless set, which
+	// suits AWS S3, while MinIO usually needs it on.
+	UsePathStyle bool
+
 	// Transfer tunables (sane defaults if zero)
 	UploadPartSize      int64 // bytes, e.g. 8<<20
 	UploadConcurrency   int   // e.g. 4-8
 	DownloadPartSize    int64 // bytes
 	DownloadConcurrency int   // e.g. 4-8
 
+	// Optional large-file uploader: files of at least LargeFileThreshold bytes
+	// go through a second uploader with bigger parts, while the settings above
+	// keep serving small files (MIDI, one-shots). 0 = single uploader only.
+	LargeFileThreshold     int64 // bytes, e.g. 64 MiB
+	LargeUploadPartSize    int64 // bytes, default 64 MiB
+	LargeUploadConcurrency int   // default UploadConcurrency
+
 	// Presign TTL default (used by Presign* helpers)
 	DefaultPresignTTL time.Duration
+
+	// VerifyRetries is how many extra HEADs VerifyUploaded spends on a blob
+	// that reads as missing right after its upload, with doubling backoff
+	// from 200ms. 0 = default 3, < 0 = none. See VerifyUploaded.
+	VerifyRetries int
+
+	// TempDir stages downloads (the .part files) outside the destination
+	// folder, e.g. on a drive with more room. "" = next to each file. A
+	// project's settings can override it (see ProjectSettings.TempDir).
+	TempDir string
+
+	// Optional transfer counters (bytes, HEADs, retries); nil = off.
+	Metrics Metrics
 }
 
 type R2Client struct {
-	cfg     R2Config
-	client  *s3.Client
-	upldr   *manager.Uploader
-	dl      *manager.Downloader
-	presign *s3.PresignClient
+	cfg        R2Config
+	client     *s3.Client
+	upldr      *manager.Uploader
+	upldrLarge *manager.Uploader // nil unless LargeFileThreshold > 0
+	dl         *manager.Downloader
+	presign    *s3.PresignClient
+	endpoint   string // resolved from AccountID, for diagnostics
+}
+
+// validateEndpoint accepts an absolute http(s) URL with a host and no path,
+// query or fragment.
+func validateEndpoint(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", raw, err)
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("invalid endpoint %q: scheme must be http or https", raw)
+	case u.Host == "":
+		return fmt.Errorf("invalid endpoint %q: missing host", raw)
+	case strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "":
+		return fmt.Errorf("invalid endpoint %q: must not have a path, query or fragment", raw)
+	}
+	return nil
 }
 
 func (c *R2Client) BucketName() string {
 	return c.cfg.Bucket
 }
 
+// BuildKey returns the blob key for hash under DefaultKeyScheme.
 func (r *R2Client) BuildKey(projectName, hash string) string {
-	base := path.Join(projectName, "blobs", hash)
+	return r.BuildKeyScheme(DefaultKeyScheme, projectName, hash)
+}
+
+// BuildKeyScheme returns the blob key for hash under a specific layout version.
+func (r *R2Client) BuildKeyScheme(scheme KeyScheme, projectName, hash string) string {
+	var base string
+	switch scheme.Resolve() {
+	case KeySchemeGlobalBlobs:
+		shard := hash
+		if len(shard) > 2 {
+			shard = shard[:2]
+		}
+		base = path.Join("blobs", shard, hash)
+	default:
+		base = path.Join(projectName, "blobs", hash)
+	}
 	if r.cfg.KeyPrefix != "" {
 		return path.Join(r.cfg.KeyPrefix, base)
 	}
@@ -61,26 +131,40 @@ func (r *R2Client) BuildKey(projectName, hash string) string {
 }
 
 func NewR2(ctx context.Context, cfg R2Config) (*R2Client, error) {
+	endpoint, pathStyle := fmt.Sprintf("https://%s.r2.cloudflarestorage.com", cfg.AccountID), true
+	if cfg.Endpoint != "" {
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return nil, err
+		}
+		endpoint, pathStyle = strings.TrimSuffix(cfg.Endpoint, "/"), cfg.UsePathStyle
+		if cfg.Region == "" {
+			cfg.Region = "us-east-1"
+		}
+	}
 	if cfg.Region == "" {
 		cfg.Region = "auto"
 	}
-	if cfg.Bucket == "" || cfg.AccountID == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+	if cfg.Bucket == "" || (cfg.AccountID == "" && cfg.Endpoint == "") || cfg.AccessKey == "" || cfg.SecretKey == "" {
 		return nil, fmt.Errorf("missing required R2 config fields")
 	}
-	endpoint := fmt.Sprintf("https://%s.r2.cloudflarestorage.com", cfg.AccountID)
 
-	awsCfg, err := config.LoadDefaultConfig(
-		ctx,
+	loadOpts := []func(*config.LoadOptions) error{
 		config.WithRegion(cfg.Region),
 		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")),
-	)
+	}
+	if cfg.Metrics != nil {
+		loadOpts = append(loadOpts, config.WithRetryer(func() aws.Retryer {
+			return countingRetryer{RetryerV2: retry.NewStandard(), metrics: cfg.Metrics}
+		}))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("load aws cfg: %w", err)
 	}
 
 	s3c := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		o.BaseEndpoint = aws.String(endpoint) // R2 endpoint
-		o.UsePathStyle = true                 // R2 requires path-style
+		o.BaseEndpoint = aws.String(endpoint) // R2 (or custom S3-compatible) endpoint
+		o.UsePathStyle = pathStyle            // R2 requires path-style
 		// Keep default retryer; R2 behaves like S3 for idempotent ops.
 	})
 
@@ -105,6 +189,21 @@ func NewR2(ctx context.Context, cfg R2Config) (*R2Client, error) {
 		u.PartSize = upPart
 		u.Concurrency = upConc
 	})
+	var upldrLarge *manager.Uploader
+	if cfg.LargeFileThreshold > 0 {
+		largePart := cfg.LargeUploadPartSize
+		if largePart <= 0 {
+			largePart = 64 << 20
+		}
+		largeConc := cfg.LargeUploadConcurrency
+		if largeConc <= 0 {
+			largeConc = upConc
+		}
+		upldrLarge = manager.NewUploader(s3c, func(u *manager.Uploader) {
+			u.PartSize = largePart
+			u.Concurrency = largeConc
+		})
+	}
 	dl := manager.NewDownloader(s3c, func(d *manager.Downloader) {
 		d.PartSize = downPart
 		d.Concurrency = downConc
@@ -115,13 +214,18 @@ func NewR2(ctx context.Context, cfg R2Config) (*R2Client, error) {
 	if cfg.DefaultPresignTTL <= 0 {
 		cfg.DefaultPresignTTL = 15 * time.Minute
 	}
+	if cfg.VerifyRetries == 0 {
+		cfg.VerifyRetries = defaultVerifyRetries
+	}
 
 	return &R2Client{
-		cfg:     cfg,
-		client:  s3c,
-		upldr:   upldr,
-		dl:      dl,
-		presign: presigner,
+		cfg:        cfg,
+		client:     s3c,
+		upldr:      upldr,
+		upldrLarge: upldrLarge,
+		dl:         dl,
+		presign:    presigner,
+		endpoint:   endpoint,
 	}, nil
 }
 
@@ -148,39 +252,108 @@ func WithMetadata(kv map[string]string) UploadOpt {
 
 // UploadFile uploads the file at localPath to key. Returns key on success.
 func (r *R2Client) UploadFile(ctx context.Context, localPath, key string, opts ...UploadOpt) (string, error) {
-	f, err := os.Open(localPath)
+	f, err := os.Open(longPath(localPath))
 	if err != nil {
 		return "", fmt.Errorf("open upload file: %w", err)
 	}
 	defer f.Close()
-	return r.uploadReader(ctx, f, key, opts...)
+	var size int64 = -1
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	return r.uploadReaderSized(ctx, f, size, key, opts...)
+}
+
+// uploaderFor picks the uploader tuned for a body of size bytes (-1 = unknown).
+func (r *R2Client) uploaderFor(size int64) *manager.Uploader {
+	if r.upldrLarge != nil && size >= r.cfg.LargeFileThreshold {
+		return r.upldrLarge
+	}
+	return r.upldr
 }
 
 func (r *R2Client) DownloadTo(ctx context.Context, key, dstPath string) error {
+	return r.downloadToIn(ctx, key, dstPath, r.cfg.TempDir)
+}
+
+// downloadToIn is DownloadTo staging in tempDir (see writeFileAtomicIn).
+func (r *R2Client) downloadToIn(ctx context.Context, key, dstPath, tempDir string) error {
+	return writeFileAtomicIn(tempDir, dstPath, func(tf *os.File) error {
+		n, err := r.dl.Download(ctx, tf, &s3.GetObjectInput{
+			Bucket: aws.String(r.cfg.Bucket),
+			Key:    aws.String(key),
+		})
+		r.count(MetricBytesDownloaded, n)
+		if err != nil {
+			if notFound(err) {
+				return fmt.Errorf("r2 key not found: %s", key)
+			}
+			return fmt.Errorf("download key=%s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// DownloadURLTo fetches a (presigned) URL with a plain GET, bypassing the SDK
+// downloader. Same .part -> fsync -> rename semantics as DownloadTo.
+func (r *R2Client) DownloadURLTo(ctx context.Context, url, dstPath string) error {
+	return writeFileAtomicIn(r.cfg.TempDir, dstPath, func(tf *os.File) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("get url: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("get url: unexpected status %s", resp.Status)
+		}
+		n, err := io.Copy(tf, resp.Body)
+		r.count(MetricBytesDownloaded, n)
+		if err != nil {
+			return fmt.Errorf("get url: %w", err)
+		}
+		return nil
+	})
+}
+
+// writeFileAtomic runs fill against dstPath+".part", then fsyncs and renames
+// it over dstPath. The temp file is removed on any failure.
+func writeFileAtomic(dstPath string, fill func(*os.File) error) error {
+	return writeFileAtomicIn("", dstPath, fill)
+}
+
+// writeFileAtomicIn is writeFileAtomic with the temp file in tempDir ("" =
+// next to dstPath); moveFile covers a tempDir on another volume.
+func writeFileAtomicIn(tempDir, dstPath string, fill func(*os.File) error) error {
+	dstPath = longPath(dstPath)
 	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
 		return fmt.Errorf("ensure parent dir: %w", err)
 	}
 
-	tmp := dstPath + ".part"
-	tf, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	var (
+		tf  *os.File
+		err error
+	)
+	if tempDir == "" {
+		tf, err = os.OpenFile(dstPath+".part", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	} else if err = os.MkdirAll(tempDir, 0o755); err == nil {
+		tf, err = os.CreateTemp(tempDir, filepath.Base(dstPath)+".*.part")
+	}
 	if err != nil {
 		return fmt.Errorf("create temp: %w", err)
 	}
+	tmp := tf.Name()
 	// Ensure cleanup on failure
 	defer func() {
 		_ = tf.Close()
 		_ = os.Remove(tmp)
 	}()
 
-	_, err = r.dl.Download(ctx, tf, &s3.GetObjectInput{
-		Bucket: aws.String(r.cfg.Bucket),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		if notFound(err) {
-			return fmt.Errorf("r2 key not found: %s", key)
-		}
-		return fmt.Errorf("download key=%s: %w", key, err)
+	if err := fill(tf); err != nil {
+		return err
 	}
 	// Flush file to disk before rename (important on Windows)
 	if err := tf.Sync(); err != nil {
@@ -189,7 +362,7 @@ func (r *R2Client) DownloadTo(ctx context.Context, key, dstPath string) error {
 	if err := tf.Close(); err != nil {
 		return fmt.Errorf("close temp: %w", err)
 	}
-	if err := os.Rename(tmp, dstPath); err != nil {
+	if err := moveFile(tmp, dstPath); err != nil {
 		return fmt.Errorf("rename temp: %w", err)
 	}
 	// Best-effort: fsync parent dir to persist rename
@@ -200,7 +373,50 @@ func (r *R2Client) DownloadTo(ctx context.Context, key, dstPath string) error {
 	return nil
 }
 
+// OpenReader streams an object's body (caller must Close). Useful when the
+// destination isn't a file, e.g. writing straight into an archive.
+func (r *R2Client) OpenReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.cfg.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if notFound(err) {
+			return nil, fmt.Errorf("r2 key not found: %s", key)
+		}
+		return nil, fmt.Errorf("get key=%s: %w", key, err)
+	}
+	return out.Body, nil
+}
+
+// PutManifest stores a gzipped state manifest (remote.StateStorageR2).
+// The key is relative to KeyPrefix, like the blob keys.
+func (r *R2Client) PutManifest(ctx context.Context, key string, data []byte) error {
+	_, err := r.uploadReaderSized(ctx, bytes.NewReader(data), int64(len(data)), r.prefixed(key), WithContentType("application/gzip"))
+	return err
+}
+
+// GetManifest reads back a manifest written by PutManifest.
+func (r *R2Client) GetManifest(ctx context.Context, key string) ([]byte, error) {
+	rc, err := r.OpenReader(ctx, r.prefixed(key))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	r.count(MetricBytesDownloaded, int64(len(data)))
+	return data, err
+}
+
+func (r *R2Client) prefixed(key string) string {
+	if r.cfg.KeyPrefix != "" {
+		return path.Join(r.cfg.KeyPrefix, key)
+	}
+	return key
+}
+
 func (r *R2Client) Exists(ctx context.Context, key string) (bool, error) {
+	r.count(MetricObjectsHeaded, 1)
 	_, err := r.client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(r.cfg.Bucket),
 		Key:    aws.String(key),
@@ -214,6 +430,57 @@ func (r *R2Client) Exists(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// defaultVerifyRetries and verifyBackoff bound VerifyUploaded: 200+400+800ms.
+const (
+	defaultVerifyRetries = 3
+	verifyBackoff        = 200 * time.Millisecond
+)
+
+// ErrBlobMissing means a blob is still absent after VerifyUploaded's retries.
+var ErrBlobMissing = errors.New("blob missing")
+
+// VerifyUploaded HEADs a key that was just written, retrying a "not found"
+// up to R2Config.VerifyRetries times with backoff before reporting
+// ErrBlobMissing. Other HEAD errors are returned at once.
+//
+// R2 itself documents strong read-after-write consistency: a HEAD after a
+// successful PUT sees the object. The retries are for S3-compatible stores
+// (R2Config.Endpoint) and caching proxies that can lag, so a finalize under
+// load doesn't fail on a blob that is about to appear. A blob that was never
+// uploaded costs the full backoff (~1.4s by default) before failing.
+func (r *R2Client) VerifyUploaded(ctx context.Context, key string) error {
+	wait := verifyBackoff
+	for attempt := 0; ; attempt++ {
+		ok, err := r.Exists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if attempt > 0 {
+				debugf("verify %s: visible after %d retries", key, attempt)
+			}
+			return nil
+		}
+		if attempt >= r.cfg.VerifyRetries {
+			return fmt.Errorf("%w: %s (%d HEAD(s))", ErrBlobMissing, key, attempt+1)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// BlobVerifier adapts VerifyUploaded to MetaStore.FinalizeCommit's
+// verify(ctx, hash) callback for a project's key scheme.
+func (r *R2Client) BlobVerifier(scheme KeyScheme, projectName string) func(context.Context, string) error {
+	return func(ctx context.Context, hash string) error {
+		return r.VerifyUploaded(ctx, r.BuildKeyScheme(scheme, projectName, hash))
+	}
+}
+
 func (r *R2Client) Delete(ctx context.Context, key string) error {
 	_, err := r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(r.cfg.Bucket),
@@ -225,6 +492,116 @@ func (r *R2Client) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// KeyFor resolves where a file's blob lives: the recorded R2Key when present,
+// otherwise the key derived from the scheme the state was written with.
+func (r *R2Client) KeyFor(st *ProjectState, projectName string, fe FileEntry) string {
+	if fe.R2Key != "" {
+		return fe.R2Key
+	}
+	return r.BuildKeyScheme(st.KeyScheme, projectName, fe.Hash)
+}
+
+// ProjectBlobPrefix is the key prefix (with trailing slash) holding a
+// project's KeySchemeProjectBlobs blobs.
+func (r *R2Client) ProjectBlobPrefix(projectName string) string {
+	return r.prefixed(path.Join(projectName, "blobs")) + "/"
+}
+
+// ListKeys returns every key under prefix, paging through ListObjectsV2.
+func (r *R2Client) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	objs, err := r.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(objs))
+	for i, o := range objs {
+		keys[i] = o.Key
+	}
+	return keys, nil
+}
+
+// ListPrefixes returns the "folders" directly under prefix (which should end
+// in "/" or be ""), each with its trailing "/", paging through ListObjectsV2
+// with a "/" delimiter.
+func (r *R2Client) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	p := s3.NewListObjectsV2Paginator(r.client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(r.cfg.Bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	var out []string
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list prefixes prefix=%s: %w", prefix, err)
+		}
+		for _, cp := range page.CommonPrefixes {
+			out = append(out, aws.ToString(cp.Prefix))
+		}
+	}
+	return out, nil
+}
+
+// hasAnyUnder reports whether at least one object exists under prefix.
+func (r *R2Client) hasAnyUnder(ctx context.Context, prefix string) (bool, error) {
+	out, err := r.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(r.cfg.Bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return false, fmt.Errorf("list prefix=%s: %w", prefix, err)
+	}
+	return len(out.Contents) > 0, nil
+}
+
+// StoredObject is one ListObjects entry.
+type StoredObject struct {
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// ListObjects is ListKeys with each object's size and last-modified time.
+func (r *R2Client) ListObjects(ctx context.Context, prefix string) ([]StoredObject, error) {
+	var out []StoredObject
+	err := r.WalkObjects(ctx, prefix, func(o StoredObject) error {
+		out = append(out, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WalkObjects calls fn for every object under prefix, a page at a time,
+// without holding the whole listing in memory. An error from fn stops the
+// walk and is returned as is.
+func (r *R2Client) WalkObjects(ctx context.Context, prefix string, fn func(StoredObject) error) error {
+	p := s3.NewListObjectsV2Paginator(r.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.cfg.Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list prefix=%s: %w", prefix, err)
+		}
+		for _, o := range page.Contents {
+			err := fn(StoredObject{
+				Key:      aws.ToString(o.Key),
+				Size:     aws.ToInt64(o.Size),
+				Modified: aws.ToTime(o.LastModified),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // BuildR2Key is a legacy helper retained for compatibility.
 // Prefer R2Client.BuildKey which respects KeyPrefix.
 func BuildR2Key(projectName, relPath, hash string) string {
@@ -238,6 +615,10 @@ func (r *R2Client) UploadReader(ctx context.Context, rd io.Reader, key string, o
 }
 
 func (r *R2Client) uploadReader(ctx context.Context, rd io.Reader, key string, opts ...UploadOpt) (string, error) {
+	return r.uploadReaderSized(ctx, rd, -1, key, opts...)
+}
+
+func (r *R2Client) uploadReaderSized(ctx context.Context, rd io.Reader, size int64, key string, opts ...UploadOpt) (string, error) {
 	in := &s3.PutObjectInput{
 		Bucket: aws.String(r.cfg.Bucket),
 		Key:    aws.String(key),
@@ -246,10 +627,13 @@ func (r *R2Client) uploadReader(ctx context.Context, rd io.Reader, key string, o
 	for _, o := range opts {
 		o(in)
 	}
-	_, err := r.upldr.Upload(ctx, in)
+	_, err := r.uploaderFor(size).Upload(ctx, in)
 	if err != nil {
 		return "", fmt.Errorf("upload to r2 key=%s: %w", key, err)
 	}
+	if size > 0 {
+		r.count(MetricBytesUploaded, size)
+	}
 	return key, nil
 }
 
@@ -269,6 +653,29 @@ func (r *R2Client) PresignGet(ctx context.Context, key string, ttl ...time.Durat
 	return out.URL, nil
 }
 
+// PresignGetForPreview is PresignGet with the response served inline as
+// contentType ("" = application/octet-stream), so a browser streams it (e.g.
+// in an <audio> element) instead of downloading it.
+func (r *R2Client) PresignGetForPreview(ctx context.Context, key, contentType string, ttl ...time.Duration) (string, error) {
+	expires := r.cfg.DefaultPresignTTL
+	if len(ttl) > 0 && ttl[0] > 0 {
+		expires = ttl[0]
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	out, err := r.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:                     aws.String(r.cfg.Bucket),
+		Key:                        aws.String(key),
+		ResponseContentType:        aws.String(contentType),
+		ResponseContentDisposition: aws.String("inline"),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", fmt.Errorf("presign preview key=%s: %w", key, err)
+	}
+	return out.URL, nil
+}
+
 func (r *R2Client) PresignPut(ctx context.Context, key string, ttl ...time.Duration) (string, http.Header, error) {
 	expires := r.cfg.DefaultPresignTTL
 	if len(ttl) > 0 && ttl[0] > 0 {
@@ -284,8 +691,80 @@ func (r *R2Client) PresignPut(ctx context.Context, key string, ttl ...time.Durat
 	return out.URL, out.SignedHeader, nil
 }
 
+// Ping failure kinds; the returned error wraps one of these and names the
+// bucket and resolved endpoint.
+var (
+	ErrR2Auth           = errors.New("R2 access key is invalid or lacks permissions")
+	ErrR2BucketNotFound = errors.New("R2 bucket not found")
+	ErrR2Endpoint       = errors.New("R2 endpoint unreachable (check R2_ACCOUNT_ID)")
+	ErrR2Region         = errors.New("R2 rejected the region (use \"auto\")")
+)
+
+// Ping does a cheap HeadBucket so a bad key, a mistyped R2_BUCKET or a wrong
+// account ID fails at startup with a clear message instead of as 403s/404s
+// deep inside the first push.
+func (r *R2Client) Ping(ctx context.Context) error {
+	_, err := r.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(r.cfg.Bucket)})
+	if err == nil {
+		return nil
+	}
+	var kind error
+	switch {
+	case isEndpointError(err):
+		kind = ErrR2Endpoint
+	case isRegionError(err):
+		kind = ErrR2Region
+	case isAuthError(err):
+		kind = ErrR2Auth
+	case notFound(err) || isErrorCode(err, "NoSuchBucket"):
+		kind = ErrR2BucketNotFound
+	default:
+		return fmt.Errorf("r2 ping bucket=%s endpoint=%s: %w", r.cfg.Bucket, r.endpoint, err)
+	}
+	return fmt.Errorf("%w (bucket=%s endpoint=%s region=%s): %v", kind, r.cfg.Bucket, r.endpoint, r.cfg.Region, err)
+}
+
 // --- internal helpers ---
 
+func isAuthError(err error) bool {
+	var re *smithyhttp.ResponseError
+	if errors.As(err, &re) {
+		if code := re.HTTPStatusCode(); code == http.StatusUnauthorized || code == http.StatusForbidden {
+			return true
+		}
+	}
+	return isErrorCode(err, "InvalidAccessKeyId", "SignatureDoesNotMatch", "AccessDenied", "Forbidden", "Unauthorized")
+}
+
+func isRegionError(err error) bool {
+	return isErrorCode(err, "InvalidRegionName", "AuthorizationHeaderMalformed", "PermanentRedirect")
+}
+
+// isEndpointError reports transport failures before any HTTP response:
+// DNS, refused connections, TLS (typically a mistyped account ID).
+func isEndpointError(err error) bool {
+	var re *smithyhttp.ResponseError
+	if errors.As(err, &re) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || errors.As(err, &opErr)
+}
+
+func isErrorCode(err error, codes ...string) bool {
+	var api smithy.APIError
+	if !errors.As(err, &api) {
+		return false
+	}
+	for _, c := range codes {
+		if api.ErrorCode() == c {
+			return true
+		}
+	}
+	return false
+}
+
 func notFound(err error) bool {
 	// Smithy API error with HTTP status
 	var api smithy.APIError
@@ -307,12 +786,32 @@ func notFound(err error) bool {
 }
 
 func (c *R2Client) UploadFileIfNoneMatch(ctx context.Context, localPath, key, ifNoneMatch string) (*s3.PutObjectOutput, error) {
-	f, err := os.Open(localPath)
+	f, err := os.Open(longPath(localPath))
 	if err != nil {
 		return nil, fmt.Errorf("open %s: %w", localPath, err)
 	}
 	defer f.Close()
 
+	// Large blobs go multipart through the large-file uploader. Multipart has
+	// no If-None-Match, but the key is content-addressed, so a racing writer
+	// stores the same bytes.
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	if c.upldrLarge != nil && size >= c.cfg.LargeFileThreshold {
+		up, err := c.upldrLarge.Upload(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(c.BucketName()),
+			Key:    aws.String(key),
+			Body:   f,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("upload to r2 key=%s: %w", key, err)
+		}
+		c.count(MetricBytesUploaded, size)
+		return &s3.PutObjectOutput{ETag: up.ETag}, nil
+	}
+
 	in := &s3.PutObjectInput{
 		Bucket:      aws.String(c.BucketName()), // <- use exported field
 		Key:         aws.String(key),
@@ -324,6 +823,9 @@ func (c *R2Client) UploadFileIfNoneMatch(ctx context.Context, localPath, key, if
 		// someone else already put it; that's success for idempotent push
 		return nil, nil
 	}
+	if err == nil {
+		c.count(MetricBytesUploaded, size)
+	}
 	return out, err
 }
 
@@ -351,17 +853,95 @@ func (c *R2Client) CopyObject(ctx context.Context, fromKey, toKey string) error
 
 // UploadIfMissing remains the convenience wrapper your sync.go expects.
 func (c *R2Client) UploadIfMissing(ctx context.Context, local, key string) error {
-	exists, err := c.Exists(ctx, key)
-	if err == nil && exists {
-		return nil
+	_, err := c.UploadIfMissingETag(ctx, local, key)
+	return err
+}
+
+// UploadIfMissingETag is UploadIfMissing that also reports the object's ETag,
+// from the PUT response or (when the blob already existed) a HEAD.
+func (c *R2Client) UploadIfMissingETag(ctx context.Context, local, key string) (string, error) {
+	if info, err := c.Head(ctx, key); err == nil && info != nil {
+		return info.ETag, nil
 	}
-	_, err = c.UploadFileIfNoneMatch(ctx, local, key, "*")
+	out, err := c.UploadFileIfNoneMatch(ctx, local, key, "*")
 	if isPreconditionFailed(err) {
-		return nil
+		err = nil
+	}
+	if err != nil {
+		return "", err
 	}
+	if out == nil {
+		// lost the race to another writer; ask R2 what it stored
+		info, err := c.Head(ctx, key)
+		if err != nil || info == nil {
+			return "", err
+		}
+		return info.ETag, nil
+	}
+	return trimETag(out.ETag), nil
+}
+
+// BlobInfo is the HeadObject view of a blob.
+type BlobInfo struct {
+	Size int64
+	ETag string // unquoted
+}
+
+// Head returns size/ETag for key, or (nil, nil) if it doesn't exist.
+func (c *R2Client) Head(ctx context.Context, key string) (*BlobInfo, error) {
+	c.count(MetricObjectsHeaded, 1)
+	out, err := c.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(c.BucketName()),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if notFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("head key=%s: %w", key, err)
+	}
+	return &BlobInfo{Size: aws.ToInt64(out.ContentLength), ETag: trimETag(out.ETag)}, nil
+}
+
+func trimETag(etag *string) string {
+	return strings.Trim(aws.ToString(etag), `"`)
+}
+
+// ForceUpload overwrites key with localPath unconditionally. This deliberately
+// bypasses the content-addressed "exists means correct" shortcut of
+// UploadIfMissing: it is the repair path for a blob whose bytes went bad
+// under a correct-looking key. Don't use it for regular pushes.
+func (c *R2Client) ForceUpload(ctx context.Context, localPath, key string) error {
+	_, err := c.forceUpload(ctx, localPath, key)
 	return err
 }
 
+func (c *R2Client) forceUpload(ctx context.Context, localPath, key string) (string, error) {
+	f, err := os.Open(longPath(localPath))
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", localPath, err)
+	}
+	defer f.Close()
+
+	// Same uploader as a regular push, so a large blob goes multipart.
+	var size int64 = -1
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	out, err := c.uploaderFor(size).Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(c.BucketName()),
+		Key:    aws.String(key),
+		Body:   f,
+	})
+	if err != nil {
+		return "", fmt.Errorf("force upload key=%s: %w", key, err)
+	}
+	if size > 0 {
+		c.count(MetricBytesUploaded, size)
+	}
+	return trimETag(out.ETag), nil
+}
+
 func (c *R2Client) CopyIfMissing(ctx context.Context, fromKey, toKey string) error {
 	if fromKey == toKey {
 		return nil
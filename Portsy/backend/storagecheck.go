@@ -0,0 +1,180 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// StorageCheck is the outcome of one CheckStorage step.
+type StorageCheck struct {
+	Name string `json:"name"`
+	Err  string `json:"error,omitempty"`
+}
+
+// CheckStorage exercises the R2 client end to end against whatever store it
+// points at: upload-if-missing (twice), the If-None-Match precondition,
+// HEAD, download, server-side copy, presigned PUT/GET and delete. Everything
+// lives under selftest/<uuid>/ and is deleted afterwards. Meant for a
+// throwaway bucket, e.g. a local MinIO via R2Config.Endpoint; nothing runs
+// it unless asked (CLI -mode=storage-check).
+func CheckStorage(ctx context.Context, r2 *R2Client) ([]StorageCheck, error) {
+	dir, err := os.MkdirTemp("", "portsy-storage-check-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	payload := []byte("portsy storage check " + uuid.NewString())
+	local := filepath.Join(dir, "blob")
+	if err := os.WriteFile(local, payload, 0o644); err != nil {
+		return nil, err
+	}
+	prefix := path.Join("selftest", uuid.NewString())
+	key, copyKey, presKey := path.Join(prefix, "blob"), path.Join(prefix, "copy"), path.Join(prefix, "presigned")
+	defer func() {
+		for _, k := range []string{key, copyKey, presKey} {
+			_ = r2.Delete(context.WithoutCancel(ctx), k)
+		}
+	}()
+
+	sameBytes := func(k string) error {
+		dst := filepath.Join(dir, "dl")
+		if err := r2.DownloadTo(ctx, k, dst); err != nil {
+			return err
+		}
+		got, err := os.ReadFile(dst)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, payload) {
+			return fmt.Errorf("downloaded %d bytes, want %d matching bytes", len(got), len(payload))
+		}
+		return nil
+	}
+	exists := func(k string, want bool) error {
+		ok, err := r2.Exists(ctx, k)
+		if err != nil {
+			return err
+		}
+		if ok != want {
+			return fmt.Errorf("exists(%s) = %v, want %v", k, ok, want)
+		}
+		return nil
+	}
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"upload if missing", func() error { return r2.UploadIfMissing(ctx, local, key) }},
+		{"exists after upload", func() error { return exists(key, true) }},
+		{"upload if missing again (idempotent)", func() error { return r2.UploadIfMissing(ctx, local, key) }},
+		{"if-none-match on existing key", func() error {
+			out, err := r2.UploadFileIfNoneMatch(ctx, local, key, "*")
+			if err != nil {
+				return err
+			}
+			if out != nil {
+				return errors.New("store accepted the PUT; If-None-Match is not enforced")
+			}
+			return nil
+		}},
+		{"head size", func() error {
+			info, err := r2.Head(ctx, key)
+			if err != nil {
+				return err
+			}
+			if info == nil || info.Size != int64(len(payload)) {
+				return fmt.Errorf("head = %+v, want size %d", info, len(payload))
+			}
+			return nil
+		}},
+		{"download", func() error { return sameBytes(key) }},
+		{"copy object", func() error {
+			if err := r2.CopyObject(ctx, key, copyKey); err != nil {
+				return err
+			}
+			return sameBytes(copyKey)
+		}},
+		{"copy if missing (no-op)", func() error { return r2.CopyIfMissing(ctx, key, copyKey) }},
+		{"presigned put", func() error {
+			url, hdr, err := r2.PresignPut(ctx, presKey)
+			if err != nil {
+				return err
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(payload))
+			if err != nil {
+				return err
+			}
+			for k, vs := range hdr {
+				for _, v := range vs {
+					req.Header.Add(k, v)
+				}
+			}
+			return doHTTP(req, nil)
+		}},
+		{"presigned get", func() error {
+			url, err := r2.PresignGet(ctx, presKey)
+			if err != nil {
+				return err
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			var got bytes.Buffer
+			if err := doHTTP(req, &got); err != nil {
+				return err
+			}
+			if !bytes.Equal(got.Bytes(), payload) {
+				return errors.New("presigned GET returned different bytes")
+			}
+			return nil
+		}},
+		{"delete", func() error {
+			if err := r2.Delete(ctx, key); err != nil {
+				return err
+			}
+			return exists(key, false)
+		}},
+		{"exists on missing key", func() error { return exists(path.Join(prefix, "never-written"), false) }},
+	}
+
+	var out []StorageCheck
+	for _, s := range steps {
+		c := StorageCheck{Name: s.name}
+		if err := s.run(); err != nil {
+			c.Err = err.Error()
+		}
+		out = append(out, c)
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// doHTTP runs req and, if body is non-nil, copies a 2xx response into it.
+func doHTTP(req *http.Request, body io.Writer) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if body != nil {
+		_, err = io.Copy(body, resp.Body)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxFileSize is the single-file size above which push and doctor
+// flag a file as a likely accident (stray render, plugin temp dump).
+const DefaultMaxFileSize int64 = 2 << 30
+
+// tempFilePatterns are base-name globs of files that are almost never meant to
+// be versioned: editor/plugin temp files, partial downloads, crash dumps.
+var tempFilePatterns = []string{
+	"*.tmp", "*.temp", "*.part", "*.crdownload", "*.partial",
+	"*.dmp", "*.swp", "~$*", "*~",
+}
+
+// Suspect file reasons.
+const (
+	SuspectHuge = "huge"
+	SuspectTemp = "temp"
+)
+
+// SuspectFile is a tracked file that looks accidentally included.
+type SuspectFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+
+	// IgnorePattern is a .portsyignore line that would exclude the file for
+	// good: the matching temp glob, or the root-anchored path for huge files.
+	IgnorePattern string `json:"ignorePattern"`
+}
+
+// ErrSuspectFiles is returned by PushProjectWithOptions when
+// PushOptions.ConfirmSuspect declined the flagged files.
+var ErrSuspectFiles = errors.New("push cancelled: suspicious files in project")
+
+// FindSuspectFiles flags entries above maxSize (0 = DefaultMaxFileSize,
+// < 0 = no size check) or matching a known temp-file pattern. Sorted by size,
+// largest first, since those are what blow up a push.
+func FindSuspectFiles(files []FileEntry, maxSize int64) []SuspectFile {
+	if maxSize == 0 {
+		maxSize = DefaultMaxFileSize
+	}
+	var out []SuspectFile
+	for _, f := range files {
+		if s, ok := suspectFile(f.Path, f.Size, maxSize); ok {
+			out = append(out, s)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Size > out[j].Size })
+	return out
+}
+
+// ScanSuspectFiles is FindSuspectFiles straight from disk (no hashing), for
+// checks like doctor that don't need a manifest.
+func ScanSuspectFiles(projectPath string, maxSize int64) ([]SuspectFile, error) {
+	found, _, err := walkManifestFiles(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]FileEntry, 0, len(found))
+	for _, mf := range found {
+		files = append(files, FileEntry{Path: mf.rel, Size: mf.size})
+	}
+	return FindSuspectFiles(files, maxSize), nil
+}
+
+func suspectFile(rel string, size, maxSize int64) (SuspectFile, bool) {
+	base := strings.ToLower(path.Base(rel))
+	for _, pat := range tempFilePatterns {
+		if ok, _ := path.Match(pat, base); ok {
+			return SuspectFile{
+				Path: rel, Size: size, Reason: SuspectTemp,
+				Detail:        fmt.Sprintf("looks like a temporary file (%s)", pat),
+				IgnorePattern: pat,
+			}, true
+		}
+	}
+	if maxSize > 0 && size > maxSize {
+		return SuspectFile{
+			Path: rel, Size: size, Reason: SuspectHuge,
+			Detail:        fmt.Sprintf("%s is larger than the %s limit", humanBytes(size), humanBytes(maxSize)),
+			IgnorePattern: "/" + rel,
+		}, true
+	}
+	return SuspectFile{}, false
+}
+
+// HumanBytes formats a byte count with binary units ("1.5 GiB").
+func HumanBytes(n int64) string { return humanBytes(n) }
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -1,6 +1,8 @@
 package backend
 
 import (
+	"Portsy/backend/internal/als"
+	"Portsy/backend/internal/audio"
 	corehash "Portsy/backend/internal/core/hash"
 	remote "Portsy/backend/remote"
 	"context"
@@ -10,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 )
@@ -19,87 +22,300 @@ import (
 // - Algo-aware (hash already inside manifest entries)
 // - Key migration prefers server-side copy
 func PushProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project AbletonProject, commit CommitMeta) error {
+	return PushProjectWithOptions(ctx, meta, r2, project, commit, PushOptions{})
+}
+
+// PushOptions tunes PushProjectWithOptions; the zero value matches PushProject.
+type PushOptions struct {
+	// ForceKeys lists blob keys to re-upload with R2Client.ForceUpload even if
+	// they already exist (repair push after VerifyCommit flagged them).
+	ForceKeys []string
+
+	// FailOnSkipped aborts the push when the manifest had to leave files out
+	// (unreadable/locked). Otherwise they are logged and the push continues.
+	FailOnSkipped bool
+
+	// AudioFingerprint records an audio-content hash for WAV/AIFF files and,
+	// when a file's bytes changed but its audio did not (re-bounce with a new
+	// header/timestamp), points the entry at the previous render's blob
+	// instead of uploading. The entry keeps the new render's hash and size;
+	// pull and verify accept the older bytes by their audio fingerprint.
+	AudioFingerprint bool
+
+	// GeneratePeaks stores waveform peaks (see GeneratePeaks, PeaksKey) for
+	// newly uploaded WAV/AIFF files once the commit is in. It costs a decode
+	// of each new audio file; failures are logged and don't fail the push.
+	GeneratePeaks bool
+
+	// MaxFileSize flags files above this size as suspicious (0 =
+	// DefaultMaxFileSize, < 0 = off); known temp-file patterns are always
+	// flagged. ConfirmSuspect is asked before any upload starts and cancels
+	// the push with ErrSuspectFiles by returning false. Without it the
+	// flagged files are only logged.
+	MaxFileSize    int64
+	ConfirmSuspect func([]SuspectFile) bool
+
+	// MaxInFlightBytes caps the combined size of large uploads running at
+	// once (0 = no cap), so several huge stems don't all buffer multipart
+	// uploads together. Files under 8 MiB and server-side copies don't count.
+	MaxInFlightBytes int64
+
+	// FirstPushMaxFiles/FirstPushMaxBytes bound a project's first push (0 =
+	// DefaultFirstPushMaxFiles/DefaultFirstPushMaxBytes, < 0 = no limit).
+	// A first push over either limit, or without a top-level .als, goes
+	// ahead only if ConfirmFirstPush returns true; nil refuses with
+	// ErrFirstPushUnconfirmed, since nothing on a cold bucket dedups it.
+	FirstPushMaxFiles int
+	FirstPushMaxBytes int64
+	ConfirmFirstPush  func(FirstPushCheck) bool
+
+	// VerifyAfter re-reads the finished commit and HEADs every blob it
+	// references (VerifyCommit's fast pass), failing the push with
+	// ErrPushVerifyFailed if any is missing or the wrong size. Costs a round
+	// trip per blob, so it's off by default. OnVerified, if set, receives the
+	// report either way.
+	VerifyAfter bool
+	OnVerified  func(*VerifyReport)
+}
+
+// ErrFilesSkipped is returned by PushProjectWithOptions with FailOnSkipped set.
+var ErrFilesSkipped = errors.New("files could not be read")
+
+// PushProjectWithOptions is PushProject with tunables (see PushOptions).
+func PushProjectWithOptions(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project AbletonProject, commit CommitMeta, opts PushOptions) error {
+	force := make(map[string]bool, len(opts.ForceKeys))
+	for _, k := range opts.ForceKeys {
+		force[k] = true
+	}
+
+	if _, err := MigrateLegacyCache(project.Path); err != nil {
+		log.Printf("push %s: %v", project.Name, err)
+	}
+
 	// 0) Build manifest (must already include Algo + per-file Hash)
-	cur, err := BuildManifest(project.Path)
+	res, err := BuildManifestDetailed(project.Path)
 	if err != nil {
 		return err
 	}
+	for _, sk := range res.Skipped {
+		log.Printf("push %s: skipped %s: %s", project.Name, sk.Path, sk.Reason)
+	}
+	if len(res.Skipped) > 0 && opts.FailOnSkipped {
+		return fmt.Errorf("push %s: %d %w (first: %s)", project.Name, len(res.Skipped), ErrFilesSkipped, res.Skipped[0].Path)
+	}
+	cur := res.State
+	if suspects := FindSuspectFiles(cur.Files, opts.MaxFileSize); len(suspects) > 0 {
+		for _, sf := range suspects {
+			log.Printf("push %s: ⚠ %s: %s", project.Name, sf.Path, sf.Detail)
+		}
+		if opts.ConfirmSuspect != nil && !opts.ConfirmSuspect(suspects) {
+			return fmt.Errorf("push %s: %w (first: %s)", project.Name, ErrSuspectFiles, suspects[0].Path)
+		}
+	}
 	cur.ProjectName = project.Name
 	cur.ProjectPath = project.Path
 
+	scheme, err := meta.GetKeyScheme(ctx, project.Name)
+	if err != nil {
+		return err
+	}
+	cur.KeyScheme = scheme
+
+	if rel := topLevelALS(ManifestFromState(cur)); rel != "" {
+		if v, err := als.ReadLiveVersion(filepath.Join(project.Path, filepath.FromSlash(rel))); err == nil {
+			cur.LiveVersion = v
+			commit.LiveVersion = v
+		}
+	}
+
 	// 1) Previous state lookup
-	prev, _, _ := meta.GetLatestState(ctx, project.Name)
+	prev, prevCommit, prevErr := meta.GetLatestState(ctx, project.Name)
+	if prevErr == nil && prevCommit == nil {
+		if c, flagged := checkFirstPush(project.Name, cur.Files, opts.FirstPushMaxFiles, opts.FirstPushMaxBytes); flagged {
+			for _, r := range c.Reasons {
+				log.Printf("push %s: ⚠ first push: %s", project.Name, r)
+			}
+			if opts.ConfirmFirstPush == nil || !opts.ConfirmFirstPush(c) {
+				return fmt.Errorf("push %s: %w (%s)", project.Name, ErrFirstPushUnconfirmed, strings.Join(c.Reasons, "; "))
+			}
+		}
+	}
+	if commit.ParentID == "" && prevCommit != nil {
+		commit.ParentID = prevCommit.ID
+	}
 	prevByPath := map[string]FileEntry{}
+	prevByHash := map[string]FileEntry{} // renames and moves reuse the stored blob
 	if prev != nil {
 		for _, pf := range prev.Files {
 			prevByPath[pf.Path] = pf
+			if pf.R2Key != "" {
+				prevByHash[pf.Hash] = pf
+			}
+		}
+	}
+
+	if opts.AudioFingerprint {
+		for i := range cur.Files {
+			f := &cur.Files[i]
+			if !audio.Supported(f.Path) {
+				continue
+			}
+			if fp, err := audio.Fingerprint(filepath.Join(project.Path, f.Path)); err == nil {
+				f.AudioHash = fp
+			}
+		}
+	}
+
+	// Audio header info and MIME type for the remote browser; unchanged
+	// content keeps the previous commit's, anything unreadable just goes
+	// without.
+	for i := range cur.Files {
+		f := &cur.Files[i]
+		if pf, ok := prevByPath[f.Path]; ok && pf.Hash == f.Hash && pf.ContentType != "" {
+			f.ContentType = pf.ContentType
+		} else {
+			f.ContentType = DetectContentType(filepath.Join(project.Path, f.Path), f.Path)
+		}
+		if !audio.InfoSupported(f.Path) {
+			continue
+		}
+		if pf, ok := prevByHash[f.Hash]; ok && pf.Audio != nil {
+			f.Audio = pf.Audio
+			continue
+		}
+		if info, err := ReadAudioInfo(filepath.Join(project.Path, f.Path)); err == nil {
+			f.Audio = info
+		} else {
+			debugf("push %s: %s: audio info: %v", project.Name, f.Path, err)
 		}
 	}
 
 	// 2) Decide actions
 	type todo struct {
-		idx int
-		key string
+		idxs []int // every file whose content lands at key
+		key  string
 		// If migrating, fromKey holds old key to copy-from
 		fromKey string
+		force   bool
 	}
 	var uploads []todo
+	// Group by target key: identical content under several paths (duplicated
+	// samples) is uploaded once and its R2Key/ETag assigned to every path.
+	byKey := map[string]int{}
+	queue := func(i int, t todo) {
+		if j, ok := byKey[t.key]; ok {
+			uploads[j].idxs = append(uploads[j].idxs, i)
+			uploads[j].force = uploads[j].force || t.force
+			debugf("push %s: %s: same content as %s", project.Name, cur.Files[i].Path, cur.Files[uploads[j].idxs[0]].Path)
+			return
+		}
+		t.idxs = []int{i}
+		byKey[t.key] = len(uploads)
+		uploads = append(uploads, t)
+	}
 
 	for i := range cur.Files {
 		f := &cur.Files[i]
-		desiredKey := r2.BuildKey(project.Name, f.Hash)
+		desiredKey := r2.BuildKeyScheme(scheme, project.Name, f.Hash)
+
+		if force[desiredKey] {
+			debugf("push %s: %s: force-upload -> %s", project.Name, f.Path, desiredKey)
+			queue(i, todo{key: desiredKey, force: true})
+			delete(force, desiredKey) // once per blob
+			continue
+		}
 
 		if prev == nil {
-			uploads = append(uploads, todo{idx: i, key: desiredKey})
+			debugf("push %s: %s: upload (first commit) -> %s", project.Name, f.Path, desiredKey)
+			queue(i, todo{key: desiredKey})
 			continue
 		}
 		if pf, ok := prevByPath[f.Path]; ok {
 			switch {
+			case pf.Hash != f.Hash && reuseRender(f, pf):
+				log.Printf("push %s: %s re-rendered with identical audio; reusing %s", project.Name, f.Path, pf.R2Key)
+				r2.count(MetricCacheHits, 1)
 			case pf.Hash != f.Hash:
-				uploads = append(uploads, todo{idx: i, key: desiredKey})
+				debugf("push %s: %s: upload (modified %.12s -> %.12s) -> %s", project.Name, f.Path, pf.Hash, f.Hash, desiredKey)
+				queue(i, todo{key: desiredKey})
 			case pf.R2Key == desiredKey:
+				debugf("push %s: %s: carry-forward %s", project.Name, f.Path, pf.R2Key)
+				r2.count(MetricCacheHits, 1)
 				f.R2Key = pf.R2Key // carry forward
+				f.ETag = pf.ETag
 			default:
 				// same content, different layout: migrate
-				uploads = append(uploads, todo{idx: i, key: desiredKey, fromKey: pf.R2Key})
+				debugf("push %s: %s: copy %s -> %s", project.Name, f.Path, pf.R2Key, desiredKey)
+				queue(i, todo{key: desiredKey, fromKey: pf.R2Key})
 			}
+		} else if pf, ok := prevByHash[f.Hash]; ok && pf.R2Key == desiredKey {
+			debugf("push %s: %s: carry-forward %s (renamed from %s)", project.Name, f.Path, pf.R2Key, pf.Path)
+			r2.count(MetricCacheHits, 1)
+			f.R2Key, f.ETag = pf.R2Key, pf.ETag
+		} else if ok {
+			debugf("push %s: %s: copy %s -> %s (renamed from %s)", project.Name, f.Path, pf.R2Key, desiredKey, pf.Path)
+			queue(i, todo{key: desiredKey, fromKey: pf.R2Key})
 		} else {
-			uploads = append(uploads, todo{idx: i, key: desiredKey})
+			debugf("push %s: %s: upload (added) -> %s", project.Name, f.Path, desiredKey)
+			queue(i, todo{key: desiredKey})
 		}
 	}
 
 	// 3) Execute with concurrency + idempotency
 	workers := max(2, runtime.NumCPU()/2)
 	type result struct {
-		idx int
-		key string
-		err error
+		idxs []int
+		key  string
+		etag string
+		err  error
 	}
 	jobs := make(chan todo)
 	results := make(chan result)
 	var wg sync.WaitGroup
 
+	// Bytes a job holds against MaxInFlightBytes while it runs.
+	var budget *byteBudget
+	if opts.MaxInFlightBytes > 0 {
+		budget = newByteBudget(opts.MaxInFlightBytes)
+	}
+	weight := func(t todo) int64 {
+		size := cur.Files[t.idxs[0]].Size
+		if budget == nil || (t.fromKey != "" && !t.force) || size < inFlightFreeSize {
+			return 0
+		}
+		return size
+	}
+
 	// worker
 	worker := func() {
 		defer wg.Done()
 		for t := range jobs {
 			select {
 			case <-ctx.Done():
-				results <- result{idx: t.idx, key: t.key, err: ctx.Err()}
+				if w := weight(t); w > 0 {
+					budget.release(w)
+				}
+				results <- result{idxs: t.idxs, key: t.key, err: ctx.Err()}
 				continue
 			default:
 			}
 
 			var err error
+			var etag string
+			local := filepath.Join(project.Path, cur.Files[t.idxs[0]].Path)
 			// Prefer server-side copy when migrating
 			switch {
+			case t.force:
+				etag, err = r2.forceUpload(ctx, local, t.key)
 			case t.fromKey != "" && t.fromKey != t.key:
 				err = r2.CopyIfMissing(ctx, t.fromKey, t.key)
 			default:
-				local := filepath.Join(project.Path, cur.Files[t.idx].Path)
-				err = r2.UploadIfMissing(ctx, local, t.key) // HEAD/If-None-Match semantics
+				etag, err = r2.UploadIfMissingETag(ctx, local, t.key) // HEAD/If-None-Match semantics
+			}
+			if w := weight(t); w > 0 {
+				budget.release(w)
 			}
-			results <- result{idx: t.idx, key: t.key, err: err}
+			results <- result{idxs: t.idxs, key: t.key, etag: etag, err: err}
 		}
 	}
 
@@ -110,6 +326,9 @@ func PushProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, proj
 	}
 	go func() {
 		for _, t := range uploads {
+			if w := weight(t); w > 0 {
+				budget.acquire(w) // released by the worker when the job ends
+			}
 			jobs <- t
 		}
 		close(jobs)
@@ -118,10 +337,20 @@ func PushProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, proj
 	var firstErr error
 	for i := 0; i < len(uploads); i++ {
 		r := <-results
+		for _, idx := range r.idxs {
+			if r.err != nil {
+				debugf("push %s: %s: failed: %v", project.Name, cur.Files[idx].Path, r.err)
+			} else {
+				debugf("push %s: %s: ok %s", project.Name, cur.Files[idx].Path, r.key)
+			}
+		}
 		if r.err != nil && firstErr == nil {
 			firstErr = r.err
 		} else {
-			cur.Files[r.idx].R2Key = r.key
+			for _, idx := range r.idxs {
+				cur.Files[idx].R2Key = r.key
+				cur.Files[idx].ETag = r.etag
+			}
 		}
 	}
 	wg.Wait()
@@ -131,7 +360,36 @@ func PushProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, proj
 	}
 
 	// 4) Persist metadata + snapshot
-	return meta.UpsertLatestState(ctx, project.Name, cur, commit)
+	cur.ManifestHash = ComputeManifestHash(cur)
+	sum := remote.SummarizeStates(prev, cur)
+	commit.Summary = &sum
+	if err := meta.UpsertLatestState(ctx, project.Name, cur, commit); err != nil {
+		return err
+	}
+	if err := recordSyncedCommit(project.Path, commit.ID, cur); err != nil {
+		log.Printf("push %s: record synced commit: %v", project.Name, err)
+	}
+	if s, err := LoadProjectSettings(project.Path); err == nil && s.RelocatedFrom != "" {
+		log.Printf("push %s: remote path now %s (was %s)", project.Name, project.Path, s.RelocatedFrom)
+		s.RelocatedFrom = ""
+		_ = SaveProjectSettings(project.Path, s)
+	}
+	if opts.GeneratePeaks {
+		var fresh []FileEntry
+		for _, t := range uploads {
+			if t.fromKey == "" {
+				fresh = append(fresh, cur.Files[t.idxs[0]])
+			}
+		}
+		uploadPeaks(ctx, r2, project, fresh)
+	}
+	if opts.VerifyAfter {
+		if err := verifyPushed(ctx, meta, r2, project.Name, commit.ID, opts.OnVerified); err != nil {
+			return err
+		}
+	}
+	r2.markSynced()
+	return nil
 }
 
 // PullProject downloads target state into destPath.
@@ -139,16 +397,144 @@ func PushProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, proj
 // - Atomic download (r2.DownloadTo already writes .part -> fsync -> rename)
 // - Preserves mtime; fsyncs parent dir after rename; bounded concurrency
 func PullProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, projectName, destPath, commitID string, allowDelete bool) (*PullStats, error) {
+	return PullProjectWithOptions(ctx, meta, r2, projectName, destPath, commitID, PullOptions{AllowDelete: allowDelete})
+}
+
+// defaultPresignedMinSize is the UsePresigned cutoff when PresignedMinSize is 0.
+const defaultPresignedMinSize = 64 << 20
+
+// PullOptions tunes PullProjectWithOptions; the zero value matches PullProject.
+type PullOptions struct {
+	AllowDelete bool // remove local files not in the target state
+
+	// DeleteWorkers is how many files the delete pass removes at once
+	// (0 = 8). Failures are reported in PullStats.DeleteErrors.
+	DeleteWorkers int
+
+	// PruneEmptyDirs removes folders the pull left empty (e.g. Samples/
+	// subfolders of a newer version after a rollback). Folders that were
+	// already empty, .portsy and folders still holding any file are kept.
+	PruneEmptyDirs bool
+
+	// UsePresigned fetches files of at least PresignedMinSize bytes through a
+	// presigned GET URL instead of the SDK downloader (fewer hops behind some
+	// proxies). FetchURL, if set, does the transfer (e.g. an external
+	// downloader); otherwise R2Client.DownloadURLTo is used. Hashes are
+	// verified after the transfer either way.
+	UsePresigned     bool
+	PresignedMinSize int64
+	FetchURL         func(ctx context.Context, url, dstPath string) error
+
+	// BackupChanged moves every local file the pull would overwrite (content
+	// differs from the target) or delete into .portsy/backup/<timestamp>/<rel>
+	// first, so nothing the pull clobbers is lost. See PullStats.BackupDir.
+	BackupChanged bool
+
+	// ALSBackupKeep bounds the copies in .portsy/als-backups: whatever
+	// BackupChanged says, a top-level .als is copied there before the pull
+	// overwrites or deletes it (0 = DefaultALSBackupKeep, < 0 = off). See
+	// ListALSBackups / RestoreALSBackup.
+	ALSBackupKeep int
+
+	// InstalledLiveVersion (e.g. "11.3.4") makes the pull warn when the target
+	// was saved by a newer Live. Empty falls back to InstalledLiveVersionEnv.
+	InstalledLiveVersion string
+
+	// Signatures, if set, is checked against the target commit before any
+	// file is touched; nil falls back to SignaturePolicyFromEnv.
+	Signatures *SignaturePolicy
+
+	// ReadOnly leaves a plain copy for listening/review: nothing is written
+	// under .portsy (no resume checkpoint, .als safety copies, cache or
+	// remote binding) and the folder icon isn't set. It can't be combined
+	// with BackupChanged. A later normal pull into the folder tracks it.
+	ReadOnly bool
+
+	// SetFolderIcon gives the folder the project's Ableton icon (see
+	// EnsureFolderIcon). nil falls back to FolderIconEnv, then to on for
+	// Windows only, as before the option existed.
+	SetFolderIcon *bool
+
+	// Paths limits the pull to these rel paths (a folder selects everything
+	// under it). A partial pull never deletes or prunes, and keeps the resume
+	// checkpoint so a later full pull skips what it fetched. See PullStats.Partial.
+	Paths []string
+
+	// ResumeFrom reads the resume checkpoint from this file instead of
+	// .portsy/pull-journal.json (e.g. one copied from an interrupted pull
+	// elsewhere). Progress is still written to the project's own journal.
+	ResumeFrom string
+}
+
+func (o PullOptions) folderIcon() bool {
+	if o.SetFolderIcon != nil {
+		return *o.SetFolderIcon
+	}
+	return defaultFolderIcon()
+}
+
+// InstalledLiveVersionEnv names the locally installed Live version for the
+// newer-Live pull warning when PullOptions.InstalledLiveVersion is unset.
+const InstalledLiveVersionEnv = "PORTSY_LIVE_VERSION"
+
+// PullProjectWithOptions is PullProject with tunables (see PullOptions).
+func PullProjectWithOptions(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, projectName, destPath, commitID string, opts PullOptions) (*PullStats, error) {
+	allowDelete := opts.AllowDelete
+	if opts.PresignedMinSize <= 0 {
+		opts.PresignedMinSize = defaultPresignedMinSize
+	}
+	if opts.FetchURL == nil {
+		opts.FetchURL = r2.DownloadURLTo
+	}
+	tempDir := projectTempDir(destPath, r2.cfg.TempDir)
+	fetch := func(ctx context.Context, rf FileEntry, key, localPath string) error {
+		if !opts.UsePresigned || rf.Size < opts.PresignedMinSize {
+			return r2.downloadToIn(ctx, key, localPath, tempDir)
+		}
+		url, err := r2.PresignGet(ctx, key)
+		if err != nil {
+			return err
+		}
+		return opts.FetchURL(ctx, url, localPath)
+	}
 
 	stats := &PullStats{}
+	if opts.ReadOnly {
+		if opts.BackupChanged {
+			return stats, fmt.Errorf("pull: a read-only pull can't back up into .portsy")
+		}
+		opts.ALSBackupKeep = -1
+	}
+
+	backupDir := filepath.Join(destPath, ".portsy", "backup", time.Now().Format("20060102-150405"))
+	backup := func(rel, localPath string) error {
+		dst := filepath.Join(backupDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return fmt.Errorf("backup %s: %w", rel, err)
+		}
+		if err := os.Rename(localPath, dst); err != nil {
+			return fmt.Errorf("backup %s: %w", rel, err)
+		}
+		return nil
+	}
+
+	if !opts.ReadOnly {
+		if _, err := MigrateLegacyCache(destPath); err != nil {
+			log.Printf("pull %s: %v", projectName, err)
+		}
+	}
 
 	// 1) Resolve target snapshot
 	var target *ProjectState
-	var err error
+	var targetCommit *CommitMeta
+	commitID, err := resolveCommitID(ctx, meta, projectName, commitID)
+	if err != nil {
+		return stats, fmt.Errorf("pull: %w", err)
+	}
 	if commitID == "" {
-		target, _, err = meta.GetLatestState(ctx, projectName)
+		target, targetCommit, err = meta.GetLatestState(ctx, projectName)
 	} else {
-		target, _, err = meta.GetStateByCommit(ctx, projectName, commitID)
+		target, targetCommit, err = meta.GetStateByCommit(ctx, projectName, commitID)
 	}
 	if err != nil {
 		return stats, fmt.Errorf("pull: read remote state: %w", err)
@@ -156,9 +542,75 @@ func PullProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, proj
 	if target == nil {
 		return stats, fmt.Errorf("pull: no remote state found for %q (commit=%q)", projectName, commitID)
 	}
+	policy := opts.Signatures
+	if policy == nil {
+		p, err := SignaturePolicyFromEnv()
+		if err != nil {
+			return stats, fmt.Errorf("pull: %w", err)
+		}
+		policy = &p
+	}
+	if err := policy.Check(targetCommit, target); err != nil {
+		return stats, fmt.Errorf("pull: %w", err)
+	}
+	installed := opts.InstalledLiveVersion
+	if installed == "" {
+		installed = os.Getenv(InstalledLiveVersionEnv)
+	}
+	if installed != "" && target.LiveVersion != "" && als.CompareLiveVersions(target.LiveVersion, installed) > 0 {
+		stats.LiveVersionWarning = fmt.Sprintf("%s was saved with Ableton Live %s; installed Live %s may not open it", projectName, target.LiveVersion, installed)
+		log.Printf("pull: ⚠ %s", stats.LiveVersionWarning)
+	}
+	files := target.Files
+	if len(opts.Paths) > 0 {
+		if files, err = selectPullFiles(target.Files, opts.Paths); err != nil {
+			return stats, fmt.Errorf("pull: %w", err)
+		}
+		stats.Partial = true
+		if allowDelete || opts.PruneEmptyDirs {
+			log.Printf("pull %s: partial pull, not deleting or pruning anything", projectName)
+			allowDelete, opts.PruneEmptyDirs = false, false
+		}
+	}
 	if err := os.MkdirAll(destPath, 0o755); err != nil {
 		return stats, fmt.Errorf("pull: mkdir dest: %w", err)
 	}
+	if err := checkPullSpace(destPath, files); err != nil {
+		return stats, fmt.Errorf("pull: %w", err)
+	}
+	var emptyBefore []string
+	if opts.PruneEmptyDirs {
+		emptyBefore = localDirs(destPath, true)
+	}
+
+	// Resume support: files an interrupted pull of this same state already
+	// verified are trusted while their size/mtime are unchanged.
+	cpTarget := target.ManifestHash
+	if cpTarget == "" {
+		cpTarget = ComputeManifestHash(*target)
+	}
+	resumed := map[string]checkpointEntry{}
+	if !opts.ReadOnly {
+		if opts.ResumeFrom != "" {
+			resumed = loadPullCheckpoint(opts.ResumeFrom, cpTarget)
+		} else {
+			resumed = loadPullJournal(destPath, cpTarget)
+		}
+	}
+	completed := make(map[string]checkpointEntry, len(resumed))
+	if stats.Partial {
+		// Carry forward what this pull won't look at.
+		for p, e := range resumed {
+			completed[p] = e
+		}
+	}
+	lastFlush := time.Now()
+	flush := func() {
+		if !opts.ReadOnly {
+			_ = savePullCheckpoint(destPath, cpTarget, completed)
+		}
+		lastFlush = time.Now()
+	}
 
 	// quick lookup for deletes
 	targetByPath := make(map[string]FileEntry, len(target.Files))
@@ -167,111 +619,113 @@ func PullProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, proj
 	}
 
 	// 2) concurrent ensure files
-	type job struct{ rf FileEntry }
-	type done struct {
-		rf         FileEntry
-		err        error
-		downloaded bool
-	}
-	jobs := make(chan job)
-	dones := make(chan done)
+	var fetched blobOnce // key -> first local path it was downloaded to
 
-	workers := max(2, runtime.NumCPU()/2)
-	var wg sync.WaitGroup
-	wg.Add(workers)
-
-	verify := func(path, algo, want string) (bool, error) {
+	verify := func(path, algo string, rf FileEntry) (bool, error) {
+		var sum string
+		var err error
 		switch algo {
 		case "sha256", "SHA-256", "":
 			// default/legacy -> SHA-256
-			sum, _, _, herr := HashFileSHA256(path)
-			if herr != nil {
-				return false, herr
-			}
-			return sum == want, nil
-
+			sum, _, _, err = HashFileSHA256(path)
 		case "blake3":
 			// compute just the hash (size/mtime not needed here)
-			sum, err := corehash.New(corehash.BLAKE3).File(path)
-			if err != nil {
-				return false, err
-			}
-			return sum == want, nil
-
+			sum, err = corehash.New(corehash.BLAKE3).File(path)
 		default:
 			return false, fmt.Errorf("unknown hash algo %q", algo)
 		}
+		if err != nil {
+			return false, err
+		}
+		return sum == rf.Hash || sameAudio(path, rf), nil
 	}
 
-	worker := func() {
-		defer wg.Done()
-		for j := range jobs {
-			rf := j.rf
-			localPath := filepath.Join(destPath, filepath.FromSlash(rf.Path))
-			// ensure parent
-			if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
-				dones <- done{rf: rf, err: fmt.Errorf("mkdir %s: %w", filepath.Dir(localPath), err)}
-				continue
+	ensure := func(ctx context.Context, rf FileEntry) pullResult {
+		localPath := filepath.Join(destPath, filepath.FromSlash(rf.Path))
+		// ensure parent
+		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+			return pullResult{rf: rf, err: fmt.Errorf("mkdir %s: %w", filepath.Dir(localPath), err)}
+		}
+
+		needDownload, differs := false, false
+		if fi, err := os.Lstat(localPath); err != nil || !fi.Mode().IsRegular() {
+			needDownload = true
+		} else if e, ok := resumed[rf.Path]; ok && e.stillComplete(rf, fi) {
+			debugf("pull %s: %s: skip (verified by checkpoint)", projectName, rf.Path)
+			r2.count(MetricCacheHits, 1)
+			return pullResult{rf: rf, fi: fi}
+		} else {
+			ok, herr := verify(localPath, target.Algo, rf)
+			if herr != nil || !ok {
+				needDownload, differs = true, true
 			}
+		}
 
-			needDownload := false
-			if fi, err := os.Lstat(localPath); err != nil || !fi.Mode().IsRegular() {
-				needDownload = true
+		if needDownload {
+			backedUp := false
+			if differs {
+				debugf("pull %s: %s: download (local differs)", projectName, rf.Path)
 			} else {
-				ok, herr := verify(localPath, target.Algo, rf.Hash)
-				if herr != nil || !ok {
-					needDownload = true
-				}
+				debugf("pull %s: %s: download (missing locally)", projectName, rf.Path)
 			}
-
-			if needDownload {
-				key := rf.R2Key
-				if key == "" {
-					key = r2.BuildKey(projectName, rf.Hash)
-				}
-				if err := r2.DownloadTo(ctx, key, localPath); err != nil {
-					dones <- done{rf: rf, err: fmt.Errorf("download %s: %w", key, err)}
-					continue
+			if differs && isTopLevelALS(rf.Path) {
+				if err := backupALS(destPath, rf.Path, opts.ALSBackupKeep); err != nil {
+					return pullResult{rf: rf, err: err}
 				}
-				// verify after download
-				ok, herr := verify(localPath, target.Algo, rf.Hash)
-				if herr != nil {
-					dones <- done{rf: rf, err: fmt.Errorf("verify %s: %w", localPath, herr)}
-					continue
+			}
+			if differs && opts.BackupChanged {
+				debugf("pull %s: %s: backup to %s", projectName, rf.Path, backupDir)
+				if err := backup(rf.Path, localPath); err != nil {
+					return pullResult{rf: rf, err: err}
 				}
-				if !ok {
-					dones <- done{rf: rf, err: fmt.Errorf("verify %s: hash mismatch", localPath)}
-					continue
+				backedUp = true
+			}
+			key := r2.KeyFor(target, projectName, rf)
+			src, shared, err := fetched.do(key, func() (string, error) {
+				return localPath, fetch(ctx, rf, key, localPath)
+			})
+			if err != nil {
+				return pullResult{rf: rf, err: fmt.Errorf("download %s: %w", key, err)}
+			}
+			if shared {
+				// Same blob as a file already pulled: copy it locally.
+				debugf("pull %s: %s: copy of %s", projectName, rf.Path, src)
+				if err := copyFile(src, localPath); err != nil {
+					return pullResult{rf: rf, err: fmt.Errorf("copy %s: %w", src, err)}
 				}
-				// Restore mtime (optional; use commit timestamp for determinism)
-				_ = os.Chtimes(localPath, time.Now(), time.Unix(0, 0))
-				dones <- done{rf: rf, downloaded: true}
-			} else {
-				dones <- done{rf: rf}
 			}
+			// verify after download
+			ok, herr := verify(localPath, target.Algo, rf)
+			if herr != nil {
+				return pullResult{rf: rf, err: fmt.Errorf("verify %s: %w", localPath, herr)}
+			}
+			if !ok {
+				return pullResult{rf: rf, err: fmt.Errorf("verify %s: hash mismatch", localPath)}
+			}
+			// Restore the pushed mtime (after the .part rename, so it sticks).
+			restoreMtime(localPath, rf, targetCommit)
+			fi, _ := os.Lstat(localPath)
+			debugf("pull %s: %s: ok (downloaded %s)", projectName, rf.Path, key)
+			return pullResult{rf: rf, downloaded: true, backedUp: backedUp, fi: fi}
+		} else {
+			debugf("pull %s: %s: skip (up to date)", projectName, rf.Path)
+			r2.count(MetricCacheHits, 1)
+			fi, _ := os.Lstat(localPath)
+			return pullResult{rf: rf, fi: fi}
 		}
 	}
 
-	for i := 0; i < workers; i++ {
-		go worker()
-	}
-	go func() {
-		for _, rf := range target.Files {
-			select {
-			case <-ctx.Done():
-				return
-			case jobs <- job{rf: rf}:
+	record := func(d pullResult) {
+		if d.err == nil && d.fi != nil {
+			completed[d.rf.Path] = checkpointEntry{Hash: d.rf.Hash, Size: d.fi.Size(), Mtime: d.fi.ModTime().UnixNano()}
+			if time.Since(lastFlush) > 2*time.Second {
+				flush()
 			}
 		}
-		close(jobs)
-	}()
-
-	for i := 0; i < len(target.Files); i++ {
-		d := <-dones
-		if d.err != nil && !errors.Is(d.err, context.Canceled) {
-			return stats, d.err
-		}
 		stats.ToDownload++
+		if d.backedUp {
+			stats.BackedUp++
+		}
 		if d.downloaded {
 			stats.Downloaded++
 			stats.Verified++
@@ -279,39 +733,91 @@ func PullProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, proj
 			stats.Skipped++
 		}
 	}
-	wg.Wait()
-	close(dones)
+	if err := runPullWorkers(ctx, files, max(2, runtime.NumCPU()/2), ensure, record, flush); err != nil {
+		debugf("pull %s: failed: %v", projectName, err)
+		flush()
+		return stats, err
+	}
+	if stats.Partial {
+		flush()
+	} else if !opts.ReadOnly {
+		clearPullCheckpoint(destPath)
+	}
 
 	// 3) Optional delete pass
 	if allowDelete {
-		_ = filepath.Walk(destPath, func(p string, info os.FileInfo, walkErr error) error {
-			if walkErr != nil || info.IsDir() {
-				if info != nil && info.IsDir() && info.Name() == ".portsy" {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-			rel, _ := filepath.Rel(destPath, p)
-			rel = filepath.ToSlash(rel)
-			if _, ok := targetByPath[rel]; !ok {
-				if err := os.Remove(p); err == nil {
-					stats.Deleted++
-				}
-			}
-			return nil
-		})
+		deleteStale(projectName, destPath, targetByPath, opts, backup, stats)
+	}
+	if opts.PruneEmptyDirs {
+		stats.PrunedDirs = pruneEmptyDirs(destPath, emptyBefore)
 	}
 
-	_ = EnsureAbletonFolderIcon(destPath)
+	if stats.BackedUp > 0 {
+		stats.BackupDir = backupDir
+		log.Printf("pull: %d local file(s) backed up to %s", stats.BackedUp, backupDir)
+	}
+	if !opts.ReadOnly && opts.folderIcon() {
+		if err := EnsureFolderIcon(destPath); err != nil {
+			debugf("pull %s: folder icon: %v", projectName, err)
+		}
+	}
+	if !opts.ReadOnly && !stats.Partial && targetCommit != nil {
+		if err := recordSyncedCommit(destPath, targetCommit.ID, hashesOnly(*target)); err != nil {
+			log.Printf("pull %s: record synced commit: %v", projectName, err)
+		}
+	}
 	log.Printf("pull: done. toDownload=%d downloaded=%d verified=%d skipped=%d deleted=%d",
 		stats.ToDownload, stats.Downloaded, stats.Verified, stats.Skipped, stats.Deleted)
+	r2.markSynced()
 	return stats, nil
 }
 
-// Rollback is unchanged (just uses Pull with allowDelete=true).
+// fileMtime is the modification time a pulled file gets: the one recorded
+// at push, else the commit's timestamp, else zero (leave it alone).
+func fileMtime(rf FileEntry, cm *CommitMeta) time.Time {
+	switch {
+	case rf.Modified > 0:
+		return time.Unix(rf.Modified, 0)
+	case cm != nil && cm.Timestamp > 0:
+		return time.Unix(cm.Timestamp, 0)
+	}
+	return time.Time{}
+}
+
+// restoreMtime sets p's mtime to fileMtime(rf, cm); best-effort, and a
+// no-op when neither records one.
+func restoreMtime(p string, rf FileEntry, cm *CommitMeta) {
+	if mt := fileMtime(rf, cm); !mt.IsZero() {
+		_ = os.Chtimes(p, mt, mt)
+	}
+}
+
+// Rollback is a Pull with deletes enabled; everything it overwrites or
+// removes is backed up first (see PullOptions.BackupChanged), and folders
+// it leaves empty are removed.
 func RollbackProject(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, projectName, destPath, commitID string) error {
-	_, err := PullProject(ctx, meta, r2, projectName, destPath, commitID, true)
-	return err
+	commitID, err := resolveCommitID(ctx, meta, projectName, commitID)
+	if err != nil {
+		return fmt.Errorf("rollback: %w", err)
+	}
+	_, err = PullProjectWithOptions(ctx, meta, r2, projectName, destPath, commitID, PullOptions{AllowDelete: true, BackupChanged: true, PruneEmptyDirs: true})
+	if err != nil {
+		return err
+	}
+	_ = meta.RecordAudit(ctx, projectName, remote.AuditRollback, commitID) // best-effort
+	return nil
+}
+
+// fullCommitIDLen is the length of a commit ID (a UUID).
+const fullCommitIDLen = 36
+
+// resolveCommitID expands a short commit ID (see ShortCommitID); "" (HEAD)
+// and full UUIDs pass through without a lookup.
+func resolveCommitID(ctx context.Context, meta *remote.MetaStore, project, id string) (string, error) {
+	if id == "" || len(id) == fullCommitIDLen {
+		return id, nil
+	}
+	return meta.ResolveCommitPrefix(ctx, project, id)
 }
 
 // Utility
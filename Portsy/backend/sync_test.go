@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"Portsy/backend/internal/audio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileMtime(t *testing.T) {
+	pushed, committed := int64(1_700_000_000), int64(1_700_000_500)
+	tests := []struct {
+		name string
+		rf   FileEntry
+		cm   *CommitMeta
+		want int64 // unix seconds; 0 for the zero time
+	}{
+		{name: "recorded at push", rf: FileEntry{Modified: pushed}, cm: &CommitMeta{Timestamp: committed}, want: pushed},
+		{name: "recorded, no commit", rf: FileEntry{Modified: pushed}, want: pushed},
+		{name: "falls back to commit time", cm: &CommitMeta{Timestamp: committed}, want: committed},
+		{name: "neither", cm: &CommitMeta{}},
+		{name: "no commit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fileMtime(tt.rf, tt.cm)
+			if tt.want == 0 {
+				if !got.IsZero() {
+					t.Fatalf("fileMtime = %v, want zero", got)
+				}
+				return
+			}
+			if got.Unix() != tt.want {
+				t.Errorf("fileMtime = %v, want unix %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRestoreMtimeAfterAtomicWrite runs the download path (write to .part,
+// rename over the file) and checks the restored mtime is what's left on
+// disk, including when the temp file had to be copied across volumes.
+func TestRestoreMtimeAfterAtomicWrite(t *testing.T) {
+	pushed := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name        string
+		tempDir     bool // stage in a separate folder, as with a pull temp dir
+		crossDevice bool
+		rf          FileEntry
+		want        time.Time // zero: mtime left at write time
+	}{
+		{name: "next to file", rf: FileEntry{Modified: pushed.Unix()}, want: pushed},
+		{name: "temp dir", tempDir: true, rf: FileEntry{Modified: pushed.Unix()}, want: pushed},
+		{name: "temp dir on another volume", tempDir: true, crossDevice: true, rf: FileEntry{Modified: pushed.Unix()}, want: pushed},
+		{name: "nothing recorded", rf: FileEntry{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.crossDevice {
+				renameFile = func(oldpath, newpath string) error {
+					return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errCrossDevice}
+				}
+				t.Cleanup(func() { renameFile = os.Rename })
+			}
+			dir := t.TempDir()
+			dst := filepath.Join(dir, "Song", "Samples", "kick.wav")
+			writeTestFile(t, dst, "old kick")
+			tempDir := ""
+			if tt.tempDir {
+				tempDir = filepath.Join(dir, "tmp")
+			}
+
+			before := time.Now().Add(-time.Minute)
+			err := writeFileAtomicIn(tempDir, dst, func(f *os.File) error {
+				_, err := io.Copy(f, strings.NewReader("new kick"))
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			restoreMtime(dst, tt.rf, nil)
+
+			fi, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want.IsZero() {
+				if fi.ModTime().Before(before) {
+					t.Errorf("mtime = %v, want left at write time", fi.ModTime())
+				}
+				return
+			}
+			if !fi.ModTime().Equal(tt.want) {
+				t.Errorf("mtime = %v, want %v", fi.ModTime(), tt.want)
+			}
+		})
+	}
+}
+
+// testWAV builds a 16-bit mono WAV with the given samples and, before the
+// data chunk, a LIST chunk holding meta (skipped when empty).
+func testWAV(samples []int16, meta string) []byte {
+	chunk := func(id string, body []byte) []byte {
+		out := binary.LittleEndian.AppendUint32([]byte(id), uint32(len(body)))
+		out = append(out, body...)
+		if len(body)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	fmtBody := binary.LittleEndian.AppendUint16(nil, 1) // PCM
+	fmtBody = binary.LittleEndian.AppendUint16(fmtBody, 1)
+	fmtBody = binary.LittleEndian.AppendUint32(fmtBody, 44100)
+	fmtBody = binary.LittleEndian.AppendUint32(fmtBody, 44100*2)
+	fmtBody = binary.LittleEndian.AppendUint16(fmtBody, 2)
+	fmtBody = binary.LittleEndian.AppendUint16(fmtBody, 16)
+	var data []byte
+	for _, v := range samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(v))
+	}
+
+	body := append([]byte("WAVE"), chunk("fmt ", fmtBody)...)
+	if meta != "" {
+		body = append(body, chunk("LIST", []byte(meta))...)
+	}
+	body = append(body, chunk("data", data)...)
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+// TestReuseRender pushes a re-render over a committed one the way
+// PushProjectWithOptions decides it with AudioFingerprint on: a header-only
+// change reuses the committed blob instead of uploading, the entry keeps the
+// new file's own hash and size, and pull accepts the reused blob's bytes.
+func TestReuseRender(t *testing.T) {
+	samples := []int16{0, 1200, -1200, 32767, -32768, 7}
+	committed := testWAV(samples, "INFOtake one")
+	tests := []struct {
+		name   string
+		render []byte
+		noFP   bool // previous commit pushed without fingerprints
+		reused bool
+	}{
+		{name: "header-only re-render", render: testWAV(samples, "INFOtake two, bounced again"), reused: true},
+		{name: "metadata dropped", render: testWAV(samples, ""), reused: true},
+		{name: "different audio", render: testWAV([]int16{0, 1200, -1200, 32767, -32768, 8}, "INFOtake one")},
+		{name: "no previous fingerprint", render: testWAV(samples, "INFOtake two"), noFP: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := func(dir string, body []byte) FileEntry {
+				t.Helper()
+				writeTestFile(t, filepath.Join(dir, "Bounces", "mix.wav"), string(body))
+				ps, err := BuildManifest(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(ps.Files) != 1 {
+					t.Fatalf("manifest = %+v, want one file", ps.Files)
+				}
+				fe := ps.Files[0]
+				if fe.AudioHash, err = audio.Fingerprint(filepath.Join(dir, "Bounces", "mix.wav")); err != nil {
+					t.Fatal(err)
+				}
+				return fe
+			}
+			blobDir := t.TempDir() // what the committed blob holds
+			pf := entry(blobDir, committed)
+			pf.R2Key, pf.ETag = "Project/blobs/"+pf.Hash, "etag-one"
+			if tt.noFP {
+				pf.AudioHash = ""
+			}
+
+			dir := t.TempDir()
+			f := entry(dir, tt.render)
+			local := filepath.Join(dir, "Bounces", "mix.wav")
+			if f.Hash == pf.Hash {
+				t.Fatal("re-render has the committed render's hash")
+			}
+
+			if got := reuseRender(&f, pf); got != tt.reused {
+				t.Fatalf("reuseRender = %v, want %v (false uploads the re-render)", got, tt.reused)
+			}
+			want, size, _, err := HashFileSHA256(local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Hash != want || f.Size != size {
+				t.Errorf("entry hash/size = %.12s/%d, want the file's %.12s/%d", f.Hash, f.Size, want, size)
+			}
+			if !tt.reused {
+				if f.R2Key != "" || f.ETag != "" {
+					t.Errorf("entry points at %q/%q, want no key until uploaded", f.R2Key, f.ETag)
+				}
+				return
+			}
+			if f.R2Key != pf.R2Key || f.ETag != pf.ETag {
+				t.Errorf("entry points at %q/%q, want the committed %q/%q", f.R2Key, f.ETag, pf.R2Key, pf.ETag)
+			}
+			if !sameAudio(filepath.Join(blobDir, "Bounces", "mix.wav"), f) {
+				t.Error("sameAudio rejects the reused blob's bytes; pull would fail to verify them")
+			}
+		})
+	}
+}
@@ -5,12 +5,34 @@ import "Portsy/backend/remote"
 // A single file tracked in a manifest/version.
 type FileEntry = remote.FileEntry
 
+// Header info of an audio file (duration, rate, channels, bit depth).
+type AudioInfo = remote.AudioInfo
+
 // The state of a project at a point in time (used for diffing).
 type ProjectState = remote.ProjectState
 
 // Commit metadata stored in Firestore
 type CommitMeta = remote.CommitMeta
 
+// Versioned blob key layout (see remote.KeyScheme)
+type KeyScheme = remote.KeyScheme
+
+const (
+	KeySchemeProjectBlobs = remote.KeySchemeProjectBlobs
+	KeySchemeGlobalBlobs  = remote.KeySchemeGlobalBlobs
+	DefaultKeyScheme      = remote.DefaultKeyScheme
+)
+
+// Short display form of a commit ID; any unique prefix resolves
+// (see remote.MetaStore.ResolveCommitPrefix).
+var ShortCommitID = remote.ShortCommitID
+
+// Firestore emulator address variable (see remote.MetaStoreConfig.EmulatorHost)
+const EmulatorHostEnv = remote.EmulatorHostEnv
+
+// One row of a project's audit log (see remote.MetaStore.GetAuditLog)
+type AuditEntry = remote.AuditEntry
+
 // Firestore document we keep as "latest" pointer
 type ProjectDoc struct {
 	ProjectID    string   `firestore:"-"              json:"projectId"`
@@ -18,6 +40,7 @@ type ProjectDoc struct {
 	LastCommitID string   `firestore:"lastCommitId"   json:"lastCommitId,omitempty"`
 	LastCommitAt int64    `firestore:"lastCommitAt"   json:"lastCommitAt,omitempty"`
 	Last5        []string `firestore:"last5"          json:"last5,omitempty"`
+	LiveVersion  string   `firestore:"liveVersion"    json:"liveVersion,omitempty"`
 }
 
 type Diff struct {
@@ -51,12 +74,30 @@ type PullStats struct {
 	Verified   int `json:"verified"`
 	Deleted    int `json:"deleted"`
 	Skipped    int `json:"skipped"`
+
+	// Local files the delete pass couldn't remove (locked, permissions).
+	DeleteErrors []DeleteError `json:"deleteErrors,omitempty"`
+
+	// Folders removed by PullOptions.PruneEmptyDirs.
+	PrunedDirs int `json:"prunedDirs,omitempty"`
+
+	// Set when PullOptions.BackupChanged moved local files aside.
+	BackedUp  int    `json:"backedUp,omitempty"`
+	BackupDir string `json:"backupDir,omitempty"`
+
+	// Set when the pulled state was saved by a newer Live than the installed one.
+	LiveVersionWarning string `json:"liveVersionWarning,omitempty"`
+
+	// Set when PullOptions.Paths limited the pull to some files: the folder
+	// doesn't match the commit as a whole, so no cache or sync record is written.
+	Partial bool `json:"partial,omitempty"`
 }
 
 type PullStatus struct {
-	LocalNewer bool   `json:"localNewer"`
-	RemoteHead string `json:"remoteHead,omitempty"`
-	LocalHead  string `json:"localhead,omitempty"`
+	LocalNewer  bool   `json:"localNewer"`
+	RemoteNewer bool   `json:"remoteNewer"`
+	RemoteHead  string `json:"remoteHead,omitempty"`
+	LocalHead   string `json:"localhead,omitempty"`
 }
 
 type Config struct {
@@ -66,4 +107,8 @@ type Config struct {
 	R2Bucket         string
 	R2AccessKey      string
 	R2SecretKey      string
+
+	// CaseSensitivePaths keeps original case in manifest keys on Windows
+	// (see SetCaseSensitivePaths). Default false = fold case as before.
+	CaseSensitivePaths bool
 }
@@ -9,6 +9,7 @@ import (
 type API struct {
 	ctx       context.Context
 	MetaStore *remote.MetaStore
+	Manifests ManifestSource // what DetectChanges diffs
 }
 
 func (a *API) SetContext(ctx context.Context) { a.ctx = ctx }
@@ -32,6 +33,7 @@ type RemoteProject struct {
 	Name         string `json:"name"`
 	LastCommitID string `json:"lastCommitId"`
 	LastCommitAt int64  `json:"lastCommitAt"`
+	Empty        bool   `json:"empty"`
 }
 
 // SHows up as window.go.uiapi.API.ListRemoteProjects()
@@ -55,6 +57,7 @@ func (a *API) ListRemoteProjects() (map[string]any, error) {
 			Name:         p.Name,
 			LastCommitID: p.LastCommitID,
 			LastCommitAt: p.LastCommitAt,
+			Empty:        p.Empty,
 		})
 	}
 	return map[string]any{"ok": true, "count": len(items), "items": items}, nil
@@ -2,14 +2,11 @@ package uiapi
 
 import (
 	"context"
-	"encoding/json"
-	"os"
+	"errors"
 	"path/filepath"
 	"time"
 
 	"Portsy/backend/internal/als"
-	"Portsy/backend/internal/core/hash"
-	"Portsy/backend/internal/core/scan"
 	syn "Portsy/backend/internal/sync"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -22,6 +19,12 @@ type DetectChangesResp struct {
 	SampleRefs []string               `json:"sampleRefs"`
 }
 
+// ManifestSource returns a project's current manifest (path -> hash, plus
+// sizes) and the baseline to diff it against. main wires in
+// backend.LocalManifests, so the GUI and CLI diff the same two maps without
+// uiapi importing backend.
+type ManifestSource func(projectRoot string) (current, baseline map[string]string, sizes map[string]int64, err error)
+
 // DetectChanges scans & diffs, emits coarse events, returns details.
 func (a *API) DetectChanges(ctx context.Context, projectRoot string) (*DetectChangesResp, error) {
 	// prefer stored ctx for events/logs (Wails runtime is tied to startup ctx)
@@ -36,16 +39,7 @@ func (a *API) DetectChanges(ctx context.Context, projectRoot string) (*DetectCha
 		"ts":        time.Now().UTC().Format(time.RFC3339),
 	})
 
-	// Load baseline hashmap (ignore if file missing; log other errors)
-	baseline := make(map[string]string)
-	if err := readJSON(filepath.Join(projectRoot, ".portsy", "hashmap.json"), &baseline); err != nil {
-		if !os.IsNotExist(err) {
-			runtime.LogErrorf(a.ctx, "[detect] read hashmap: %v", err)
-		}
-	}
-
-	// Scan filesystem
-	entries, err := scan.WalkProject(projectRoot, nil)
+	cs, err := a.diffProject(projectRoot)
 	if err != nil {
 		runtime.LogErrorf(a.ctx, "[detect] scan error: %v", err)
 		runtime.EventsEmit(a.ctx, "detect:status", map[string]any{
@@ -56,26 +50,6 @@ func (a *API) DetectChanges(ctx context.Context, projectRoot string) (*DetectCha
 		return nil, err
 	}
 
-	current := make(map[string]string, len(entries))
-	sizes := make(map[string]int64, len(entries))
-
-	for _, e := range entries {
-		h, err := hash.FileHash(e.Abs)
-		if err != nil {
-			runtime.LogErrorf(a.ctx, "[detect] hashing error on %s: %v", e.Rel, err)
-			runtime.EventsEmit(a.ctx, "detect:status", map[string]any{
-				"phase":     "error",
-				"projectId": projectRoot,
-				"error":     err.Error(),
-			})
-			return nil, err
-		}
-		current[e.Rel] = h
-		sizes[e.Rel] = e.Size
-	}
-
-	cs := syn.Diff(current, baseline, sizes)
-
 	// Enrich with .als sample refs if any .als changed
 	var refs []string
 	for _, ch := range cs.Files {
@@ -92,8 +66,9 @@ func (a *API) DetectChanges(ctx context.Context, projectRoot string) (*DetectCha
 	added := cs.Counts[syn.Added]
 	modified := cs.Counts[syn.Modified]
 	deleted := cs.Counts[syn.Deleted]
+	renamed := cs.Counts[syn.Renamed]
 
-	runtime.LogInfof(a.ctx, "[detect] done added=%d modified=%d deleted=%d", added, modified, deleted)
+	runtime.LogInfof(a.ctx, "[detect] done added=%d modified=%d deleted=%d renamed=%d", added, modified, deleted, renamed)
 	runtime.EventsEmit(a.ctx, "detect:status", map[string]any{
 		"phase":     "done",
 		"projectId": projectRoot,
@@ -101,6 +76,7 @@ func (a *API) DetectChanges(ctx context.Context, projectRoot string) (*DetectCha
 			string(syn.Added):    added,
 			string(syn.Modified): modified,
 			string(syn.Deleted):  deleted,
+			string(syn.Renamed):  renamed,
 		},
 	})
 
@@ -109,12 +85,16 @@ func (a *API) DetectChanges(ctx context.Context, projectRoot string) (*DetectCha
 	}, nil
 }
 
-func readJSON(p string, v any) error {
-	b, err := os.ReadFile(p)
+// diffProject diffs projectRoot through a.Manifests.
+func (a *API) diffProject(projectRoot string) (syn.ChangeSet, error) {
+	if a.Manifests == nil {
+		return syn.ChangeSet{}, errors.New("detect: no manifest source configured")
+	}
+	current, baseline, sizes, err := a.Manifests(projectRoot)
 	if err != nil {
-		return err
+		return syn.ChangeSet{}, err
 	}
-	return json.Unmarshal(b, v)
+	return syn.Diff(current, baseline, sizes), nil
 }
 
 func dedupe(in []string) []string {
@@ -0,0 +1,117 @@
+package uiapi
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"Portsy/backend"
+	syn "Portsy/backend/internal/sync"
+)
+
+// TestDetectChangesMatchesDiffManifests checks the GUI's detect view and the
+// CLI's diff agree on the same project. syn.Diff folds a delete+add of the
+// same bytes into a rename, so renames are unfolded before comparing.
+func TestDetectChangesMatchesDiffManifests(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits func(t *testing.T, dir string)
+	}{
+		{name: "unchanged", edits: func(t *testing.T, dir string) {}},
+		{name: "added", edits: func(t *testing.T, dir string) {
+			write(t, filepath.Join(dir, "Samples", "snare.wav"), "snare")
+		}},
+		{name: "modified", edits: func(t *testing.T, dir string) {
+			write(t, filepath.Join(dir, "Set.als"), "set v2")
+		}},
+		{name: "deleted", edits: func(t *testing.T, dir string) {
+			remove(t, filepath.Join(dir, "Samples", "kick.wav"))
+		}},
+		{name: "renamed", edits: func(t *testing.T, dir string) {
+			remove(t, filepath.Join(dir, "Samples", "kick.wav"))
+			write(t, filepath.Join(dir, "Samples", "kick 2.wav"), "kick")
+		}},
+		{name: "everything", edits: func(t *testing.T, dir string) {
+			write(t, filepath.Join(dir, "Set.als"), "set v2")
+			remove(t, filepath.Join(dir, "Samples", "kick.wav"))
+			write(t, filepath.Join(dir, "Samples", "hat.wav"), "hat")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			write(t, filepath.Join(dir, "Set.als"), "set")
+			write(t, filepath.Join(dir, "Samples", "kick.wav"), "kick")
+			ps, err := backend.BuildManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := backend.WriteCacheFromState(dir, ps, ps.Algo); err != nil {
+				t.Fatal(err)
+			}
+			tt.edits(t, dir)
+
+			a := &API{Manifests: backend.LocalManifests}
+			cs, err := a.diffProject(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range cs.Files {
+				if c.Type == syn.Renamed {
+					got = append(got, "deleted "+c.OldPath, "added "+c.Path)
+					continue
+				}
+				got = append(got, string(c.Type)+" "+c.Path)
+			}
+
+			cur, err := backend.BuildManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lc, err := backend.LoadLocalCache(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, c := range backend.DiffManifests(backend.ManifestFromState(cur), lc.Hashes()) {
+				want = append(want, c.Type+" "+c.Path)
+			}
+
+			sort.Strings(got)
+			sort.Strings(want)
+			if len(got) != len(want) {
+				t.Fatalf("DetectChanges = %v, DiffManifests = %v", got, want)
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("DetectChanges = %v, DiffManifests = %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffProjectNeedsManifestSource(t *testing.T) {
+	if _, err := (&API{}).diffProject(t.TempDir()); err == nil {
+		t.Fatal("diffProject with no Manifests: want error")
+	}
+}
+
+func write(t *testing.T, p, body string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func remove(t *testing.T, p string) {
+	t.Helper()
+	if err := os.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+}
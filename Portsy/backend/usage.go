@@ -0,0 +1,220 @@
+package backend
+
+import (
+	remote "Portsy/backend/remote"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StorageUsage is what a project occupies in R2.
+//
+// Exclusive is everything under the project's own prefix (per-project blobs,
+// R2 manifests, anything else), which only this project uses. Shared is the
+// global-layout blobs (KeySchemeGlobalBlobs) its states reference; they are
+// stored once for every project that has the same content, so summing
+// Shared across projects over-counts the bill.
+type StorageUsage struct {
+	Project string `json:"project"`
+
+	ExclusiveBytes   int64 `json:"exclusiveBytes"`
+	ExclusiveObjects int   `json:"exclusiveObjects"`
+	BlobBytes        int64 `json:"blobBytes"`
+	BlobObjects      int   `json:"blobObjects"`
+	ManifestBytes    int64 `json:"manifestBytes"`
+	ManifestObjects  int   `json:"manifestObjects"`
+	OtherBytes       int64 `json:"otherBytes"`
+	OtherObjects     int   `json:"otherObjects"`
+
+	// Only filled by ProjectStorageUsageWithStates.
+	SharedBytes   int64 `json:"sharedBytes"`
+	SharedObjects int   `json:"sharedObjects"`
+
+	// Only filled by TotalStorageUsage.
+	MonthlyCost float64 `json:"monthlyCost,omitempty"`
+}
+
+// TotalBytes is exclusive plus shared usage.
+func (u *StorageUsage) TotalBytes() int64 { return u.ExclusiveBytes + u.SharedBytes }
+
+// add files one object under the project's prefix; rel is the key relative
+// to "<project>/".
+func (u *StorageUsage) add(rel string, size int64) {
+	u.ExclusiveBytes += size
+	u.ExclusiveObjects++
+	switch {
+	case strings.HasPrefix(rel, "blobs/"):
+		u.BlobBytes += size
+		u.BlobObjects++
+	case strings.HasPrefix(rel, "manifests/"):
+		u.ManifestBytes += size
+		u.ManifestObjects++
+	default:
+		u.OtherBytes += size
+		u.OtherObjects++
+	}
+}
+
+// ProjectStorageUsage sums the objects under the project's R2 prefix
+// (exclusive usage only; see ProjectStorageUsageWithStates for shared).
+func ProjectStorageUsage(ctx context.Context, r2 *R2Client, project string) (*StorageUsage, error) {
+	prefix := r2.prefixed(project) + "/"
+	objs, err := r2.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("usage: %w", err)
+	}
+	u := &StorageUsage{Project: project}
+	for _, o := range objs {
+		u.add(strings.TrimPrefix(o.Key, prefix), o.Size)
+	}
+	return u, nil
+}
+
+// ProjectStorageUsageWithStates is ProjectStorageUsage plus the global blobs
+// any of the project's states reference, sized from one listing of the
+// global blob prefix.
+func ProjectStorageUsageWithStates(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project string) (*StorageUsage, error) {
+	u, err := ProjectStorageUsage(ctx, r2, project)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := meta.ListStateIDs(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("usage: %w", err)
+	}
+	global := r2.prefixed("blobs") + "/"
+	referenced := map[string]bool{}
+	for _, id := range ids {
+		st, err := meta.GetState(ctx, project, id)
+		if err != nil {
+			return nil, fmt.Errorf("usage: %w", err)
+		}
+		for _, fe := range st.Files {
+			if k := r2.KeyFor(st, project, fe); strings.HasPrefix(k, global) {
+				referenced[k] = true
+			}
+		}
+	}
+	if len(referenced) == 0 {
+		return u, nil
+	}
+	objs, err := r2.ListObjects(ctx, global)
+	if err != nil {
+		return nil, fmt.Errorf("usage: %w", err)
+	}
+	for _, o := range objs {
+		if referenced[o.Key] {
+			u.SharedBytes += o.Size
+			u.SharedObjects++
+		}
+	}
+	return u, nil
+}
+
+// SharedUsageName is the BucketUsage entry for global-layout blobs, which
+// belong to no single project.
+const SharedUsageName = "(shared)"
+
+// StoragePricing turns bytes into a rough monthly bill. GB are decimal, as
+// providers bill them.
+type StoragePricing struct {
+	PerGBMonth float64 `json:"perGbMonth"` // USD per GB-month
+	FreeGB     float64 `json:"freeGb"`     // free tier, taken off the bucket total only
+}
+
+// DefaultStoragePricing is Cloudflare R2 standard storage.
+var DefaultStoragePricing = StoragePricing{PerGBMonth: 0.015, FreeGB: 10}
+
+// StoragePriceEnv overrides DefaultStoragePricing.PerGBMonth (e.g. "0.023").
+const StoragePriceEnv = "PORTSY_STORAGE_PRICE_PER_GB"
+
+// StoragePricingFromEnv is DefaultStoragePricing with StoragePriceEnv
+// applied when it parses as a non-negative number.
+func StoragePricingFromEnv() StoragePricing {
+	p := DefaultStoragePricing
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(StoragePriceEnv)), 64); err == nil && v >= 0 {
+		p.PerGBMonth = v
+	}
+	return p
+}
+
+// Cost is the monthly price of n bytes, ignoring the free tier.
+func (p StoragePricing) Cost(n int64) float64 {
+	return float64(n) / 1e9 * p.PerGBMonth
+}
+
+// BucketUsage is the whole bucket broken down by project.
+type BucketUsage struct {
+	// Projects is sorted by exclusive bytes, largest first; each entry's
+	// MonthlyCost is its share at list price.
+	Projects []StorageUsage `json:"projects"`
+
+	// Shared holds the global blobs (SharedBytes/SharedObjects), counted
+	// once no matter how many projects reference them.
+	Shared StorageUsage `json:"shared"`
+
+	TotalBytes   int64          `json:"totalBytes"`
+	TotalObjects int            `json:"totalObjects"`
+	Pricing      StoragePricing `json:"pricing"`
+	MonthlyCost  float64        `json:"monthlyCost"` // whole bucket, after the free tier
+}
+
+// TotalStorageUsage lists the whole bucket once and aggregates it by
+// project prefix, priced with StoragePricingFromEnv.
+func TotalStorageUsage(ctx context.Context, r2 *R2Client) (*BucketUsage, error) {
+	return TotalStorageUsageWithPricing(ctx, r2, StoragePricingFromEnv())
+}
+
+// TotalStorageUsageWithPricing is TotalStorageUsage with explicit pricing.
+// Objects are streamed page by page, so memory grows with the number of
+// projects, not objects.
+func TotalStorageUsageWithPricing(ctx context.Context, r2 *R2Client, pricing StoragePricing) (*BucketUsage, error) {
+	root := r2.prefixed("")
+	if root != "" {
+		root += "/"
+	}
+	global := r2.prefixed("blobs") + "/"
+
+	out := &BucketUsage{Shared: StorageUsage{Project: SharedUsageName}, Pricing: pricing}
+	byProject := map[string]*StorageUsage{}
+	err := r2.WalkObjects(ctx, root, func(o StoredObject) error {
+		out.TotalBytes += o.Size
+		out.TotalObjects++
+		if strings.HasPrefix(o.Key, global) {
+			out.Shared.SharedBytes += o.Size
+			out.Shared.SharedObjects++
+			return nil
+		}
+		project, rel, _ := strings.Cut(strings.TrimPrefix(o.Key, root), "/")
+		u := byProject[project]
+		if u == nil {
+			u = &StorageUsage{Project: project}
+			byProject[project] = u
+		}
+		u.add(rel, o.Size)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("usage: %w", err)
+	}
+
+	for _, u := range byProject {
+		u.MonthlyCost = pricing.Cost(u.ExclusiveBytes)
+		out.Projects = append(out.Projects, *u)
+	}
+	sort.Slice(out.Projects, func(i, j int) bool {
+		a, b := out.Projects[i], out.Projects[j]
+		if a.ExclusiveBytes != b.ExclusiveBytes {
+			return a.ExclusiveBytes > b.ExclusiveBytes
+		}
+		return a.Project < b.Project
+	})
+	out.Shared.MonthlyCost = pricing.Cost(out.Shared.SharedBytes)
+	if billable := float64(out.TotalBytes)/1e9 - pricing.FreeGB; billable > 0 {
+		out.MonthlyCost = billable * pricing.PerGBMonth
+	}
+	return out, nil
+}
@@ -0,0 +1,240 @@
+package backend
+
+import (
+	corehash "Portsy/backend/internal/core/hash"
+	remote "Portsy/backend/remote"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Blob problem kinds reported by VerifyCommit.
+const (
+	BlobMissing      = "missing"
+	BlobSizeMismatch = "size"
+	BlobETagMismatch = "etag"
+	BlobHashMismatch = "hash"
+)
+
+// BlobProblem is one bad blob referenced by a commit.
+type BlobProblem struct {
+	Path   string `json:"path"`
+	Key    string `json:"key"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// VerifyReport lists every blob of a commit that failed verification.
+type VerifyReport struct {
+	Project  string        `json:"project"`
+	CommitID string        `json:"commitId"`
+	Checked  int           `json:"checked"` // distinct blobs
+	Problems []BlobProblem `json:"problems"`
+
+	// SignatureError is set when the commit fails SignaturePolicyFromEnv
+	// (signed by an untrusted key, bad signature, or unsigned when required).
+	SignatureError string `json:"signatureError,omitempty"`
+}
+
+// OK reports whether every blob passed and the signature (if checked) held.
+func (r *VerifyReport) OK() bool { return len(r.Problems) == 0 && r.SignatureError == "" }
+
+// BadKeys returns the keys to feed into PushOptions.ForceKeys for a repair push.
+func (r *VerifyReport) BadKeys() []string { return badKeys(r.Problems) }
+
+func badKeys(problems []BlobProblem) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, p := range problems {
+		if !seen[p.Key] {
+			seen[p.Key] = true
+			out = append(out, p.Key)
+		}
+	}
+	return out
+}
+
+// VerifyCommit checks that every blob of a commit (HEAD if commitID == "")
+// is intact in R2, and its signature under SignaturePolicyFromEnv.
+//   - fast pass: HeadObject per blob; compares size and, when the state
+//     recorded one, the upload ETag (single-part: MD5 of the bytes)
+//   - deep pass (deep=true): blobs that passed the fast pass are streamed
+//     and re-hashed with the state's algo
+//
+// A blob that fails the fast pass is never downloaded.
+func VerifyCommit(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project, commitID string, deep bool) (*VerifyReport, error) {
+	var st *ProjectState
+	var cm *CommitMeta
+	commitID, err := resolveCommitID(ctx, meta, project, commitID)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	if commitID == "" {
+		st, cm, err = meta.GetLatestState(ctx, project)
+	} else {
+		st, cm, err = meta.GetStateByCommit(ctx, project, commitID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("verify: read state: %w", err)
+	}
+	if st == nil {
+		return nil, fmt.Errorf("verify: no remote state for %q", project)
+	}
+	rep := &VerifyReport{Project: project, CommitID: commitID}
+	if cm != nil {
+		rep.CommitID = cm.ID
+	}
+	policy, err := SignaturePolicyFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	if err := policy.Check(cm, st); err != nil {
+		rep.SignatureError = err.Error()
+	}
+
+	// one check per distinct key
+	byKey := map[string]FileEntry{}
+	for _, fe := range st.Files {
+		k := r2.KeyFor(st, project, fe)
+		if _, ok := byKey[k]; !ok {
+			byKey[k] = fe
+		}
+	}
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rep.Checked = len(keys)
+
+	hasher := corehash.New(corehash.Algorithm(st.Algo))
+	problems, err := checkBlobs(ctx, keys, func(key string) *BlobProblem {
+		return checkBlob(ctx, r2, hasher, key, byKey[key], deep)
+	})
+	rep.Problems = problems
+	return rep, err
+}
+
+// ErrPushVerifyFailed is returned by PushProjectWithOptions with
+// PushOptions.VerifyAfter set when the new commit fails verification. The
+// commit stays HEAD: repair it by pushing again with the report's BadKeys as
+// PushOptions.ForceKeys.
+var ErrPushVerifyFailed = errors.New("pushed commit failed verification")
+
+// verifyPushed is PushOptions.VerifyAfter: VerifyCommit's fast pass over the
+// commit just written, read back from the MetaStore rather than trusted from
+// the push's own bookkeeping.
+func verifyPushed(ctx context.Context, meta *remote.MetaStore, r2 *R2Client, project, commitID string, onVerified func(*VerifyReport)) error {
+	rep, err := VerifyCommit(ctx, meta, r2, project, commitID, false)
+	if err != nil {
+		return fmt.Errorf("push %s: %w", project, err)
+	}
+	if onVerified != nil {
+		onVerified(rep)
+	}
+	if rep.OK() {
+		log.Printf("push %s: verified %d blob(s) in commit %s", project, rep.Checked, ShortCommitID(rep.CommitID))
+		return nil
+	}
+	for _, p := range rep.Problems {
+		log.Printf("push %s: ✗ %s [%s] %s %s", project, p.Path, p.Kind, p.Key, p.Detail)
+	}
+	if len(rep.Problems) == 0 {
+		return fmt.Errorf("push %s: %w: signature: %s", project, ErrPushVerifyFailed, rep.SignatureError)
+	}
+	return fmt.Errorf("push %s: %w: %d of %d blob(s) bad (first: %s)", project, ErrPushVerifyFailed, len(rep.Problems), rep.Checked, rep.Problems[0].Path)
+}
+
+// checkBlob verifies one blob against the entry that references it (see
+// VerifyCommit for the fast and deep passes). nil means it's intact.
+func checkBlob(ctx context.Context, r2 *R2Client, hasher corehash.Hasher, key string, fe FileEntry, deep bool) *BlobProblem {
+	bad := func(kind, detail string) *BlobProblem {
+		return &BlobProblem{Path: fe.Path, Key: key, Kind: kind, Detail: detail}
+	}
+
+	info, err := r2.Head(ctx, key)
+	switch {
+	case err != nil:
+		return bad(BlobMissing, err.Error())
+	case info == nil:
+		return bad(BlobMissing, "")
+	// A reused render (see reuseRender) can differ in size; its ETag still
+	// pins the blob.
+	case info.Size != fe.Size && (fe.AudioHash == "" || fe.ETag == ""):
+		return bad(BlobSizeMismatch, fmt.Sprintf("want %d bytes, got %d", fe.Size, info.Size))
+	case fe.ETag != "" && info.ETag != fe.ETag:
+		return bad(BlobETagMismatch, fmt.Sprintf("want %s, got %s", fe.ETag, info.ETag))
+	}
+	if !deep {
+		return nil
+	}
+
+	body, err := r2.OpenReader(ctx, key)
+	if err != nil {
+		return bad(BlobMissing, err.Error())
+	}
+	defer body.Close()
+	sum, err := hasher.Reader(body)
+	if err != nil {
+		return bad(BlobHashMismatch, err.Error())
+	}
+	if sum != fe.Hash && !blobSameAudio(ctx, r2, key, fe) {
+		return bad(BlobHashMismatch, fmt.Sprintf("got %s", sum))
+	}
+	return nil
+}
+
+// blobSameAudio reads the blob at key again and reports whether it holds
+// fe's audio, for entries pointing at an earlier render (see reuseRender).
+func blobSameAudio(ctx context.Context, r2 *R2Client, key string, fe FileEntry) bool {
+	if fe.AudioHash == "" {
+		return false
+	}
+	body, err := r2.OpenReader(ctx, key)
+	if err != nil {
+		return false
+	}
+	defer body.Close()
+	return sameAudioReader(body, fe)
+}
+
+// checkBlobs runs check over keys on a bounded worker pool and returns the
+// problems in key order.
+func checkBlobs(ctx context.Context, keys []string, check func(key string) *BlobProblem) ([]BlobProblem, error) {
+	problems := make([]*BlobProblem, len(keys))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := max(2, runtime.NumCPU()/2)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				problems[i] = check(keys[i])
+			}
+		}()
+	}
+	for i := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []BlobProblem
+	for _, p := range problems {
+		if p != nil {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
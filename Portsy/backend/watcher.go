@@ -21,6 +21,16 @@ type SaveEvent struct {
 	DetectedAt  time.Time
 }
 
+// emitLog mirrors a watcher log line to the GUI's "log" event. Only a Wails
+// context carries the event bus; the CLI's watch mode passes a plain one,
+// where EventsEmit would exit the process.
+func emitLog(ctx context.Context, msg string) {
+	if ctx.Value("events") == nil {
+		return
+	}
+	runtime.EventsEmit(ctx, "log", msg)
+}
+
 // WatchProjectALS watches the project root and debounces top-level .als saves.
 func WatchProjectALS(
 	ctx context.Context,
@@ -37,7 +47,7 @@ func WatchProjectALS(
 	}
 
 	log.Printf("[WatchProjectALS] watching %s (als=%s)", projectName, alsPath)
-	runtime.EventsEmit(ctx, "log", fmt.Sprintf("[WatchProjectALS] watching %s (als=%s)", projectName, alsPath))
+	emitLog(ctx, fmt.Sprintf("[WatchProjectALS] watching %s (als=%s)", projectName, alsPath))
 
 	// Normalize/prefetch lowercase forms for case-insensitive filesystems
 	mkLC := func(p string) string { return strings.ToLower(filepath.Clean(p)) }
@@ -112,7 +122,7 @@ func WatchProjectALS(
 				alsPathLC = mkLC(alsPath)
 				alsBaseLC = strings.ToLower(filepath.Base(alsPathLC))
 				log.Printf("[WatchProjectALS] ALS path updated -> %s", alsPath)
-				runtime.EventsEmit(ctx, "log", fmt.Sprintf("[WatchProjectALS] ALS path updated -> %s", alsPath))
+				emitLog(ctx, fmt.Sprintf("[WatchProjectALS] ALS path updated -> %s", alsPath))
 			}
 		}
 		if err := waitFileStable(alsPath, 150*time.Millisecond, 10); err == nil {
@@ -141,7 +151,7 @@ func WatchProjectALS(
 			baseLC := strings.ToLower(filepath.Base(nameLC))
 
 			log.Printf("[fsnotify] %s op=%v", ev.Name, ev.Op)
-			runtime.EventsEmit(ctx, "log", fmt.Sprintf("[fsnotify] %s op=%v", ev.Name, ev.Op))
+			emitLog(ctx, fmt.Sprintf("[fsnotify] %s op=%v", ev.Name, ev.Op))
 
 			// Only care about top-level files in the project folder
 			if filepath.Dir(nameLC) != projDirLC {
@@ -159,7 +169,7 @@ func WatchProjectALS(
 					alsPathLC = mkLC(alsPath)
 					alsBaseLC = strings.ToLower(filepath.Base(alsPathLC))
 					log.Printf("[WatchProjectALS] path replaced -> %s", alsPath)
-					runtime.EventsEmit(ctx, "log", fmt.Sprintf("[WatchProjectALS] path replaced -> %s", alsPath))
+					emitLog(ctx, fmt.Sprintf("[WatchProjectALS] path replaced -> %s", alsPath))
 				}
 				schedule()
 				continue
@@ -168,7 +178,7 @@ func WatchProjectALS(
 		case err := <-w.Errors:
 			if err != nil {
 				log.Printf("[fsnotify:error] %v", err)
-				runtime.EventsEmit(ctx, "log", fmt.Sprintf("[fsnotify:error] %v", err))
+				emitLog(ctx, fmt.Sprintf("[fsnotify:error] %v", err))
 			}
 
 		case <-tmrC:
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// watchReadyDelay gives fsnotify time to register before a test writes.
+const watchReadyDelay = 200 * time.Millisecond
+
+func TestWatchProjectALSDebounce(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits on real debounce timers")
+	}
+	const debounce = 100 * time.Millisecond
+	tests := []struct {
+		name   string
+		bursts [][]string // files (project-relative) written per burst, 20ms apart
+		want   int32
+	}{
+		{name: "one save", bursts: [][]string{{"Song.als"}}, want: 1},
+		{name: "burst coalesces", bursts: [][]string{{"Song.als", "Song.als", "Song.als", "Song.als", "Song.als"}}, want: 1},
+		{name: "separate bursts", bursts: [][]string{{"Song.als", "Song.als"}, {"Song.als"}}, want: 2},
+		{name: "backup and temp files", bursts: [][]string{{"Song.als~", "Song.als.tmp"}}},
+		{name: "nested set", bursts: [][]string{{"Backup/Song [2024].als"}}},
+		{name: "other files", bursts: [][]string{{"Samples/kick.wav", "notes.txt"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "Song")
+			writeTestFile(t, filepath.Join(dir, "Song.als"), "v0")
+			if err := os.MkdirAll(filepath.Join(dir, "Backup"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			var saves atomic.Int32
+			ctx, cancel := context.WithCancel(context.Background())
+			errc := make(chan error, 1)
+			go func() {
+				errc <- WatchProjectALS(ctx, "Song", dir, debounce, func(SaveEvent) { saves.Add(1) })
+			}()
+			time.Sleep(watchReadyDelay)
+
+			n := 0
+			for i, burst := range tt.bursts {
+				if i > 0 {
+					time.Sleep(debounce + time.Second) // past the debounce and stability check
+				}
+				for _, rel := range burst {
+					n++
+					writeTestFile(t, filepath.Join(dir, filepath.FromSlash(rel)), fmt.Sprintf("v%d", n))
+					time.Sleep(20 * time.Millisecond)
+				}
+			}
+			time.Sleep(debounce + time.Second)
+			cancel()
+			if err := <-errc; err != context.Canceled {
+				t.Errorf("WatchProjectALS = %v, want context.Canceled", err)
+			}
+			if got := saves.Load(); got != tt.want {
+				t.Errorf("onSave called %d times, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWatchAllProjectsChurn creates and deletes project folders as fast as it
+// can while the root is watched; run with -race. Every watcher must be gone
+// once WatchAllProjects returns.
+func TestWatchAllProjectsChurn(t *testing.T) {
+	if testing.Short() {
+		t.Skip("churns real folders under fsnotify")
+	}
+	tests := []struct {
+		name     string
+		projects int
+		rounds   int
+	}{
+		{name: "one project", projects: 1, rounds: 20},
+		{name: "many projects", projects: 8, rounds: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, filepath.Join(root, "Existing", "Existing.als"), "set")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errc := make(chan error, 1)
+			go func() {
+				errc <- WatchAllProjects(ctx, root, 50*time.Millisecond, func(SaveEvent) {})
+			}()
+			time.Sleep(watchReadyDelay)
+
+			for r := 0; r < tt.rounds; r++ {
+				for p := 0; p < tt.projects; p++ {
+					name := fmt.Sprintf("P%d", p)
+					writeTestFile(t, filepath.Join(root, name, name+".als"), "set")
+				}
+				time.Sleep(time.Duration(r%4) * 100 * time.Millisecond) // sometimes let the rescan run
+				for p := 0; p < tt.projects; p++ {
+					if err := os.RemoveAll(filepath.Join(root, fmt.Sprintf("P%d", p))); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			cancel()
+			select {
+			case err := <-errc:
+				if err != context.Canceled {
+					t.Errorf("WatchAllProjects = %v, want context.Canceled", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("WatchAllProjects did not return after cancel")
+			}
+		})
+	}
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
-	backend "Portsy/backend/remote"
+	"Portsy/backend"
+	remote "Portsy/backend/remote"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,7 +31,7 @@ func mustEnv(key string) string {
 	return v
 }
 
-func checkFirestore(ctx context.Context, meta *backend.MetaStore) error {
+func checkFirestore(ctx context.Context, meta *remote.MetaStore) error {
 	testProj := "portsy-selftest"
 	commit := backend.CommitMeta{
 		ID:        uuid.NewString(),
@@ -54,6 +59,10 @@ func checkFirestore(ctx context.Context, meta *backend.MetaStore) error {
 }
 
 func checkR2(ctx context.Context, r2 *backend.R2Client) error {
+	if err := r2.Ping(ctx); err != nil {
+		return err
+	}
+	log.Println("✓ R2: credentials ok")
 	key := fmt.Sprintf("selftest/%s.txt", uuid.NewString())
 	data := []byte("portsy r2 ping")
 	if err := r2.UploadReader(ctx, bytes.NewReader(data), key); err != nil {
@@ -78,21 +87,40 @@ func checkR2(ctx context.Context, r2 *backend.R2Client) error {
 	return nil
 }
 
+func printScrubReport(rep *backend.ScrubReport) {
+	for _, p := range rep.Problems {
+		fmt.Printf("[scrub] ✗ %s: %s [%s] %s %s\n", rep.Project, p.Path, p.Kind, p.Key, p.Detail)
+	}
+	for _, id := range rep.UnreadableStates {
+		fmt.Printf("[scrub] ⚠ %s: state %s unreadable\n", rep.Project, id)
+	}
+	if !rep.OK() {
+		fmt.Printf("[scrub] repair from a machine with the files: -mode=push -project %q -force-keys %q\n", rep.Project, strings.Join(rep.BadKeys(), ","))
+		return
+	}
+	fmt.Printf("[scrub] %s: %d of %d blob(s) re-hashed ✓\n", rep.Project, rep.Sampled, rep.Total)
+}
+
 // smokePush uploads all files using the SAME key builder as production,
 // then BeginCommit -> FinalizeCommit with verify(hash->key).
-func smokePush(ctx context.Context, meta *backend.MetaStore, r2 *backend.R2Client, projectName, projectPath, message string) {
+func smokePush(ctx context.Context, meta *remote.MetaStore, r2 *backend.R2Client, projectName, projectPath, message string) {
 	// 1) Build manifest/state
 	st, err := backend.BuildManifest(projectPath)
 	if err != nil {
 		log.Fatalf("manifest: %v", err)
 	}
 	log.Printf("manifest: %d file(s)", len(st.Files))
+	scheme, err := meta.GetKeyScheme(ctx, projectName)
+	if err != nil {
+		log.Fatalf("key scheme: %v", err)
+	}
+	st.KeyScheme = scheme
 
 	// 2) Idempotent upload/ensure every blob
 	up := 0
 	for i := range st.Files {
 		fe := &st.Files[i]
-		fe.R2Key = r2.BuildKey(projectName, fe.Hash)
+		fe.R2Key = r2.BuildKeyScheme(scheme, projectName, fe.Hash)
 		abs := filepath.Join(projectPath, filepath.FromSlash(fe.Path))
 
 		if err := r2.UploadIfMissing(ctx, abs, fe.R2Key); err != nil {
@@ -115,26 +143,120 @@ func smokePush(ctx context.Context, meta *backend.MetaStore, r2 *backend.R2Clien
 	log.Printf("commit %s: pending", cm.ID)
 
 	// 4) Finalize with verify(hash -> SAME key)
-	verify := func(ctx context.Context, sha string) error {
-		key := r2.BuildKey(projectName, sha)
-		ok, err := r2.Exists(ctx, key)
-		if err != nil {
-			return err
-		}
-		if !ok {
-			return fmt.Errorf("missing blob %s", key)
-		}
-		return nil
-	}
-	if err := meta.FinalizeCommit(ctx, projectName, cm, st, verify); err != nil {
+	// (HEAD-not-found right after the upload is retried; see VerifyUploaded)
+	if err := meta.FinalizeCommit(ctx, projectName, cm, st, r2.BlobVerifier(scheme, projectName)); err != nil {
 		log.Fatalf("finalize: %v", err)
 	}
 	log.Printf("commit %s: FINAL ✓", cm.ID)
 }
 
+// changeSummary renders a project's pending changes as "(+added ~modified -deleted)".
+func changeSummary(c backend.ProjectChange) string {
+	return fmt.Sprintf("(+%d ~%d -%d)", c.Added, c.Modified, c.Deleted)
+}
+
+// askYesNo prints prompt + " [y/N]: " to w and reads one answer from stdin.
+func askYesNo(w io.Writer, prompt string) bool {
+	fmt.Fprintf(w, "%s [y/N]: ", prompt)
+	var resp string
+	_, _ = fmt.Scanln(&resp)
+	resp = strings.TrimSpace(strings.ToLower(resp))
+	return resp == "y" || resp == "yes"
+}
+
+// interactive reports whether stdin is a terminal (the GUI spawns us without one).
+func interactive() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmSuspect lists files that look accidentally included and asks
+// whether to push anyway.
+func confirmSuspect(files []backend.SuspectFile) bool {
+	fmt.Fprintf(os.Stderr, "⚠ %d file(s) look like they shouldn't be pushed:\n", len(files))
+	for _, sf := range files {
+		fmt.Fprintf(os.Stderr, "  - %s: %s\n    to exclude it, add to .portsyignore: %s\n", sf.Path, sf.Detail, sf.IgnorePattern)
+	}
+	return askYesNo(os.Stderr, "Push them anyway?")
+}
+
+// warnDeleteErrors lists local files a -force pull couldn't remove.
+func warnDeleteErrors(stats *backend.PullStats) {
+	if stats == nil || len(stats.DeleteErrors) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "⚠ %d file(s) not in the pulled version could not be deleted (open in Live?):\n", len(stats.DeleteErrors))
+	for _, de := range stats.DeleteErrors {
+		fmt.Fprintf(os.Stderr, "  - %s: %s\n", de.Path, de.Error)
+	}
+}
+
+// confirmFirstPush explains why a never-pushed folder looks wrong and asks
+// whether to upload it anyway.
+func confirmFirstPush(c backend.FirstPushCheck) bool {
+	fmt.Fprintf(os.Stderr, "⚠ %q has never been pushed and doesn't look like a Live project:\n", c.Project)
+	for _, r := range c.Reasons {
+		fmt.Fprintf(os.Stderr, "  - %s\n", r)
+	}
+	return askYesNo(os.Stderr, fmt.Sprintf("Upload all %d file(s) (%s)?", c.Files, backend.HumanBytes(c.Bytes)))
+}
+
+// firstPushConfirmer is -yes (always), a prompt on a terminal, or nil
+// (refuse) when run from the GUI or a script.
+func firstPushConfirmer(yes bool) func(backend.FirstPushCheck) bool {
+	switch {
+	case yes:
+		return func(backend.FirstPushCheck) bool { return true }
+	case interactive():
+		return confirmFirstPush
+	}
+	return nil
+}
+
+// storeCredential saves one secret, read from stdin, in the OS keychain.
+func storeCredential(name string) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatalf("read secret: %v", err)
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		log.Fatal("empty secret on stdin")
+	}
+	if err := backend.StoreCredential(name, secret); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("stored %s in the OS keychain; set %s=keychain to use it", name, backend.CredentialSourceEnv)
+}
+
 func main() {
 	// Load .env with override semantics
 	_ = godotenv.Overload(".env", "../.env", "../../.env")
+	backend.SetCaseSensitivePathsFromEnv()
+
+	// Keychain setup runs before anything needs credentials:
+	//   portsy store-credential R2_SECRET_KEY < secret.txt
+	if len(os.Args) == 3 && os.Args[1] == "store-credential" {
+		storeCredential(os.Args[2])
+		return
+	}
+	// New commit-signing key pair, no credentials needed:
+	//   portsy keygen
+	if len(os.Args) == 2 && os.Args[1] == "keygen" {
+		pub, priv, err := backend.GenerateSigningKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("PORTSY_SIGNING_KEY=%s\n", priv)
+		fmt.Printf("public key (add to collaborators' PORTSY_TRUSTED_KEYS): %s\n", pub)
+		fmt.Println("keep the signing key secret, e.g.: portsy store-credential PORTSY_SIGNING_KEY")
+		return
+	}
+	// PORTSY_CREDENTIALS=keychain: secrets stored in the OS keychain override .env.
+	if err := backend.LoadKeychainEnv(); err != nil {
+		log.Fatalf("keychain: %v", err)
+	}
+	saJSON := []byte(os.Getenv(backend.GCPServiceAccountJSON))
 
 	// Normalize GOOGLE_APPLICATION_CREDENTIALS to absolute path if relative
 	cred := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
@@ -143,50 +265,150 @@ func main() {
 			cred = abs
 		}
 	}
-	if _, err := os.Stat(cred); err != nil {
-		log.Fatalf("GOOGLE_APPLICATION_CREDENTIALS not found at %q: %v", cred, err)
+	// FIRESTORE_EMULATOR_HOST points at a local emulator; no credentials are used.
+	emulatorHost := os.Getenv(backend.EmulatorHostEnv)
+	// With impersonation (Cloud Run / workload identity) a key file is optional; ADC is the base.
+	impersonateSA := os.Getenv("GCP_IMPERSONATE_SA")
+	if emulatorHost == "" && len(saJSON) == 0 && (cred != "" || impersonateSA == "") {
+		if _, err := os.Stat(cred); err != nil {
+			log.Fatalf("GOOGLE_APPLICATION_CREDENTIALS not found at %q: %v", cred, err)
+		}
 	}
 
-	metaCfg := backend.MetaStoreConfig{
-		GCPProjectID:      mustEnv("GCP_PROJECT_ID"),
-		ServiceAccountKey: cred,
+	metaCfg := remote.MetaStoreConfig{
+		GCPProjectID:              mustEnv("GCP_PROJECT_ID"),
+		ServiceAccountKey:         cred,
+		ServiceAccountJSON:        saJSON,
+		ImpersonateServiceAccount: impersonateSA,
 	}
+	// PORTSY_COMPRESS_STATES=1 stores new commit file lists gzipped (see StateStorageGzip).
+	metaCfg.CompressStates, _ = strconv.ParseBool(os.Getenv("PORTSY_COMPRESS_STATES"))
+	// PORTSY_MANIFESTS_IN_R2=1 keeps new file lists in R2 with a pointer in Firestore.
+	metaCfg.ManifestsInR2, _ = strconv.ParseBool(os.Getenv("PORTSY_MANIFESTS_IN_R2"))
+	// PORTSY_ACTOR names who the audit log attributes changes to (default user@host).
+	metaCfg.Actor = os.Getenv("PORTSY_ACTOR")
+	metaCfg.EmulatorHost = emulatorHost
+	// PORTSY_SIGNING_KEY (see `portsy keygen`) signs every commit this client writes.
+	signingKey, err := backend.SigningKeyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	metaCfg.SigningKey = signingKey
 
 	var (
-		mode        = flag.String("mode", "check", "check | scan | push | pull | rollback | watch | pending | diff | smoke")
+		mode        = flag.String("mode", "check", "check | scan | push | pull | rollback | watch | pending | diff | explain | smoke | doctor | create | export | import | migrate | migrate-gc | gc | migrate-manifests | verify | scrub | reconcile | storage-check | rebind | relocate | clone | discover | usage | blame | files | preview | peaks | audit | als-diff | als-backups | push-all | pull-all")
 		root        = flag.String("root", "", "projects root (scan/push/watch)")
 		projectName = flag.String("project", "", "project name (push/pull/rollback/watch/smoke)")
 		msg         = flag.String("msg", "test push", "commit message (push/smoke)")
-		dest        = flag.String("dest", "", "destination for pull/rollback (defaults to <root>/<project>)")
-		commitID    = flag.String("commit", "", "commit ID (rollback or pull specific commit)")
+		dest        = flag.String("dest", "", "destination for pull/rollback (defaults to <root>/<project>) or zip path for export")
+		commitID    = flag.String("commit", "", "commit ID or unique prefix (rollback or pull specific commit)")
 		force       = flag.Bool("force", false, "allow deleting local files not in target state (pull)")
 		jsonOut     = flag.Bool("json", false, "emit JSON (for scan|pending|diff)")
+		forceKeys   = flag.String("force-keys", "", "comma-separated blob keys to re-upload even if present (push repair)")
+		strict      = flag.Bool("strict", false, "fail the push if any file could not be read")
+		maxFileMB   = flag.Int64("max-file-mb", 0, "flag files larger than this many MiB before pushing (push/doctor, 0 = 2048, -1 = off)")
+		inFlightMB  = flag.Int64("max-inflight-mb", 0, "cap the MiB of large files uploading at once (push, 0 = no cap)")
+		audioFP     = flag.Bool("audio-fp", false, "skip re-uploading WAV/AIFF files whose audio is unchanged (push)")
+		peaks       = flag.Bool("peaks", false, "store waveform peaks for new WAV/AIFF files (push, push-all)")
+		verifyAfter = flag.Bool("verify-after", false, "HEAD every blob of the new commit once it's in and fail if any is missing or the wrong size (push, push-all)")
+		deep        = flag.Bool("deep", false, "download and re-hash every blob (verify)")
+		scrubRate   = flag.Float64("scrub-rate", 0.01, "fraction of blobs to download and re-hash per pass (scrub, watch -scrub-every)")
+		scrubEvery  = flag.Duration("scrub-every", 0, "scrub a sample of remote blobs at this interval while watching, e.g. 1h (watch, 0 = off)")
+		dryRun      = flag.Bool("dry-run", false, "report what would be deleted without deleting (migrate-gc, gc)")
+		backup      = flag.Bool("backup", false, "move local files a pull would overwrite/delete into .portsy/backup (pull)")
+		readOnly    = flag.Bool("readonly", false, "plain copy for listening: write nothing under .portsy and don't track the folder (pull)")
+		noIcon      = flag.Bool("no-folder-icon", false, "leave the folder's icon alone (pull, pull-all; default: on for Windows, $PORTSY_FOLDER_ICON=1 elsewhere)")
+		pruneEmpty  = flag.Bool("prune-empty", false, "remove folders the pull leaves empty (pull; rollback always does)")
+		pullPaths   = flag.String("paths", "", "comma-separated files or folders to pull instead of the whole project (pull; never deletes)")
+		resumeFrom  = flag.String("resume-from", "", "pull checkpoint to resume from instead of .portsy/pull-journal.json (pull)")
+		against     = flag.String("against", "cache", "what diff compares the files on disk with: cache (last sync) | remote (remote HEAD)")
+		presigned   = flag.Bool("presigned", false, "fetch large files through presigned URLs (pull)")
 		autoPush    = flag.Bool("autopush", false, "if set, push automatically after collect (watch)")
+		workers     = flag.Int("workers", 0, "parallel project scans for pending (0 = auto)")
+		keyScheme   = flag.Int("scheme", int(backend.KeySchemeGlobalBlobs), "target blob key scheme (migrate): 1=project blobs, 2=global blobs")
+		confirm     = flag.Bool("confirm", false, "ask before pushing each changed project (push-all)")
+		yes         = flag.Bool("yes", false, "answer yes to every prompt (push-all -confirm, watch, first push of an unusual folder); gc only deletes with -yes")
+		remoteName  = flag.String("remote", "", "remote project to bind the local folder to (rebind)")
+		cloneAs     = flag.String("as", "", "name of the new remote project (clone)")
+		keepRecent  = flag.Int("keep", 0, "newest final commits whose blobs gc keeps (0 = all commits)")
+		protect     = flag.String("protect", "", "comma-separated commit IDs whose blobs gc never deletes")
+		gcDelOnly   = flag.Bool("deletable-only", false, "gc: only list blobs that would be deleted")
+		filePath    = flag.String("file", "", "project-relative file path (blame)")
+		explainPath = flag.String("path", "", "file to explain, relative to the project folder (explain)")
+		fromALS     = flag.String("from", "", "older .als file (als-diff); the old projects root (relocate)")
+		toALS       = flag.String("to", "", "newer .als file (als-diff)")
+		restore     = flag.String("restore", "", "backup name to put back as the project's .als (als-backups)")
+		limit       = flag.Int("limit", 0, "max commits to inspect (blame) or entries to list (audit), 0 = default")
+		verbose     = flag.Bool("verbose", false, "log every file's decision and result (push/pull/diff)")
+		pricePerGB  = flag.Float64("price-per-gb", -1, "storage price in USD per GB-month for the usage estimate (-1 = $PORTSY_STORAGE_PRICE_PER_GB or R2's $0.015)")
+		metricsAddr = flag.String("metrics-addr", "", "serve transfer/Firestore counters in Prometheus format at http://<addr>/metrics (e.g. :9464)")
 	)
+	flag.BoolVar(verbose, "v", false, "shorthand for -verbose")
 	flag.Parse()
+	backend.SetLogger(backend.StdLogger{Debug: *verbose})
+
+	var metrics *backend.Counters
+	if *metricsAddr != "" {
+		metrics = &backend.Counters{}
+		metaCfg.Metrics = metrics
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Printf("metrics server: %v", err)
+			}
+		}()
+	}
 
 	ctx := context.Background()
 
-	meta, err := backend.NewMetaStore(ctx, metaCfg)
+	meta, err := remote.NewMetaStore(ctx, metaCfg)
 	if err != nil {
 		log.Fatalf("firestore init: %v", err)
 	}
 	defer meta.Close()
+	// Retry cache.json writes that failed after a sync (see FlushCacheWrites).
+	defer func() {
+		if err := backend.FlushCacheWrites(); err != nil {
+			log.Printf("%v", err)
+		}
+	}()
 
+	// R2_ENDPOINT points at any S3-compatible store instead (MinIO, B2, S3);
+	// R2_PATH_STYLE=1 for stores that need path-style bucket addressing.
 	r2Cfg := backend.R2Config{
-		AccountID: mustEnv("R2_ACCOUNT_ID"),
+		Endpoint:  os.Getenv("R2_ENDPOINT"),
 		AccessKey: mustEnv("R2_ACCESS_KEY"),
 		SecretKey: mustEnv("R2_SECRET_KEY"),
 		Bucket:    mustEnv("R2_BUCKET"),
 		Region:    os.Getenv("R2_REGION"),
 	}
+	if r2Cfg.Endpoint == "" {
+		r2Cfg.AccountID = mustEnv("R2_ACCOUNT_ID")
+	}
+	r2Cfg.UsePathStyle, _ = strconv.ParseBool(os.Getenv("R2_PATH_STYLE"))
+	r2Cfg.TempDir = os.Getenv(backend.TempDirEnv) // scratch space for .part downloads
+	if metrics != nil {
+		r2Cfg.Metrics = metrics
+	}
+	// PORTSY_LARGE_FILE_MB=N sends files of N MiB or more through a
+	// bigger-part uploader (see R2Config.LargeFileThreshold).
+	if mb, err := strconv.ParseInt(os.Getenv("PORTSY_LARGE_FILE_MB"), 10, 64); err == nil && mb > 0 {
+		r2Cfg.LargeFileThreshold = mb << 20
+	}
+	// PORTSY_VERIFY_RETRIES=N: extra HEADs before finalize calls a just-uploaded
+	// blob missing (see R2Config.VerifyRetries; -1 = none).
+	if n, err := strconv.Atoi(os.Getenv("PORTSY_VERIFY_RETRIES")); err == nil {
+		r2Cfg.VerifyRetries = n
+	}
 	r2, err := backend.NewR2(ctx, r2Cfg)
 	if err != nil {
 		log.Fatalf("r2 init: %v", err)
 	}
+	meta.SetManifestBlobs(r2) // states stored as R2 manifests are readable in every mode
 
-	log.Printf("cfg: proj=%s r2[acct=%s bucket=%s region=%s key=%s...]",
-		metaCfg.GCPProjectID, r2Cfg.AccountID, r2Cfg.Bucket,
+	log.Printf("cfg: proj=%s r2[acct=%s endpoint=%s bucket=%s region=%s key=%s...]",
+		metaCfg.GCPProjectID, r2Cfg.AccountID, r2Cfg.Endpoint, r2Cfg.Bucket,
 		func() string {
 			if r2Cfg.Region == "" {
 				return "auto"
@@ -211,6 +433,35 @@ func main() {
 		}
 		log.Println("All checks passed 🎉")
 
+	case "storage-check":
+		// Full round trip against the configured bucket under selftest/; point
+		// R2_ENDPOINT (+ R2_PATH_STYLE) at a local MinIO to exercise it offline.
+		res, err := backend.CheckStorage(ctx, r2)
+		failed := 0
+		for _, c := range res {
+			if c.Err != "" {
+				failed++
+			}
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(res)
+		} else {
+			for _, c := range res {
+				if c.Err != "" {
+					fmt.Printf("FAIL %s: %s\n", c.Name, c.Err)
+				} else {
+					fmt.Printf("ok   %s\n", c.Name)
+				}
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if failed > 0 {
+			log.Fatalf("storage check: %d of %d step(s) failed", failed, len(res))
+		}
+		log.Printf("storage check: all %d steps passed", len(res))
+
 	case "smoke":
 		if *root == "" || *projectName == "" {
 			log.Fatal("smoke requires -root and -project")
@@ -257,13 +508,29 @@ func main() {
 		if sel == nil {
 			log.Fatalf("project %q not found under %s", *projectName, *root)
 		}
+		sel.Name = backend.RemoteProjectFor(projectPath, sel.Name) // honor -mode=rebind
 
 		cm := backend.CommitMeta{
 			ID:        uuid.NewString(),
 			Message:   *msg,
 			Timestamp: time.Now().Unix(),
 		}
-		if err := backend.PushProject(ctx, meta, r2, *sel, cm); err != nil {
+		opts := backend.PushOptions{FailOnSkipped: *strict, AudioFingerprint: *audioFP, GeneratePeaks: *peaks, MaxFileSize: *maxFileMB << 20, MaxInFlightBytes: *inFlightMB << 20, VerifyAfter: *verifyAfter}
+		if !*yes && interactive() {
+			opts.ConfirmSuspect = confirmSuspect
+		}
+		opts.ConfirmFirstPush = firstPushConfirmer(*yes)
+		for _, k := range strings.Split(*forceKeys, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				opts.ForceKeys = append(opts.ForceKeys, k)
+			}
+		}
+		var verified *backend.VerifyReport
+		opts.OnVerified = func(rep *backend.VerifyReport) { verified = rep }
+		if err := backend.PushProjectWithOptions(ctx, meta, r2, *sel, cm, opts); err != nil {
+			if errors.Is(err, backend.ErrPushVerifyFailed) && verified != nil && len(verified.Problems) > 0 {
+				fmt.Printf("repair with: -mode=push -project %q -force-keys %q\n", *projectName, strings.Join(verified.BadKeys(), ","))
+			}
 			log.Fatal(err)
 		}
 		if ps, err := backend.BuildManifest(projectPath); err == nil {
@@ -275,29 +542,121 @@ func main() {
 		}
 		log.Println("Push completed ✓")
 
+	case "push-all":
+		// Commit every project that changed since its last sync, one after another.
+		if *root == "" {
+			log.Fatal("push-all requires -root")
+		}
+		opts := backend.PushOptions{FailOnSkipped: *strict, AudioFingerprint: *audioFP, GeneratePeaks: *peaks, MaxFileSize: *maxFileMB << 20, MaxInFlightBytes: *inFlightMB << 20, VerifyAfter: *verifyAfter}
+		if !*yes && interactive() {
+			opts.ConfirmSuspect = confirmSuspect
+		}
+		opts.ConfirmFirstPush = firstPushConfirmer(*yes)
+		var include func(backend.ProjectChange) bool
+		if *confirm && !*yes {
+			include = func(c backend.ProjectChange) bool {
+				// Prompts go to stderr so -json output stays parseable.
+				return askYesNo(os.Stderr, fmt.Sprintf("Push %s %s?", c.Name, changeSummary(c)))
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		results, err := backend.PushAllSelected(ctx, meta, r2, *root, *msg, opts, include, func(p backend.PushAllProgress) {
+			if *jsonOut {
+				_ = enc.Encode(p)
+			} else if p.Status != backend.PushAllPushing {
+				mark := "✓"
+				if p.Status == backend.PushAllFailed {
+					mark = "✗"
+				}
+				fmt.Printf("[%d/%d] %s %s %s\n", p.Index, p.Total, mark, p.Project, p.Error)
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if results == nil {
+			results = []backend.PushAllResult{}
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		if *jsonOut {
+			_ = enc.Encode(map[string]any{"results": results})
+		}
+		if failed > 0 {
+			log.Fatalf("push-all: %d of %d project(s) failed", failed, len(results))
+		}
+		log.Printf("push-all: %d project(s) pushed ✓", len(results))
+
+	case "pull-all":
+		// Update every local project whose remote HEAD is ahead; never clobber local changes.
+		if *root == "" {
+			log.Fatal("pull-all requires -root")
+		}
+		opts := backend.PullOptions{AllowDelete: *force, UsePresigned: *presigned}
+		if *noIcon {
+			opts.SetFolderIcon = new(bool)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		results, err := backend.PullAll(ctx, meta, r2, *root, opts, func(p backend.PullAllProgress) {
+			if *jsonOut {
+				_ = enc.Encode(p)
+			} else if p.Status != backend.PullAllPulling {
+				fmt.Printf("[%d/%d] %s: %s %s\n", p.Index, p.Total, p.Project, p.Status, p.Reason)
+			}
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if results == nil {
+			results = []backend.PullAllResult{}
+		}
+		counts := map[string]int{}
+		for _, r := range results {
+			counts[r.Status]++
+		}
+		if *jsonOut {
+			_ = enc.Encode(map[string]any{"results": results})
+		}
+		log.Printf("pull-all: pulled=%d up-to-date=%d skipped=%d failed=%d",
+			counts[backend.PullAllPulled], counts[backend.PullAllUpToDate], counts[backend.PullAllSkipped], counts[backend.PullAllFailed])
+		if counts[backend.PullAllFailed] > 0 {
+			os.Exit(1)
+		}
+
 	case "pull":
 		if *projectName == "" {
 			log.Fatal("pull requires -project")
 		}
-		dst := *dest
-		if dst == "" {
-			base := *root
-			if base == "" {
-				cwd, _ := os.Getwd()
-				base = cwd
-			}
-			dst = filepath.Join(base, *projectName)
+		opts := backend.PullOptions{AllowDelete: *force, UsePresigned: *presigned, BackupChanged: *backup, ReadOnly: *readOnly, PruneEmptyDirs: *pruneEmpty, ResumeFrom: *resumeFrom}
+		if *noIcon {
+			opts.SetFolderIcon = new(bool)
 		}
-		if _, err := backend.PullProject(ctx, meta, r2, *projectName, dst, *commitID, *force); err != nil {
-			log.Fatal(err)
+		for _, p := range strings.Split(*pullPaths, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				opts.Paths = append(opts.Paths, p)
+			}
 		}
-		if ps, err := backend.BuildManifest(dst); err == nil {
-			algo := ps.Algo
-			if algo == "" {
-				algo = "sha256"
+		if *dest == "" {
+			// <root or cwd>/<project>, collision-safe
+			dst, stats, err := backend.PullInto(ctx, meta, r2, *projectName, *root, *commitID, opts)
+			if err != nil {
+				log.Fatal(err)
 			}
-			_ = backend.WriteCacheFromState(dst, ps, algo)
+			warnDeleteErrors(stats)
+			log.Printf("Pulled %q into %s ✓", *projectName, dst)
+			return
+		}
+		// Any folder name; the folder stays bound to -project for pushes.
+		dst := *dest
+		stats, err := backend.PullIntoDir(ctx, meta, r2, *projectName, dst, *commitID, opts)
+		if err != nil {
+			log.Fatal(err)
 		}
+		warnDeleteErrors(stats)
 		log.Printf("Pulled %q into %s ✓", *projectName, dst)
 
 	case "rollback":
@@ -306,12 +665,9 @@ func main() {
 		}
 		dst := *dest
 		if dst == "" {
-			base := *root
-			if base == "" {
-				cwd, _ := os.Getwd()
-				base = cwd
+			if dst, err = backend.ProjectDirFor(*root, *projectName); err != nil {
+				log.Fatal(err)
 			}
-			dst = filepath.Join(base, *projectName)
 		}
 		if err := backend.RollbackProject(ctx, meta, r2, *projectName, dst, *commitID); err != nil {
 			log.Fatal(err)
@@ -322,28 +678,42 @@ func main() {
 		rootFlag := flag.Lookup("root")
 		projectFlag := flag.Lookup("project")
 		if rootFlag == nil || rootFlag.Value.String() == "" {
-			fmt.Println(`usage: -mode=watch -root "<path>" [-project "<name>"] [-autopush]`)
+			fmt.Println(`usage: -mode=watch -root "<path>" [-project "<name>"] [-autopush|-yes]`)
 			return
 		}
 		rootPath := rootFlag.Value.String()
 
 		onSave := func(evt backend.SaveEvent) {
 			fmt.Printf("[watch] %s: %s saved @ %s\n", evt.ProjectName, filepath.Base(evt.ALSPath), evt.DetectedAt.Format(time.RFC3339))
-			copied, err := backend.CollectNewSamples(context.Background(), evt.ProjectPath, evt.ALSPath)
+			if err := backend.CheckALS(evt.ALSPath); errors.Is(err, backend.ErrCorruptALS) {
+				// Never push a broken set over the last good one.
+				fmt.Printf("[watch] ⚠ %v; not collecting or pushing %s\n", err, evt.ProjectName)
+				if good := backend.LastGoodALS(evt.ProjectPath, filepath.Base(evt.ALSPath)); good != "" {
+					fmt.Printf("[watch]   last good copy: %s\n", good)
+				}
+				return
+			}
+			res, err := backend.CollectNewSamplesResult(context.Background(), evt.ProjectPath, evt.ALSPath)
 			if err != nil {
 				fmt.Printf("[collect] error: %v\n", err)
-			} else if len(copied) > 0 {
-				fmt.Printf("[collect] copied %d sample(s) into Samples/Imported\n", len(copied))
+			} else if len(res.Copied) > 0 {
+				fmt.Printf("[collect] copied %d sample(s) into Samples/Imported\n", len(res.Copied))
 			} else {
 				fmt.Printf("[collect] no new samples to copy\n")
 			}
-			doPush := *autoPush
+			if res != nil && len(res.Unresolved) > 0 {
+				fmt.Printf("[collect] ⚠ %d sample(s) couldn't be found:\n", len(res.Unresolved))
+				for _, p := range res.Unresolved {
+					fmt.Printf("  - %s\n", p)
+				}
+			}
+			doPush := *autoPush || *yes
 			if !doPush {
-				fmt.Printf("Push changes to remote for \"%s\"? [y/N]: ", evt.ProjectName)
-				var resp string
-				_, _ = fmt.Scanln(&resp)
-				resp = strings.TrimSpace(strings.ToLower(resp))
-				doPush = resp == "y" || resp == "yes"
+				summary := ""
+				if c, err := backend.ProjectChangesSinceCache(evt.ProjectName, evt.ProjectPath); err == nil {
+					summary = " " + changeSummary(c)
+				}
+				doPush = askYesNo(os.Stdout, fmt.Sprintf("Push changes to remote for \"%s\"%s?", evt.ProjectName, summary))
 			}
 			if !doPush {
 				return
@@ -373,7 +743,23 @@ func main() {
 		if projectFlag != nil {
 			proj = strings.TrimSpace(projectFlag.Value.String())
 		}
+		if *scrubEvery > 0 {
+			var only []string
+			if proj != "" {
+				only = []string{proj}
+			}
+			go backend.RunScrubber(ctx, meta, r2, only, *scrubEvery, *scrubRate, func(rep *backend.ScrubReport, err error) {
+				if err != nil {
+					fmt.Printf("[scrub] error: %v\n", err)
+					return
+				}
+				printScrubReport(rep)
+			})
+		}
 		if proj == "" {
+			if warn, ok := backend.DetectCloudSyncConflict(rootPath); ok {
+				fmt.Printf("⚠ %s\n", warn)
+			}
 			fmt.Printf("Watching ALL projects under %s … (Ctrl+C to stop)\n", rootPath)
 			if err := backend.WatchAllProjects(ctx, rootPath, 750*time.Millisecond, onSave); err != nil {
 				fmt.Printf("watch error: %v\n", err)
@@ -381,6 +767,9 @@ func main() {
 			return
 		}
 		projectPath := filepath.Join(rootPath, proj)
+		if warn, ok := backend.DetectCloudSyncConflict(projectPath); ok {
+			fmt.Printf("⚠ %s\n", warn)
+		}
 		fmt.Printf("Watching %s … (Ctrl+C to stop)\n", projectPath)
 		if err := backend.WatchProjectALS(ctx, proj, projectPath, 750*time.Millisecond, onSave); err != nil {
 			fmt.Printf("watch error: %v\n", err)
@@ -391,7 +780,7 @@ func main() {
 			fmt.Println(`usage: -mode=pending -root "<path>" [-json]`)
 			return
 		}
-		changes, err := backend.ChangedProjectsSinceCache(*root)
+		changes, err := backend.ChangedProjectsSinceCacheN(*root, *workers)
 		if err != nil {
 			fmt.Printf("error: %v\n", err)
 			return
@@ -405,35 +794,585 @@ func main() {
 			return
 		}
 		for _, c := range changes {
-			fmt.Printf("- %s  (+%d ~%d -%d)  total %d\n", c.Name, c.Added, c.Modified, c.Deleted, c.Total)
+			fmt.Printf("- %s  %s  total %d\n", c.Name, changeSummary(c), c.Total)
 		}
 
 	case "diff":
 		if *root == "" || *projectName == "" {
-			fmt.Println(`usage: -mode=diff -root "<path>" -project "<name>" [-json]`)
+			fmt.Println(`usage: -mode=diff -root "<path>" -project "<name>" [-against cache|remote] [-json]`)
 			return
 		}
 		projectPath := filepath.Join(*root, *projectName)
-		ps, err := backend.BuildManifest(projectPath)
-		if err != nil {
-			fmt.Printf("manifest error: %v\n", err)
-			return
+		var changes []backend.FileChange
+		switch *against {
+		case "cache":
+			ps, err := backend.BuildManifest(projectPath)
+			if err != nil {
+				fmt.Printf("manifest error: %v\n", err)
+				return
+			}
+			cur := backend.ManifestFromState(ps)
+			lc, _ := backend.LoadLocalCache(projectPath)
+			changes = backend.DiffManifests(cur, lc.Hashes())
+		case "remote":
+			// "Do I need to push?", answered from the remote HEAD.
+			remoteName := backend.RemoteProjectFor(projectPath, *projectName)
+			if changes, err = backend.DiffAgainstRemote(ctx, meta, projectPath, remoteName); err != nil {
+				log.Fatal(err)
+			}
+		default:
+			log.Fatalf("unknown -against %q (cache | remote)", *against)
 		}
-		cur := backend.ManifestFromState(ps)
-		lc, _ := backend.LoadLocalCache(projectPath)
-		changes := backend.DiffManifests(cur, lc.Manifest)
 		if *jsonOut {
 			_ = json.NewEncoder(os.Stdout).Encode(changes)
 			return
 		}
 		if len(changes) == 0 {
-			fmt.Println("No local changes since last cache.")
+			if *against == "remote" {
+				fmt.Println("No differences from the remote HEAD.")
+			} else {
+				fmt.Println("No local changes since last cache.")
+			}
 			return
 		}
 		for _, ch := range changes {
 			fmt.Printf("%-8s %s\n", ch.Type, ch.Path)
 		}
 
+	case "explain":
+		// "Why isn't my file syncing?"
+		if *root == "" || *projectName == "" || *explainPath == "" {
+			fmt.Println(`usage: -mode=explain -root "<path>" -project "<name>" -path "<rel path>" [-json]`)
+			return
+		}
+		d, err := backend.ExplainPath(filepath.Join(*root, *projectName), *explainPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(d)
+			return
+		}
+		verdict := "not tracked"
+		if d.Tracked {
+			verdict = "tracked"
+		}
+		fmt.Printf("%s: %s (%s): %s\n", d.Path, verdict, d.Rule, d.Detail)
+
+	case "export":
+		if *projectName == "" {
+			log.Fatal("export requires -project")
+		}
+		out := *dest
+		if out == "" {
+			out = *projectName + ".zip"
+		}
+		if err := backend.ExportProjectZip(ctx, meta, r2, *projectName, *commitID, out); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Exported %q to %s ✓", *projectName, out)
+
+	case "import":
+		// First-time onboarding: hash + upload + commit in one go.
+		// Progress is printed as one JSON object per line on stdout for the GUI.
+		if *root == "" || *projectName == "" {
+			log.Fatal("import requires -root and -project")
+		}
+		projectPath := filepath.Join(*root, *projectName)
+		enc := json.NewEncoder(os.Stdout)
+		err := backend.ImportAndPush(ctx, meta, r2, projectPath, *projectName, *msg, func(p backend.ImportProgress) {
+			_ = enc.Encode(p)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Imported %q ✓", *projectName)
+
+	case "verify":
+		if *projectName == "" {
+			log.Fatal("verify requires -project")
+		}
+		rep, err := backend.VerifyCommit(ctx, meta, r2, *projectName, *commitID, *deep)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(rep)
+			return
+		}
+		for _, p := range rep.Problems {
+			fmt.Printf("✗ %s [%s] %s %s\n", p.Path, p.Kind, p.Key, p.Detail)
+		}
+		if rep.SignatureError != "" {
+			fmt.Printf("✗ signature: %s\n", rep.SignatureError)
+		}
+		if len(rep.Problems) > 0 {
+			fmt.Printf("repair with: -mode=push -project %q -force-keys %q\n", *projectName, strings.Join(rep.BadKeys(), ","))
+		}
+		if !rep.OK() {
+			log.Fatalf("verify: %d of %d blob(s) bad in commit %s", len(rep.Problems), rep.Checked, backend.ShortCommitID(rep.CommitID))
+		}
+		log.Printf("Verified %d blob(s) in commit %s ✓", rep.Checked, backend.ShortCommitID(rep.CommitID))
+
+	case "scrub":
+		// Re-hash a random sample of every version's blobs (bit-rot check).
+		if *projectName == "" {
+			log.Fatal("scrub requires -project")
+		}
+		rep, err := backend.ScrubProject(ctx, meta, r2, *projectName, *scrubRate)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(rep)
+			return
+		}
+		printScrubReport(rep)
+		if !rep.OK() {
+			os.Exit(1)
+		}
+
+	case "reconcile":
+		// Stored blobs vs state references: orphans (GC) and dangling keys (repair).
+		if *projectName == "" {
+			log.Fatal("reconcile requires -project")
+		}
+		rep, err := backend.ReconcileProject(ctx, meta, r2, *projectName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(rep)
+			return
+		}
+		for _, k := range rep.Orphans {
+			fmt.Printf("orphan   %s\n", k)
+		}
+		for _, p := range rep.Dangling {
+			fmt.Printf("dangling %s (%s)\n", p.Key, p.Path)
+		}
+		if rep.GlobalBlobs {
+			fmt.Println("note: some states use global blobs; those are shared and never listed as orphans")
+		}
+		if len(rep.Dangling) > 0 {
+			fmt.Printf("repair with: -mode=push -project %q -force-keys %q\n", *projectName, strings.Join(rep.BadKeys(), ","))
+		}
+		log.Printf("reconcile %s: %d stored, %d orphan(s), %d dangling", backend.ShortCommitID(rep.CommitID), rep.Stored, len(rep.Orphans), len(rep.Dangling))
+
+	case "als-backups":
+		// .als safety copies taken before pulls overwrote/deleted the set.
+		if *root == "" || *projectName == "" {
+			fmt.Println(`usage: -mode=als-backups -root "<path>" -project "<name>" [-restore "<backup name>"] [-json]`)
+			return
+		}
+		projectPath := filepath.Join(*root, *projectName)
+		if *restore != "" {
+			if err := backend.RestoreALSBackup(projectPath, *restore); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("Restored %s ✓", *restore)
+			return
+		}
+		backups, err := backend.ListALSBackups(projectPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(backups)
+			return
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %s  %s (%d bytes)\n", b.Created.Format(time.RFC3339), b.Name, b.Original, b.Size)
+		}
+
+	case "als-diff":
+		// Logical diff of two local sets, e.g. the current .als vs a Backup/ copy.
+		if *fromALS == "" || *toALS == "" {
+			fmt.Println(`usage: -mode=als-diff -from "<old.als>" -to "<new.als>" [-root "<project dir>"] [-json]`)
+			return
+		}
+		d, err := backend.DiffALSFiles(*fromALS, *toALS, *root)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(d)
+			return
+		}
+		if d.ParseError != "" {
+			fmt.Printf("⚠ %s (diff may be incomplete)\n", d.ParseError)
+		}
+		for _, s := range d.Samples.Added {
+			fmt.Printf("+ sample %s\n", s)
+		}
+		for _, s := range d.Samples.Removed {
+			fmt.Printf("- sample %s\n", s)
+		}
+		for _, c := range d.MIDI.AddedClips {
+			fmt.Printf("+ clip %s\n", c)
+		}
+		for _, c := range d.MIDI.RemovedClips {
+			fmt.Printf("- clip %s\n", c)
+		}
+		for _, c := range d.MIDI.ChangedClips {
+			fmt.Printf("~ clip %s\n", c)
+		}
+
+	case "discover":
+		// Project names found in the bucket, and whether metadata knows them.
+		names, err := backend.DiscoverProjects(ctx, r2)
+		if err != nil {
+			log.Fatal(err)
+		}
+		type found struct {
+			Name       string `json:"name"`
+			Registered bool   `json:"registered"`
+		}
+		rows := make([]found, 0, len(names))
+		for _, n := range names {
+			ok, err := meta.ProjectExists(ctx, n)
+			if err != nil {
+				log.Fatal(err)
+			}
+			rows = append(rows, found{Name: n, Registered: ok})
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(rows)
+			return
+		}
+		for _, f := range rows {
+			state := "registered"
+			if !f.Registered {
+				state = "NOT in metadata"
+			}
+			fmt.Printf("%-40s %s\n", f.Name, state)
+		}
+
+	case "clone":
+		// New remote project from an existing commit, without re-uploading.
+		if *projectName == "" || *cloneAs == "" {
+			fmt.Println(`usage: -mode=clone -project "<source>" [-commit <id>] -as "<new project>"`)
+			return
+		}
+		if err := backend.CloneProject(ctx, meta, r2, *projectName, *commitID, *cloneAs); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("cloned %s -> %s\n", *projectName, *cloneAs)
+
+	case "usage":
+		// R2 bytes a project occupies, for cost allocation; without -project,
+		// the whole bucket by project with a monthly estimate.
+		if *projectName == "" {
+			pricing := backend.StoragePricingFromEnv()
+			if *pricePerGB >= 0 {
+				pricing.PerGBMonth = *pricePerGB
+			}
+			t, err := backend.TotalStorageUsageWithPricing(ctx, r2, pricing)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if *jsonOut {
+				_ = json.NewEncoder(os.Stdout).Encode(t)
+				return
+			}
+			fmt.Printf("%-32s %12s %10s %10s\n", "PROJECT", "SIZE", "OBJECTS", "$/MONTH")
+			for _, u := range t.Projects {
+				fmt.Printf("%-32s %12s %10d %10.2f\n", u.Project, backend.HumanBytes(u.ExclusiveBytes), u.ExclusiveObjects, u.MonthlyCost)
+			}
+			if t.Shared.SharedObjects > 0 {
+				fmt.Printf("%-32s %12s %10d %10.2f\n", t.Shared.Project, backend.HumanBytes(t.Shared.SharedBytes), t.Shared.SharedObjects, t.Shared.MonthlyCost)
+			}
+			fmt.Printf("\ntotal %s in %d object(s): ~$%.2f/month at $%g/GB after %g GB free\n",
+				backend.HumanBytes(t.TotalBytes), t.TotalObjects, t.MonthlyCost, t.Pricing.PerGBMonth, t.Pricing.FreeGB)
+			return
+		}
+		u, err := backend.ProjectStorageUsageWithStates(ctx, meta, r2, *projectName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(u)
+			return
+		}
+		fmt.Printf("%s\n", u.Project)
+		fmt.Printf("  exclusive  %10s  %d object(s)\n", backend.HumanBytes(u.ExclusiveBytes), u.ExclusiveObjects)
+		fmt.Printf("    blobs    %10s  %d\n", backend.HumanBytes(u.BlobBytes), u.BlobObjects)
+		fmt.Printf("    manifests%10s  %d\n", backend.HumanBytes(u.ManifestBytes), u.ManifestObjects)
+		if u.OtherObjects > 0 {
+			fmt.Printf("    other    %10s  %d\n", backend.HumanBytes(u.OtherBytes), u.OtherObjects)
+		}
+		fmt.Printf("  shared     %10s  %d global blob(s), deduplicated across projects\n", backend.HumanBytes(u.SharedBytes), u.SharedObjects)
+
+	case "rebind":
+		// Point a local folder at another remote project (duplicate/fork recovery).
+		if *root == "" || *projectName == "" || *remoteName == "" {
+			fmt.Println(`usage: -mode=rebind -root "<path>" -project "<local folder>" -remote "<remote project>"`)
+			return
+		}
+		projectPath := filepath.Join(*root, *projectName)
+		if err := backend.RebindProject(ctx, meta, projectPath, *remoteName); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s now syncs with remote project %q ✓", projectPath, *remoteName)
+
+	case "relocate":
+		// The library moved drives: fix the absolute paths Portsy kept.
+		if *root == "" || *fromALS == "" {
+			fmt.Println(`usage: -mode=relocate -from "<old root>" -root "<new root>"`)
+			return
+		}
+		if err := backend.RelocateRoot(*fromALS, *root); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Projects relocated from %s to %s ✓ (remote paths update on each project's next push)", *fromALS, *root)
+
+	case "files":
+		// What a remote commit holds, with audio info ("0:02, 44.1kHz, 24-bit").
+		if *projectName == "" {
+			fmt.Println(`usage: -mode=files -project "<name>" [-commit <id>] [-json]`)
+			return
+		}
+		files, err := backend.RemoteFiles(ctx, meta, *projectName, *commitID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(files)
+			return
+		}
+		for _, f := range files {
+			if f.Audio != nil {
+				fmt.Printf("%10s  %s — %s\n", backend.HumanBytes(f.Size), f.Path, backend.FormatAudioInfo(f.Audio))
+			} else {
+				fmt.Printf("%10s  %s\n", backend.HumanBytes(f.Size), f.Path)
+			}
+		}
+
+	case "preview":
+		// Presigned URL that streams one remote file inline (sample preview).
+		if *projectName == "" || *filePath == "" {
+			fmt.Println(`usage: -mode=preview -project "<name>" -file "<rel path>" [-commit <id>]`)
+			return
+		}
+		url, err := backend.PreviewURL(ctx, meta, r2, *projectName, *commitID, *filePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(url)
+
+	case "peaks":
+		// Stored waveform peaks of one remote audio file (push -peaks).
+		if *projectName == "" || *filePath == "" {
+			fmt.Println(`usage: -mode=peaks -project "<name>" -file "<rel path>" [-commit <id>]`)
+			return
+		}
+		d, err := backend.FilePeaks(ctx, meta, r2, *projectName, *commitID, *filePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(d)
+
+	case "blame":
+		// Which commits changed one file, newest first.
+		if *projectName == "" || *filePath == "" {
+			fmt.Println(`usage: -mode=blame -project "<name>" -file "<rel path>" [-limit N] [-json]`)
+			return
+		}
+		versions, err := backend.FileHistoryN(ctx, meta, *projectName, *filePath, *limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			if versions == nil {
+				versions = []backend.FileVersion{}
+			}
+			_ = json.NewEncoder(os.Stdout).Encode(versions)
+			return
+		}
+		if len(versions) == 0 {
+			fmt.Printf("No changes to %s in the inspected commits.\n", *filePath)
+			return
+		}
+		for _, v := range versions {
+			fmt.Printf("%s  %-8s  %s  %s\n", time.Unix(v.Timestamp, 0).Format("2006-01-02 15:04"), v.Change, backend.ShortCommitID(v.CommitID), v.Message)
+		}
+
+	case "audit":
+		// Who changed what on the remote (commits, repairs, relayouts, rollbacks).
+		if *projectName == "" {
+			fmt.Println(`usage: -mode=audit -project "<name>" [-limit N] [-json]`)
+			return
+		}
+		entries, err := meta.GetAuditLog(ctx, *projectName, *limit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *jsonOut {
+			if entries == nil {
+				entries = []backend.AuditEntry{}
+			}
+			_ = json.NewEncoder(os.Stdout).Encode(entries)
+			return
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No audit entries for %q.\n", *projectName)
+			return
+		}
+		for _, e := range entries {
+			fmt.Printf("%s  %-16s  %-15s  %s\n", e.At.Local().Format("2006-01-02 15:04:05"), e.Actor, e.Action, e.Target)
+		}
+
+	case "migrate":
+		if *projectName == "" {
+			log.Fatal("migrate requires -project")
+		}
+		rep, err := backend.MigrateKeyScheme(ctx, meta, r2, *projectName, backend.KeyScheme(*keyScheme))
+		if rep != nil {
+			log.Printf("migrate %q -> %s: states=%d migrated=%d skipped=%d blobsCopied=%d",
+				rep.Project, rep.Target, rep.States, rep.StatesMigrated, rep.StatesSkipped, rep.BlobsCopied)
+		}
+		if err != nil {
+			log.Fatalf("%v (safe to re-run)", err)
+		}
+		log.Println("Migration completed ✓")
+
+	case "gc":
+		// Delete blobs no kept commit references; dry run (full reference map)
+		// unless -yes.
+		if *projectName == "" {
+			log.Fatal("gc requires -project")
+		}
+		opt := backend.GCOptions{DryRun: *dryRun || !*yes, KeepRecent: *keepRecent, DeletableOnly: *gcDelOnly}
+		for _, id := range strings.Split(*protect, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				opt.Protect = append(opt.Protect, id)
+			}
+		}
+		rep, err := backend.GCProject(ctx, meta, r2, *projectName, opt)
+		if rep != nil {
+			if *jsonOut {
+				_ = json.NewEncoder(os.Stdout).Encode(rep)
+			} else {
+				for _, c := range rep.Candidates {
+					verdict := "keep  "
+					if c.Deletable {
+						verdict = "delete"
+					}
+					fmt.Printf("%s %s (%s)\n", verdict, c.Key, c.Reason)
+					for _, r := range c.Refs {
+						fmt.Printf("         %s %s %s %s\n", backend.ShortCommitID(r.CommitID), r.Status, time.Unix(r.Timestamp, 0).Format("2006-01-02 15:04"), r.Keep)
+					}
+				}
+			}
+			log.Printf("gc %q: %d commit(s), %d blob(s) stored, %d deletable (%d bytes), %d deleted",
+				rep.Project, rep.Commits, rep.Stored, rep.DeletableCount, rep.DeletableBytes, len(rep.Deleted))
+			if rep.GlobalBlobs {
+				log.Println("note: global blobs are shared across projects and never collected")
+			}
+			if rep.DryRun {
+				log.Println("dry run: nothing deleted (pass -yes to delete)")
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "migrate-gc":
+		// After migrate: drop old per-project blob keys that have a global copy.
+		if *projectName == "" {
+			log.Fatal("migrate-gc requires -project")
+		}
+		rep, err := backend.PruneMigratedKeys(ctx, meta, r2, *projectName, *dryRun)
+		if rep != nil {
+			if *jsonOut {
+				_ = json.NewEncoder(os.Stdout).Encode(rep)
+			}
+			verb := "deleted"
+			if rep.DryRun {
+				verb = "would delete"
+			}
+			log.Printf("migrate-gc %q: oldKeys=%d %s=%d stillReferenced=%d unconfirmed=%d statesRewritten=%d",
+				rep.Project, rep.OldKeys, verb, len(rep.Deleted), rep.StillReferenced, len(rep.Unconfirmed), rep.StatesRewritten)
+			for _, k := range rep.Unconfirmed {
+				log.Printf("kept %s: no global copy", k)
+			}
+		}
+		if err != nil {
+			log.Fatalf("%v (safe to re-run)", err)
+		}
+
+	case "migrate-manifests":
+		// Move a project's existing state file lists into R2 manifests.
+		if *projectName == "" {
+			log.Fatal("migrate-manifests requires -project")
+		}
+		rep, err := backend.MigrateStatesToR2(ctx, meta, r2, *projectName)
+		if rep != nil {
+			log.Printf("migrate-manifests %q: states=%d migrated=%d skipped=%d",
+				rep.Project, rep.States, rep.StatesMigrated, rep.StatesSkipped)
+		}
+		if err != nil {
+			log.Fatalf("%v (safe to re-run)", err)
+		}
+		log.Println("Migration completed ✓")
+
+	case "create":
+		if *projectName == "" {
+			log.Fatal("create requires -project")
+		}
+		id, err := meta.CreateProject(ctx, *projectName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Created empty remote project %q (id=%s) ✓", *projectName, id)
+
+	case "doctor":
+		// Self-heal remote pointer fields (HEAD/Last5) that drifted from the commits subcollection.
+		names := []string{}
+		if *projectName != "" {
+			names = append(names, *projectName)
+		} else {
+			projs, err := meta.ListProjects(ctx)
+			if err != nil {
+				log.Fatalf("list projects: %v", err)
+			}
+			for _, p := range projs {
+				names = append(names, p.ProjectID)
+			}
+		}
+		failed := 0
+		for _, n := range names {
+			if err := meta.NormalizeProjectDoc(ctx, n); err != nil {
+				fmt.Printf("✗ %s: %v\n", n, err)
+				failed++
+				continue
+			}
+			if err := meta.RepairRecent(ctx, n); err != nil {
+				fmt.Printf("✗ %s: %v\n", n, err)
+				failed++
+				continue
+			}
+			fmt.Printf("✓ %s: recent commits ok\n", n)
+		}
+		// Local checks: with -root, flag projects living inside cloud-sync folders
+		// and files that look accidentally included (huge renders, temp files).
+		if *root != "" {
+			if projs, err := backend.ScanProjects(*root); err == nil {
+				for _, p := range projs {
+					if warn, ok := backend.DetectCloudSyncConflict(p.Path); ok {
+						fmt.Printf("⚠ %s: %s\n", p.Name, warn)
+					}
+					suspects, _ := backend.ScanSuspectFiles(p.Path, *maxFileMB<<20)
+					for _, sf := range suspects {
+						fmt.Printf("⚠ %s: %s: %s (.portsyignore pattern: %s)\n", p.Name, sf.Path, sf.Detail, sf.IgnorePattern)
+					}
+				}
+			}
+		}
+		if failed > 0 {
+			log.Fatalf("doctor: %d project(s) could not be repaired", failed)
+		}
+
 	default:
 		log.Fatalf("unknown mode: %s", *mode)
 	}
@@ -67,6 +67,24 @@ export const pullProject = (name, { allowDelete=false, commitId='' }={}) => {
 };
 
 
+// New remote project from src at commit ('' = HEAD), e.g. to start a remix
+export const cloneProject = (src, commit, newName) => call('CloneProject', src, commit, newName);
+
+// Files of a remote commit ('' = HEAD); audio files carry { audio: { format, duration, sampleRate, channels, bitDepth } }
+export const listRemoteFiles = async (name, commit) => JSON.parse(await call('RemoteFiles', name, commit));
+
+// Presigned URL streaming one remote file inline (e.g. a sample in an <audio> player)
+export const previewUrl = (name, commit, file) => call('PreviewURL', name, commit, file);
+
+// Waveform overview of a pushed audio file: { sampleRate, channels, frames, samplesPerPixel, peaks: [min,max,...] }
+export const getPeaks = async (name, commit, file) => JSON.parse(await call('Peaks', name, commit, file));
+
+// -------------- STORAGE ----------------
+// R2 usage of one project (JSON from the CLI: exclusive/shared bytes + breakdown)
+export const getStorageUsage = async (name) => JSON.parse(await call('StorageUsage', name));
+// Whole bucket by project, largest first, with a monthly cost estimate
+export const getTotalStorageUsage = async () => JSON.parse(await call('TotalStorageUsage'));
+
 // ----- WATCHER ------
 // Start filesystem watcher for all projects under root
 export const startWatcherAll = (root, autopush = false) => call('StartWatcherAll', root, autopush);
@@ -11,6 +11,7 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
 
+	"Portsy/backend"
 	"Portsy/backend/uiapi"
 )
 
@@ -24,7 +25,7 @@ var (
 
 func main() {
 	app = NewApp()
-	api = &uiapi.API{} // exposes DetectChanges + event/log emitter
+	api = &uiapi.API{Manifests: backend.LocalManifests} // exposes DetectChanges + event/log emitter
 
 	err := wails.Run(&options.App{
 		Title:  "Portsy",
@@ -49,8 +50,8 @@ func main() {
 			if closer, ok := interface{}(api).(interface{ Close() error }); ok {
 				_ = closer.Close()
 			}
-			if closer, ok := interface{}(app).(interface{ Shutdown() error }); ok {
-				_ = closer.Shutdown()
+			if err := app.Shutdown(); err != nil {
+				log.Printf("%v", err)
 			}
 		},
 		Bind: []interface{}{